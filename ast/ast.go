@@ -15,6 +15,7 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position
 }
 
 // Statement defines the interface for all statement nodes.
@@ -42,6 +43,14 @@ func (p *Program) TokenLiteral() string {
 	return ""
 }
 
+// Pos returns the position of the first statement in the program
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
 // String returns a stringified version of the AST for debugging
 func (p *Program) String() string {
 	var out bytes.Buffer
@@ -64,6 +73,9 @@ func (c *Comment) statementNode() {}
 // TokenLiteral prints the literal value of the token associated with this node
 func (c *Comment) TokenLiteral() string { return c.Token.Literal }
 
+// Pos returns the position of the token associated with this node
+func (c *Comment) Pos() token.Position { return c.Token.Pos }
+
 // String returns a stringified version of the AST for debugging
 func (c *Comment) String() string {
 	var out bytes.Buffer
@@ -85,6 +97,9 @@ func (rs *ReturnStatement) statementNode() {}
 // TokenLiteral prints the literal value of the token associated with this node
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
 
+// Pos returns the position of the token associated with this node
+func (rs *ReturnStatement) Pos() token.Position { return rs.Token.Pos }
+
 // String returns a stringified version of the AST for debugging
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
@@ -100,6 +115,38 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+// BreakStatement represents the `break` statement node
+type BreakStatement struct {
+	Token token.Token // the 'break' token
+}
+
+func (bs *BreakStatement) statementNode() {}
+
+// TokenLiteral prints the literal value of the token associated with this node
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+
+// Pos returns the position of the token associated with this node
+func (bs *BreakStatement) Pos() token.Position { return bs.Token.Pos }
+
+// String returns a stringified version of the AST for debugging
+func (bs *BreakStatement) String() string { return bs.TokenLiteral() + ";" }
+
+// ContinueStatement represents the `continue` statement node
+type ContinueStatement struct {
+	Token token.Token // the 'continue' token
+}
+
+func (cs *ContinueStatement) statementNode() {}
+
+// TokenLiteral prints the literal value of the token associated with this node
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+
+// Pos returns the position of the token associated with this node
+func (cs *ContinueStatement) Pos() token.Position { return cs.Token.Pos }
+
+// String returns a stringified version of the AST for debugging
+func (cs *ContinueStatement) String() string { return cs.TokenLiteral() + ";" }
+
 // ExpressionStatement represents an expression statement and holds an
 // expression
 type ExpressionStatement struct {
@@ -112,6 +159,9 @@ func (es *ExpressionStatement) statementNode() {}
 // TokenLiteral prints the literal value of the token associated with this node
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
 
+// Pos returns the position of the token associated with this node
+func (es *ExpressionStatement) Pos() token.Position { return es.Token.Pos }
+
 // String returns a stringified version of the AST for debugging
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
@@ -132,6 +182,9 @@ func (bs *BlockStatement) statementNode() {}
 // TokenLiteral prints the literal value of the token associated with this node
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
 
+// Pos returns the position of the token associated with this node
+func (bs *BlockStatement) Pos() token.Position { return bs.Token.Pos }
+
 // String returns a stringified version of the AST for debugging
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
@@ -154,6 +207,9 @@ func (i *Identifier) expressionNode() {}
 // TokenLiteral prints the literal value of the token associated with this node
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
 
+// Pos returns the position of the token associated with this node
+func (i *Identifier) Pos() token.Position { return i.Token.Pos }
+
 // String returns a stringified version of the AST for debugging
 func (i *Identifier) String() string { return i.Value }
 
@@ -167,6 +223,9 @@ func (n *Null) expressionNode() {}
 // TokenLiteral prints the literal value of the token associated with this node
 func (n *Null) TokenLiteral() string { return n.Token.Literal }
 
+// Pos returns the position of the token associated with this node
+func (n *Null) Pos() token.Position { return n.Token.Pos }
+
 // String returns a stringified version of the AST for debugging
 func (n *Null) String() string { return n.Token.Literal }
 
@@ -181,6 +240,9 @@ func (b *Boolean) expressionNode() {}
 // TokenLiteral prints the literal value of the token associated with this node
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
 
+// Pos returns the position of the token associated with this node
+func (b *Boolean) Pos() token.Position { return b.Token.Pos }
+
 // String returns a stringified version of the AST for debugging
 func (b *Boolean) String() string { return b.Token.Literal }
 
@@ -195,9 +257,30 @@ func (il *IntegerLiteral) expressionNode() {}
 // TokenLiteral prints the literal value of the token associated with this node
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 
+// Pos returns the position of the token associated with this node
+func (il *IntegerLiteral) Pos() token.Position { return il.Token.Pos }
+
 // String returns a stringified version of the AST for debugging
 func (il *IntegerLiteral) String() string { return il.Token.Literal }
 
+// FloatLiteral represents a literal floating point number and holds a
+// float value
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode() {}
+
+// TokenLiteral prints the literal value of the token associated with this node
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+
+// Pos returns the position of the token associated with this node
+func (fl *FloatLiteral) Pos() token.Position { return fl.Token.Pos }
+
+// String returns a stringified version of the AST for debugging
+func (fl *FloatLiteral) String() string { return fl.Token.Literal }
+
 // StringLiteral represents a literal string and holds a string value
 type StringLiteral struct {
 	Token token.Token
@@ -209,6 +292,9 @@ func (sl *StringLiteral) expressionNode() {}
 // TokenLiteral prints the literal value of the token associated with this node
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
 
+// Pos returns the position of the token associated with this node
+func (sl *StringLiteral) Pos() token.Position { return sl.Token.Pos }
+
 // String returns a stringified version of the AST for debugging
 func (sl *StringLiteral) String() string { return sl.Token.Literal }
 
@@ -225,6 +311,9 @@ func (pe *PrefixExpression) expressionNode() {}
 // TokenLiteral prints the literal value of the token associated with this node
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
 
+// Pos returns the position of the token associated with this node
+func (pe *PrefixExpression) Pos() token.Position { return pe.Token.Pos }
+
 // String returns a stringified version of the AST for debugging
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
@@ -251,6 +340,9 @@ func (ie *InfixExpression) expressionNode() {}
 // TokenLiteral prints the literal value of the token associated with this node
 func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
 
+// Pos returns the position of the token associated with this node
+func (ie *InfixExpression) Pos() token.Position { return ie.Token.Pos }
+
 // String returns a stringified version of the AST for debugging
 func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
@@ -278,6 +370,9 @@ func (ie *IfExpression) expressionNode() {}
 // TokenLiteral prints the literal value of the token associated with this node
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
 
+// Pos returns the position of the token associated with this node
+func (ie *IfExpression) Pos() token.Position { return ie.Token.Pos }
+
 // String returns a stringified version of the AST for debugging
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
@@ -308,6 +403,9 @@ func (we *WhileExpression) expressionNode() {}
 // TokenLiteral prints the literal value of the token associated with this node
 func (we *WhileExpression) TokenLiteral() string { return we.Token.Literal }
 
+// Pos returns the position of the token associated with this node
+func (we *WhileExpression) Pos() token.Position { return we.Token.Pos }
+
 // String returns a stringified version of the AST for debugging
 func (we *WhileExpression) String() string {
 	var out bytes.Buffer
@@ -320,6 +418,199 @@ func (we *WhileExpression) String() string {
 	return out.String()
 }
 
+// ForExpression represents a `for ... in` expression and holds the loop
+// variable(s), the iterable expression being iterated over and the body.
+// Key is only set when two loop variables are given, e.g: `for k, v in xs`
+type ForExpression struct {
+	Token    token.Token // The 'for' token
+	Key      *Identifier
+	Value    *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fe *ForExpression) expressionNode() {}
+
+// TokenLiteral prints the literal value of the token associated with this node
+func (fe *ForExpression) TokenLiteral() string { return fe.Token.Literal }
+
+// Pos returns the position of the token associated with this node
+func (fe *ForExpression) Pos() token.Position { return fe.Token.Pos }
+
+// String returns a stringified version of the AST for debugging
+func (fe *ForExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for ")
+	if fe.Key != nil {
+		out.WriteString(fe.Key.String())
+		out.WriteString(", ")
+	}
+	out.WriteString(fe.Value.String())
+	out.WriteString(" in ")
+	out.WriteString(fe.Iterable.String())
+	out.WriteString(" ")
+	out.WriteString(fe.Body.String())
+
+	return out.String()
+}
+
+// TryExpression represents a `try` expression and holds the block being
+// guarded, the optional `catch` block along with the identifier the caught
+// error is bound to (if any) and the optional `finally` block. At least one
+// of Catch or Finally is set.
+type TryExpression struct {
+	Token      token.Token // The 'try' token
+	Block      *BlockStatement
+	CatchParam *Identifier
+	Catch      *BlockStatement
+	Finally    *BlockStatement
+}
+
+func (te *TryExpression) expressionNode() {}
+
+// TokenLiteral prints the literal value of the token associated with this node
+func (te *TryExpression) TokenLiteral() string { return te.Token.Literal }
+
+// Pos returns the position of the token associated with this node
+func (te *TryExpression) Pos() token.Position { return te.Token.Pos }
+
+// String returns a stringified version of the AST for debugging
+func (te *TryExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try ")
+	out.WriteString(te.Block.String())
+	if te.Catch != nil {
+		out.WriteString(" catch ")
+		if te.CatchParam != nil {
+			out.WriteString("(")
+			out.WriteString(te.CatchParam.String())
+			out.WriteString(") ")
+		}
+		out.WriteString(te.Catch.String())
+	}
+	if te.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(te.Finally.String())
+	}
+
+	return out.String()
+}
+
+// ThrowExpression represents a `throw` expression and holds the value being
+// thrown, either an error or a string used as the message of a new error.
+type ThrowExpression struct {
+	Token token.Token // The 'throw' token
+	Value Expression
+}
+
+func (te *ThrowExpression) expressionNode() {}
+
+// TokenLiteral prints the literal value of the token associated with this node
+func (te *ThrowExpression) TokenLiteral() string { return te.Token.Literal }
+
+// Pos returns the position of the token associated with this node
+func (te *ThrowExpression) Pos() token.Position { return te.Token.Pos }
+
+// String returns a stringified version of the AST for debugging
+func (te *ThrowExpression) String() string {
+	return te.TokenLiteral() + " " + te.Value.String()
+}
+
+// SpawnExpression represents a `spawn` expression and holds the function
+// run as a new task, a call expression whose function is run with the
+// arguments evaluated by the spawning task or any other expression whose
+// value is a function run without arguments.
+type SpawnExpression struct {
+	Token token.Token // The 'spawn' token
+	Value Expression
+}
+
+func (se *SpawnExpression) expressionNode() {}
+
+// TokenLiteral prints the literal value of the token associated with this node
+func (se *SpawnExpression) TokenLiteral() string { return se.Token.Literal }
+
+// Pos returns the position of the token associated with this node
+func (se *SpawnExpression) Pos() token.Position { return se.Token.Pos }
+
+// String returns a stringified version of the AST for debugging
+func (se *SpawnExpression) String() string {
+	return se.TokenLiteral() + " " + se.Value.String()
+}
+
+// SelectExpression represents a `select` expression and holds the cases
+// waiting to send to or receive from channels and the optional `default`
+// block run if none of them can proceed.
+type SelectExpression struct {
+	Token   token.Token // The 'select' token
+	Cases   []*SelectCase
+	Default *BlockStatement
+}
+
+func (se *SelectExpression) expressionNode() {}
+
+// TokenLiteral prints the literal value of the token associated with this node
+func (se *SelectExpression) TokenLiteral() string { return se.Token.Literal }
+
+// Pos returns the position of the token associated with this node
+func (se *SelectExpression) Pos() token.Position { return se.Token.Pos }
+
+// String returns a stringified version of the AST for debugging
+func (se *SelectExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("select { ")
+	for _, c := range se.Cases {
+		out.WriteString(c.String())
+		out.WriteString(" ")
+	}
+	if se.Default != nil {
+		out.WriteString("default ")
+		out.WriteString(se.Default.String())
+		out.WriteString(" ")
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// SelectCase is a case of a select expression and holds the channel sent to
+// or received from, the value sent (nil for cases receiving), the identifier
+// the value received is bound to (if any) and the block run if the case is
+// selected, e.g: `case v := recv(ch) { ... }` or `case send(ch, v) { ... }`.
+type SelectCase struct {
+	Token   token.Token // The 'case' token
+	Name    *Identifier
+	Channel Expression
+	Value   Expression
+	Body    *BlockStatement
+}
+
+// Pos returns the position of the token associated with this case
+func (sc *SelectCase) Pos() token.Position { return sc.Token.Pos }
+
+// Operation returns the operation of the case without the `case` keyword,
+// e.g: `v := recv(ch)`
+func (sc *SelectCase) Operation() string {
+	if sc.Value != nil {
+		return "send(" + sc.Channel.String() + ", " + sc.Value.String() + ")"
+	}
+	if sc.Name != nil {
+		return sc.Name.String() + " := recv(" + sc.Channel.String() + ")"
+	}
+	return "recv(" + sc.Channel.String() + ")"
+}
+
+// String returns a stringified version of the AST for debugging
+func (sc *SelectCase) String() string {
+	return sc.TokenLiteral() + " " + sc.Operation() + " " + sc.Body.String()
+}
+
+// TokenLiteral prints the literal value of the token associated with this case
+func (sc *SelectCase) TokenLiteral() string { return sc.Token.Literal }
+
 // ImportExpression represents an `import` expression and holds the name
 // of the module being imported.
 type ImportExpression struct {
@@ -332,6 +623,9 @@ func (ie *ImportExpression) expressionNode() {}
 // TokenLiteral prints the literal value of the token associated with this node
 func (ie *ImportExpression) TokenLiteral() string { return ie.Token.Literal }
 
+// Pos returns the position of the token associated with this node
+func (ie *ImportExpression) Pos() token.Position { return ie.Token.Pos }
+
 // String returns a stringified version of the AST for debugging
 func (ie *ImportExpression) String() string {
 	var out bytes.Buffer
@@ -351,6 +645,12 @@ type FunctionLiteral struct {
 	Name       string
 	Parameters []*Identifier
 	Body       *BlockStatement
+
+	// ParameterTypes are the annotated types of the parameters (nil for
+	// those without annotations) or nil if none are annotated
+	ParameterTypes []Type
+	// ReturnType is the annotated return type or nil
+	ReturnType Type
 }
 
 func (fl *FunctionLiteral) expressionNode() {}
@@ -358,19 +658,29 @@ func (fl *FunctionLiteral) expressionNode() {}
 // TokenLiteral prints the literal value of the token associated with this node
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
 
+// Pos returns the position of the token associated with this node
+func (fl *FunctionLiteral) Pos() token.Position { return fl.Token.Pos }
+
 // String returns a stringified version of the AST for debugging
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
 	params := []string{}
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
+	for i, p := range fl.Parameters {
+		if i < len(fl.ParameterTypes) && fl.ParameterTypes[i] != nil {
+			params = append(params, p.String()+": "+fl.ParameterTypes[i].String())
+		} else {
+			params = append(params, p.String())
+		}
 	}
 
 	out.WriteString(fmt.Sprintf("%s %s", fl.TokenLiteral(), fl.Name))
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
+	if fl.ReturnType != nil {
+		out.WriteString("-> " + fl.ReturnType.String() + " ")
+	}
 	out.WriteString(fl.Body.String())
 
 	return out.String()
@@ -389,6 +699,9 @@ func (ce *CallExpression) expressionNode() {}
 // TokenLiteral prints the literal value of the token associated with this node
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
 
+// Pos returns the position of the token associated with this node
+func (ce *CallExpression) Pos() token.Position { return ce.Token.Pos }
+
 // String returns a stringified version of the AST for debugging
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
@@ -417,6 +730,9 @@ func (al *ArrayLiteral) expressionNode() {}
 // TokenLiteral prints the literal value of the token associated with this node
 func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
 
+// Pos returns the position of the token associated with this node
+func (al *ArrayLiteral) Pos() token.Position { return al.Token.Pos }
+
 // String returns a stringified version of the AST for debugging
 func (al *ArrayLiteral) String() string {
 	var out bytes.Buffer
@@ -439,6 +755,7 @@ type BindExpression struct {
 	Token token.Token // The := token
 	Left  Expression
 	Value Expression
+	Type  Type // The annotated type, e.g. x: int := 1, or nil
 }
 
 func (be *BindExpression) expressionNode() {}
@@ -446,11 +763,17 @@ func (be *BindExpression) expressionNode() {}
 // TokenLiteral prints the literal value of the token associated with this node
 func (be *BindExpression) TokenLiteral() string { return be.Token.Literal }
 
+// Pos returns the position of the token associated with this node
+func (be *BindExpression) Pos() token.Position { return be.Token.Pos }
+
 // String returns a stringified version of the AST for debugging
 func (be *BindExpression) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(be.Left.String())
+	if be.Type != nil {
+		out.WriteString(": " + be.Type.String() + " ")
+	}
 	out.WriteString(be.TokenLiteral())
 	out.WriteString(be.Value.String())
 
@@ -470,6 +793,9 @@ func (ae *AssignmentExpression) expressionNode() {}
 // TokenLiteral prints the literal value of the token associated with this node
 func (ae *AssignmentExpression) TokenLiteral() string { return ae.Token.Literal }
 
+// Pos returns the position of the token associated with this node
+func (ae *AssignmentExpression) Pos() token.Position { return ae.Token.Pos }
+
 // String returns a stringified version of the AST for debugging
 func (ae *AssignmentExpression) String() string {
 	var out bytes.Buffer
@@ -494,6 +820,9 @@ func (ie *IndexExpression) expressionNode() {}
 // TokenLiteral prints the literal value of the token associated with this node
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
 
+// Pos returns the position of the token associated with this node
+func (ie *IndexExpression) Pos() token.Position { return ie.Token.Pos }
+
 // String returns a stringified version of the AST for debugging
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
@@ -512,6 +841,7 @@ func (ie *IndexExpression) String() string {
 type HashLiteral struct {
 	Token token.Token // the '{' token
 	Pairs map[Expression]Expression
+	Keys  []Expression // the keys of Pairs in the order of the source
 }
 
 func (hl *HashLiteral) expressionNode() {}
@@ -519,13 +849,16 @@ func (hl *HashLiteral) expressionNode() {}
 // TokenLiteral prints the literal value of the token associated with this node
 func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
 
+// Pos returns the position of the token associated with this node
+func (hl *HashLiteral) Pos() token.Position { return hl.Token.Pos }
+
 // String returns a stringified version of the AST for debugging
 func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, key := range hl.Keys {
+		pairs = append(pairs, key.String()+":"+hl.Pairs[key].String())
 	}
 
 	out.WriteString("{")
@@ -534,3 +867,94 @@ func (hl *HashLiteral) String() string {
 
 	return out.String()
 }
+
+// Type is a type annotation of a binding or a function's parameter or return
+// value
+type Type interface {
+	Node
+	typeNode()
+}
+
+// NamedType represents a type named by an identifier, e.g. int or str
+type NamedType struct {
+	Token token.Token // The identifier (or null) token
+	Name  string
+}
+
+func (nt *NamedType) typeNode() {}
+
+// TokenLiteral prints the literal value of the token associated with this node
+func (nt *NamedType) TokenLiteral() string { return nt.Token.Literal }
+
+// Pos returns the position of the token associated with this node
+func (nt *NamedType) Pos() token.Position { return nt.Token.Pos }
+
+// String returns a stringified version of the AST for debugging
+func (nt *NamedType) String() string { return nt.Name }
+
+// ArrayType represents the type of arrays of elements of a type, e.g. [int]
+type ArrayType struct {
+	Token   token.Token // The '[' token
+	Element Type
+}
+
+func (at *ArrayType) typeNode() {}
+
+// TokenLiteral prints the literal value of the token associated with this node
+func (at *ArrayType) TokenLiteral() string { return at.Token.Literal }
+
+// Pos returns the position of the token associated with this node
+func (at *ArrayType) Pos() token.Position { return at.Token.Pos }
+
+// String returns a stringified version of the AST for debugging
+func (at *ArrayType) String() string { return "[" + at.Element.String() + "]" }
+
+// HashType represents the type of hashes with keys and values of a type,
+// e.g. {str: int}
+type HashType struct {
+	Token token.Token // The '{' token
+	Key   Type
+	Value Type
+}
+
+func (ht *HashType) typeNode() {}
+
+// TokenLiteral prints the literal value of the token associated with this node
+func (ht *HashType) TokenLiteral() string { return ht.Token.Literal }
+
+// Pos returns the position of the token associated with this node
+func (ht *HashType) Pos() token.Position { return ht.Token.Pos }
+
+// String returns a stringified version of the AST for debugging
+func (ht *HashType) String() string {
+	return "{" + ht.Key.String() + ": " + ht.Value.String() + "}"
+}
+
+// FunctionType represents the type of functions, e.g. fn(int, str) -> bool
+type FunctionType struct {
+	Token      token.Token // The 'fn' token
+	Parameters []Type
+	Return     Type // The return type or nil
+}
+
+func (ft *FunctionType) typeNode() {}
+
+// TokenLiteral prints the literal value of the token associated with this node
+func (ft *FunctionType) TokenLiteral() string { return ft.Token.Literal }
+
+// Pos returns the position of the token associated with this node
+func (ft *FunctionType) Pos() token.Position { return ft.Token.Pos }
+
+// String returns a stringified version of the AST for debugging
+func (ft *FunctionType) String() string {
+	params := []string{}
+	for _, p := range ft.Parameters {
+		params = append(params, p.String())
+	}
+
+	out := "fn(" + strings.Join(params, ", ") + ")"
+	if ft.Return != nil {
+		out += " -> " + ft.Return.String()
+	}
+	return out
+}
@@ -1,24 +1,34 @@
 package builtins
 
 import (
+	"math"
+
 	"github.com/prologic/monkey-lang/object"
 	"github.com/prologic/monkey-lang/typing"
 )
 
 // Abs ...
-func Abs(args ...object.Object) object.Object {
+func Abs(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"abs", args,
 		typing.ExactArgs(1),
-		typing.WithTypes(object.INTEGER),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
-	i := args[0].(*object.Integer)
-	value := i.Value
-	if value < 0 {
-		value = value * -1
+	switch arg := args[0].(type) {
+	case *object.Integer:
+		value := arg.Value
+		if value < 0 {
+			value = value * -1
+		}
+		return &object.Integer{Value: value}
+	case *object.Float:
+		return &object.Float{Value: math.Abs(arg.Value)}
+	default:
+		return newError(
+			"TypeError: abs() expected argument #1 to be `int` or `float` got `%s`",
+			args[0].Type(),
+		)
 	}
-	return &object.Integer{Value: value}
 }
@@ -8,13 +8,13 @@ import (
 )
 
 // Accept ...
-func Accept(args ...object.Object) object.Object {
+func Accept(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"accept", args,
 		typing.ExactArgs(1),
 		typing.WithTypes(object.INTEGER),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	var (
@@ -24,7 +24,14 @@ func Accept(args ...object.Object) object.Object {
 
 	fd := int(args[0].(*object.Integer).Value)
 
+	// Other tasks run while waiting for a connection
+	ctx.Release()
 	nfd, _, err = syscall.Accept(fd)
+	ctx.Acquire()
+	if err == syscall.EAGAIN {
+		// No connection to accept on the non-blocking socket yet
+		return &object.Null{}
+	}
 	if err != nil {
 		return newError("SocketError: %s", err)
 	}
@@ -6,16 +6,16 @@ import (
 )
 
 // Args ...
-func Args(args ...object.Object) object.Object {
+func Args(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"args", args,
 		typing.ExactArgs(0),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
-	elements := make([]object.Object, len(object.Arguments))
-	for i, arg := range object.Arguments {
+	elements := make([]object.Object, len(ctx.Args))
+	for i, arg := range ctx.Args {
 		elements[i] = &object.String{Value: arg}
 	}
 	return &object.Array{Elements: elements}
@@ -6,12 +6,12 @@ import (
 )
 
 // Bool ...
-func Bool(args ...object.Object) object.Object {
+func Bool(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"bool", args,
 		typing.ExactArgs(1),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	return &object.Boolean{Value: args[0].Bool()}
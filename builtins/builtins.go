@@ -5,57 +5,144 @@ import (
 	"sort"
 
 	. "github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/typing"
 )
 
 // Builtins ...
 var Builtins = map[string]*Builtin{
-	"len":       &Builtin{Name: "len", Fn: Len},
-	"input":     &Builtin{Name: "input", Fn: Input},
-	"print":     &Builtin{Name: "print", Fn: Print},
-	"first":     &Builtin{Name: "first", Fn: First},
-	"last":      &Builtin{Name: "last", Fn: Last},
-	"rest":      &Builtin{Name: "rest", Fn: Rest},
-	"push":      &Builtin{Name: "push", Fn: Push},
-	"pop":       &Builtin{Name: "pop", Fn: Pop},
-	"exit":      &Builtin{Name: "exit", Fn: Exit},
-	"assert":    &Builtin{Name: "assert", Fn: Assert},
-	"bool":      &Builtin{Name: "bool", Fn: Bool},
-	"int":       &Builtin{Name: "int", Fn: Int},
-	"str":       &Builtin{Name: "str", Fn: Str},
-	"type":      &Builtin{Name: "type", Fn: TypeOf},
-	"args":      &Builtin{Name: "args", Fn: Args},
-	"lower":     &Builtin{Name: "lower", Fn: Lower},
-	"upper":     &Builtin{Name: "upper", Fn: Upper},
-	"join":      &Builtin{Name: "join", Fn: Join},
-	"split":     &Builtin{Name: "split", Fn: Split},
-	"find":      &Builtin{Name: "find", Fn: Find},
-	"readfile":  &Builtin{Name: "readfile", Fn: ReadFile},
-	"writefile": &Builtin{Name: "writefile", Fn: WriteFile},
-	"ffi":       &Builtin{Name: "ffi", Fn: FFI},
-	"abs":       &Builtin{Name: "abs", Fn: Abs},
-	"bin":       &Builtin{Name: "bin", Fn: Bin},
-	"hex":       &Builtin{Name: "hex", Fn: Hex},
-	"ord":       &Builtin{Name: "ord", Fn: Ord},
-	"chr":       &Builtin{Name: "chr", Fn: Chr},
-	"divmod":    &Builtin{Name: "divmod", Fn: Divmod},
-	"hash":      &Builtin{Name: "hash", Fn: HashOf},
-	"id":        &Builtin{Name: "id", Fn: IdOf},
-	"oct":       &Builtin{Name: "oct", Fn: Oct},
-	"pow":       &Builtin{Name: "pow", Fn: Pow},
-	"min":       &Builtin{Name: "min", Fn: Min},
-	"max":       &Builtin{Name: "max", Fn: Max},
-	"sorted":    &Builtin{Name: "sorted", Fn: Sorted},
-	"reversed":  &Builtin{Name: "reversed", Fn: Reversed},
-	"open":      &Builtin{Name: "open", Fn: Open},
-	"close":     &Builtin{Name: "close", Fn: Close},
-	"write":     &Builtin{Name: "write", Fn: Write},
-	"read":      &Builtin{Name: "read", Fn: Read},
-	"seek":      &Builtin{Name: "seek", Fn: Seek},
-	"socket":    &Builtin{Name: "socket", Fn: Socket},
-	"bind":      &Builtin{Name: "bind", Fn: Bind},
-	"accept":    &Builtin{Name: "accept", Fn: Accept},
-	"listen":    &Builtin{Name: "listen", Fn: Listen},
-	"connect":   &Builtin{Name: "connect", Fn: Connect},
+	"len":       &Builtin{Name: "len", Fn: Len, Signature: "len(iterable)"},
+	"input":     &Builtin{Name: "input", Fn: Input, Signature: "input([prompt])"},
+	"print":     &Builtin{Name: "print", Fn: Print, Signature: "print(value...)"},
+	"first":     &Builtin{Name: "first", Fn: First, Signature: "first(array)"},
+	"last":      &Builtin{Name: "last", Fn: Last, Signature: "last(array)"},
+	"rest":      &Builtin{Name: "rest", Fn: Rest, Signature: "rest(array)"},
+	"push":      &Builtin{Name: "push", Fn: Push, Signature: "push(array, value)"},
+	"pop":       &Builtin{Name: "pop", Fn: Pop, Signature: "pop(array)"},
+	"exit":      &Builtin{Name: "exit", Fn: Exit, Signature: "exit([status])", Capability: ProcessCapability},
+	"assert":    &Builtin{Name: "assert", Fn: Assert, Signature: "assert(expr, msg)"},
+	"bool":      &Builtin{Name: "bool", Fn: Bool, Signature: "bool(value)"},
+	"int":       &Builtin{Name: "int", Fn: Int, Signature: "int(value)"},
+	"float":     &Builtin{Name: "float", Fn: FloatOf, Signature: "float(value)"},
+	"str":       &Builtin{Name: "str", Fn: Str, Signature: "str(value)"},
+	"type":      &Builtin{Name: "type", Fn: TypeOf, Signature: "type(value)"},
+	"args":      &Builtin{Name: "args", Fn: Args, Signature: "args()", Capability: ProcessCapability},
+	"getenv":    &Builtin{Name: "getenv", Fn: Getenv, Signature: "getenv(name)", Capability: ProcessCapability},
+	"lower":     &Builtin{Name: "lower", Fn: Lower, Signature: "lower(str)"},
+	"upper":     &Builtin{Name: "upper", Fn: Upper, Signature: "upper(str)"},
+	"join":      &Builtin{Name: "join", Fn: Join, Signature: "join(array, sep)"},
+	"split":     &Builtin{Name: "split", Fn: Split, Signature: "split(str[, sep])"},
+	"find":      &Builtin{Name: "find", Fn: Find, Signature: "find(haystack, needle)"},
+	"readfile":  &Builtin{Name: "readfile", Fn: ReadFile, Signature: "readfile(filename)", Capability: FilesystemCapability},
+	"writefile": &Builtin{Name: "writefile", Fn: WriteFile, Signature: "writefile(filename, data)", Capability: FilesystemCapability},
+	"ffi":       &Builtin{Name: "ffi", Fn: FFI, Signature: "ffi(name, symbol)", Capability: FFICapability},
+	"abs":       &Builtin{Name: "abs", Fn: Abs, Signature: "abs(n)"},
+	"bin":       &Builtin{Name: "bin", Fn: Bin, Signature: "bin(n)"},
+	"hex":       &Builtin{Name: "hex", Fn: Hex, Signature: "hex(n)"},
+	"ord":       &Builtin{Name: "ord", Fn: Ord, Signature: "ord(c)"},
+	"chr":       &Builtin{Name: "chr", Fn: Chr, Signature: "chr(n)"},
+	"divmod":    &Builtin{Name: "divmod", Fn: Divmod, Signature: "divmod(a, b)"},
+	"hash":      &Builtin{Name: "hash", Fn: HashOf, Signature: "hash(any)"},
+	"id":        &Builtin{Name: "id", Fn: IdOf, Signature: "id(any)"},
+	"oct":       &Builtin{Name: "oct", Fn: Oct, Signature: "oct(n)"},
+	"pow":       &Builtin{Name: "pow", Fn: Pow, Signature: "pow(x, y)"},
+	"min":       &Builtin{Name: "min", Fn: Min, Signature: "min(array)"},
+	"max":       &Builtin{Name: "max", Fn: Max, Signature: "max(array)"},
+	"sorted":    &Builtin{Name: "sorted", Fn: Sorted, Signature: "sorted(array)"},
+	"reversed":  &Builtin{Name: "reversed", Fn: Reversed, Signature: "reversed(array)"},
+	"keys":      &Builtin{Name: "keys", Fn: Keys, Signature: "keys(hash)"},
+	"values":    &Builtin{Name: "values", Fn: Values, Signature: "values(hash)"},
+	"items":     &Builtin{Name: "items", Fn: Items, Signature: "items(hash)"},
+	"range":     &Builtin{Name: "range", Fn: RangeOf, Signature: "range([start, ]stop[, step])"},
+	"open":      &Builtin{Name: "open", Fn: Open, Signature: "open(filename[, mode])", Capability: FilesystemCapability},
+	"close":     &Builtin{Name: "close", Fn: Close, Signature: "close(fd|channel)"},
+	"write":     &Builtin{Name: "write", Fn: Write, Signature: "write(fd, data)", Capability: FilesystemCapability | NetworkCapability},
+	"read":      &Builtin{Name: "read", Fn: Read, Signature: "read(fd[, n])", Capability: FilesystemCapability | NetworkCapability},
+	"seek":      &Builtin{Name: "seek", Fn: Seek, Signature: "seek(fd, offset[, whence])", Capability: FilesystemCapability},
+	"socket":    &Builtin{Name: "socket", Fn: Socket, Signature: "socket(type)", Capability: NetworkCapability},
+	"bind":      &Builtin{Name: "bind", Fn: Bind, Signature: "bind(fd, address)", Capability: NetworkCapability},
+	"accept":    &Builtin{Name: "accept", Fn: Accept, Signature: "accept(fd)", Capability: NetworkCapability},
+	"listen":    &Builtin{Name: "listen", Fn: Listen, Signature: "listen(fd, backlog)", Capability: NetworkCapability},
+	"connect":   &Builtin{Name: "connect", Fn: Connect, Signature: "connect(fd, address)", Capability: NetworkCapability},
+	"channel":   &Builtin{Name: "channel", Fn: ChannelOf, Signature: "channel([size])"},
+	"send":      &Builtin{Name: "send", Fn: Send, Signature: "send(channel, value)"},
+	"recv":      &Builtin{Name: "recv", Fn: Recv, Signature: "recv(channel)"},
+
+	"setblocking": &Builtin{Name: "setblocking", Fn: SetBlocking, Signature: "setblocking(fd, flag)", Capability: FilesystemCapability | NetworkCapability},
+	"register":    &Builtin{Name: "register", Fn: Register, Signature: "register(fd, events, callback)", Capability: FilesystemCapability | NetworkCapability},
+	"unregister":  &Builtin{Name: "unregister", Fn: Unregister, Signature: "unregister(fd)", Capability: FilesystemCapability | NetworkCapability},
+	"timer":       &Builtin{Name: "timer", Fn: Timer, Signature: "timer(ms, callback[, repeat])"},
+	"cancel":      &Builtin{Name: "cancel", Fn: Cancel, Signature: "cancel(id)"},
+	"run_loop":    &Builtin{Name: "run_loop", Fn: RunLoop, Signature: "run_loop()"},
+	"stop_loop":   &Builtin{Name: "stop_loop", Fn: StopLoop, Signature: "stop_loop()"},
+}
+
+// Arities is the number of arguments each builtin takes, as checked when it
+// is called, so calls can be checked without running a program
+var Arities = map[string]typing.Arity{
+	"len":       {Min: 1, Max: 1},
+	"input":     {Min: 0, Max: 1},
+	"print":     {Min: 1, Max: typing.Variadic},
+	"first":     {Min: 1, Max: 1},
+	"last":      {Min: 1, Max: 1},
+	"rest":      {Min: 1, Max: 1},
+	"push":      {Min: 2, Max: 2},
+	"pop":       {Min: 1, Max: 1},
+	"exit":      {Min: 0, Max: 1},
+	"assert":    {Min: 2, Max: 2},
+	"bool":      {Min: 1, Max: 1},
+	"int":       {Min: 1, Max: 1},
+	"float":     {Min: 1, Max: 1},
+	"str":       {Min: 1, Max: 1},
+	"type":      {Min: 1, Max: 1},
+	"args":      {Min: 0, Max: 0},
+	"getenv":    {Min: 1, Max: 1},
+	"lower":     {Min: 1, Max: 1},
+	"upper":     {Min: 1, Max: 1},
+	"join":      {Min: 2, Max: 2},
+	"split":     {Min: 1, Max: 2},
+	"find":      {Min: 2, Max: 2},
+	"readfile":  {Min: 1, Max: 1},
+	"writefile": {Min: 2, Max: 2},
+	"ffi":       {Min: 2, Max: 2},
+	"abs":       {Min: 1, Max: 1},
+	"bin":       {Min: 1, Max: 1},
+	"hex":       {Min: 1, Max: 1},
+	"ord":       {Min: 1, Max: 1},
+	"chr":       {Min: 1, Max: 1},
+	"divmod":    {Min: 2, Max: 2},
+	"hash":      {Min: 1, Max: 1},
+	"id":        {Min: 1, Max: 1},
+	"oct":       {Min: 1, Max: 1},
+	"pow":       {Min: 2, Max: 2},
+	"min":       {Min: 1, Max: 1},
+	"max":       {Min: 1, Max: 1},
+	"sorted":    {Min: 1, Max: 1},
+	"reversed":  {Min: 1, Max: 1},
+	"keys":      {Min: 1, Max: 1},
+	"values":    {Min: 1, Max: 1},
+	"items":     {Min: 1, Max: 1},
+	"range":     {Min: 1, Max: 3},
+	"open":      {Min: 1, Max: 2},
+	"close":     {Min: 1, Max: 1},
+	"write":     {Min: 2, Max: 2},
+	"read":      {Min: 1, Max: 2},
+	"seek":      {Min: 1, Max: 3},
+	"socket":    {Min: 1, Max: 1},
+	"bind":      {Min: 2, Max: 2},
+	"accept":    {Min: 1, Max: 1},
+	"listen":    {Min: 2, Max: 2},
+	"connect":   {Min: 2, Max: 2},
+	"channel":   {Min: 0, Max: 1},
+	"send":      {Min: 2, Max: 2},
+	"recv":      {Min: 1, Max: 1},
+
+	"setblocking": {Min: 2, Max: 2},
+	"register":    {Min: 3, Max: 3},
+	"unregister":  {Min: 1, Max: 1},
+	"timer":       {Min: 2, Max: 3},
+	"cancel":      {Min: 1, Max: 1},
+	"run_loop":    {Min: 0, Max: 0},
+	"stop_loop":   {Min: 0, Max: 0},
 }
 
 // BuiltinsIndex ...
@@ -76,3 +163,16 @@ func init() {
 func newError(format string, a ...interface{}) *Error {
 	return &Error{Message: fmt.Sprintf(format, a...)}
 }
+
+// checkFunction returns a TypeError if the argument at index i of the
+// builtin given by name is not a function, e.g: a callback
+func checkFunction(name string, args []Object, i int) *Error {
+	switch args[i].(type) {
+	case *Closure, *Function, *Builtin:
+		return nil
+	}
+	return newError(
+		"TypeError: %s() expected argument #%d to be `fn` got `%s`",
+		name, i+1, args[i].Type(),
+	)
+}
@@ -0,0 +1,21 @@
+package builtins
+
+import (
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/typing"
+)
+
+// Cancel ...
+func Cancel(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"cancel", args,
+		typing.ExactArgs(1),
+		typing.WithTypes(object.INTEGER),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	id := args[0].(*object.Integer).Value
+
+	return &object.Boolean{Value: ctx.Loop().Cancel(id)}
+}
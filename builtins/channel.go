@@ -0,0 +1,27 @@
+package builtins
+
+import (
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/typing"
+)
+
+// ChannelOf ...
+func ChannelOf(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"channel", args,
+		typing.RangeOfArgs(0, 1),
+		typing.WithTypes(object.INTEGER),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	size := 0
+	if len(args) == 1 {
+		size = int(args[0].(*object.Integer).Value)
+		if size < 0 {
+			return newError("ValueError: channel() size must not be negative")
+		}
+	}
+
+	return object.NewChannel(size)
+}
@@ -8,13 +8,13 @@ import (
 )
 
 // Chr ...
-func Chr(args ...object.Object) object.Object {
+func Chr(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"chr", args,
 		typing.ExactArgs(1),
 		typing.WithTypes(object.INTEGER),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	i := args[0].(*object.Integer)
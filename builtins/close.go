@@ -8,17 +8,37 @@ import (
 )
 
 // Close ...
-func Close(args ...object.Object) object.Object {
+func Close(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"close", args,
 		typing.ExactArgs(1),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	// Channels can be closed in a sandbox unlike file descriptors
+	if ch, ok := args[0].(*object.Channel); ok {
+		if err := ch.Close(); err != nil {
+			return newError("ChannelError: %s", err)
+		}
+		return &object.Null{}
+	}
+
+	if err := typing.Check(
+		"close", args,
 		typing.WithTypes(object.INTEGER),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
+	}
+	if err := ctx.Require("close", object.FilesystemCapability|object.NetworkCapability); err != nil {
+		return err
 	}
 
 	fd := int(args[0].(*object.Integer).Value)
 
+	// The event loop must not wait for a closed file descriptor
+	ctx.Loop().Unregister(fd)
+
 	err := syscall.Close(fd)
 	if err != nil {
 		return newError("IOError: %s", err)
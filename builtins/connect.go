@@ -8,42 +8,28 @@ import (
 )
 
 // Connect ...
-func Connect(args ...object.Object) object.Object {
+func Connect(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"connect", args,
 		typing.ExactArgs(2),
 		typing.WithTypes(object.INTEGER, object.STRING),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
-	var sa syscall.Sockaddr
-
 	fd := int(args[0].(*object.Integer).Value)
 	address := args[1].(*object.String).Value
 
-	sockaddr, err := syscall.Getsockname(fd)
+	sa, err := sockaddrOf(fd, address)
 	if err != nil {
 		return newError("ValueError: %s", err)
 	}
 
-	if _, ok := sockaddr.(*syscall.SockaddrInet4); ok {
-		addr, port, err := parseV4Address(address)
-		if err != nil {
-			return newError("ValueError: Invalid IPv4 address '%s': %s", address, err)
-		}
-		sa = &syscall.SockaddrInet4{Addr: addr, Port: port}
-	} else if _, ok := sockaddr.(*syscall.SockaddrInet6); ok {
-		addr, port, err := parseV6Address(address)
-		if err != nil {
-			return newError("ValueError: Invalid IPv6 address '%s': %s", address, err)
-		}
-		sa = &syscall.SockaddrInet6{Addr: addr, Port: port}
-	} else {
-		return newError("ValueError: Invalid socket type %T for bind '%s'", sockaddr, address)
-	}
-
-	if err = syscall.Connect(fd, sa); err != nil {
+	ctx.Release()
+	err = syscall.Connect(fd, sa)
+	ctx.Acquire()
+	// Non-blocking sockets become writable once connected
+	if err != nil && err != syscall.EINPROGRESS {
 		return newError("SocketError: %s", err)
 	}
 
@@ -6,17 +6,20 @@ import (
 )
 
 // Divmod ...
-func Divmod(args ...object.Object) object.Object {
+func Divmod(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"divmod", args,
 		typing.ExactArgs(2),
 		typing.WithTypes(object.INTEGER, object.INTEGER),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	a := args[0].(*object.Integer)
 	b := args[1].(*object.Integer)
+	if b.Value == 0 {
+		return newError("ZeroDivisionError: integer division or modulo by zero")
+	}
 	elements := make([]object.Object, 2)
 	elements[0] = &object.Integer{Value: a.Value / b.Value}
 	elements[1] = &object.Integer{Value: a.Value % b.Value}
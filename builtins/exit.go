@@ -6,13 +6,13 @@ import (
 )
 
 // Exit ...
-func Exit(args ...object.Object) object.Object {
+func Exit(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"exit", args,
 		typing.RangeOfArgs(0, 1),
 		typing.WithTypes(object.INTEGER),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	var status int
@@ -20,7 +20,7 @@ func Exit(args ...object.Object) object.Object {
 		status = int(args[0].(*object.Integer).Value)
 	}
 
-	object.ExitFunction(status)
+	ctx.Exit(status)
 
 	return nil
 }
@@ -9,13 +9,13 @@ import (
 )
 
 // FFI ...
-func FFI(args ...object.Object) object.Object {
+func FFI(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"ffi", args,
 		typing.ExactArgs(2),
 		typing.WithTypes(object.STRING, object.STRING),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	name := args[0].(*object.String).Value
@@ -31,5 +31,18 @@ func FFI(args ...object.Object) object.Object {
 		return newError("error finding symbol: %s", err)
 	}
 
-	return &object.Builtin{Name: symbol, Fn: v.(object.BuiltinFunction)}
+	switch fn := v.(type) {
+	case func(*object.Context, ...object.Object) object.Object:
+		return &object.Builtin{Name: symbol, Fn: fn}
+	case func(...object.Object) object.Object:
+		// Functions written before builtins were passed a context
+		return &object.Builtin{
+			Name: symbol,
+			Fn: func(ctx *object.Context, args ...object.Object) object.Object {
+				return fn(args...)
+			},
+		}
+	default:
+		return newError("TypeError: symbol '%s' is not a builtin function", symbol)
+	}
 }
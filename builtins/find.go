@@ -9,12 +9,12 @@ import (
 )
 
 // Find ...
-func Find(args ...object.Object) object.Object {
+func Find(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"find", args,
 		typing.ExactArgs(2),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	// find("foobar", "bo")
@@ -23,7 +23,7 @@ func Find(args ...object.Object) object.Object {
 			"find", args,
 			typing.WithTypes(object.STRING, object.STRING),
 		); err != nil {
-			return newError(err.Error())
+			return newError("%s", err)
 		}
 
 		needle := args[1].(*object.String)
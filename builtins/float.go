@@ -0,0 +1,38 @@
+package builtins
+
+import (
+	"strconv"
+
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/typing"
+)
+
+// FloatOf ...
+func FloatOf(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"float", args,
+		typing.ExactArgs(1),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	switch arg := args[0].(type) {
+	case *object.Boolean:
+		if arg.Value {
+			return &object.Float{Value: 1}
+		}
+		return &object.Float{Value: 0}
+	case *object.Integer:
+		return &object.Float{Value: float64(arg.Value)}
+	case *object.Float:
+		return arg
+	case *object.String:
+		n, err := strconv.ParseFloat(arg.Value, 64)
+		if err != nil {
+			return newError("could not parse string to float: %s", err)
+		}
+		return &object.Float{Value: n}
+	default:
+		return &object.Float{}
+	}
+}
@@ -0,0 +1,23 @@
+package builtins
+
+import (
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/typing"
+)
+
+// Getenv ...
+func Getenv(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"getenv", args,
+		typing.ExactArgs(1),
+		typing.WithTypes(object.STRING),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	value, ok := ctx.Getenv(args[0].(*object.String).Value)
+	if !ok {
+		return &object.Null{}
+	}
+	return &object.String{Value: value}
+}
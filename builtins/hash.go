@@ -6,15 +6,15 @@ import (
 )
 
 // HashOf ...
-func HashOf(args ...object.Object) object.Object {
+func HashOf(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"hash", args,
 		typing.ExactArgs(1),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
-	if hash, ok := args[0].(object.Hashable); ok {
+	if hash, ok := object.AsHashable(args[0]); ok {
 		return &object.Integer{Value: int64(hash.HashKey().Value)}
 	}
 
@@ -0,0 +1,446 @@
+package builtins
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/typing"
+)
+
+// HTTPModule is the `http` module of an HTTP/1.1 client and server
+var HTTPModule = map[string]*object.Builtin{
+	"get":     &object.Builtin{Name: "http.get", Fn: HTTPGet, Signature: "http.get(url[, headers])", Capability: object.NetworkCapability},
+	"post":    &object.Builtin{Name: "http.post", Fn: HTTPPost, Signature: "http.post(url, body[, headers])", Capability: object.NetworkCapability},
+	"request": &object.Builtin{Name: "http.request", Fn: HTTPRequest, Signature: "http.request(method, url[, body[, headers]])", Capability: object.NetworkCapability},
+	"listen":  &object.Builtin{Name: "http.listen", Fn: HTTPListen, Signature: "http.listen(address, routes)", Capability: object.NetworkCapability},
+}
+
+// wildcard matches the wildcards of a route pattern, e.g: `{id}` or `{path...}`
+var wildcard = regexp.MustCompile(`{([A-Za-z_][A-Za-z0-9_]*)(?:\.\.\.)?}`)
+
+// HTTPGet ...
+func HTTPGet(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"http.get", args,
+		typing.RangeOfArgs(1, 2),
+		typing.WithTypes(object.STRING, object.HASH),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	var headers *object.Hash
+	if len(args) == 2 {
+		headers = args[1].(*object.Hash)
+	}
+
+	return doRequest(ctx, "http.get", "GET", args[0].(*object.String).Value, nil, headers)
+}
+
+// HTTPPost ...
+func HTTPPost(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"http.post", args,
+		typing.RangeOfArgs(2, 3),
+		typing.WithTypes(object.STRING, object.STRING, object.HASH),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	var headers *object.Hash
+	if len(args) == 3 {
+		headers = args[2].(*object.Hash)
+	}
+
+	body := args[1].(*object.String).Value
+	return doRequest(ctx, "http.post", "POST", args[0].(*object.String).Value, &body, headers)
+}
+
+// HTTPRequest ...
+func HTTPRequest(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"http.request", args,
+		typing.RangeOfArgs(2, 4),
+		typing.WithTypes(object.STRING, object.STRING, object.STRING, object.HASH),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	var (
+		body    *string
+		headers *object.Hash
+	)
+	if len(args) >= 3 {
+		body = &args[2].(*object.String).Value
+	}
+	if len(args) == 4 {
+		headers = args[3].(*object.Hash)
+	}
+
+	method := strings.ToUpper(args[0].(*object.String).Value)
+	return doRequest(ctx, "http.request", method, args[1].(*object.String).Value, body, headers)
+}
+
+// doRequest makes an HTTP request with an optional body and headers and
+// returns the response as a hash of its `status`, `headers` and `body`
+func doRequest(ctx *object.Context, name, method, url string, body *string, headers *object.Hash) object.Object {
+	var r io.Reader
+	if body != nil {
+		r = strings.NewReader(*body)
+	}
+
+	req, err := http.NewRequest(method, url, r)
+	if err != nil {
+		return newError("ValueError: %s", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	if err := setHeaders(req.Header, headers); err != nil {
+		return newError("TypeError: %s() %s", name, err)
+	}
+
+	ctx.Release()
+	resp, err := http.DefaultClient.Do(req)
+	var data []byte
+	if err == nil {
+		data, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	ctx.Acquire()
+	if err != nil {
+		return newError("HTTPError: %s", err)
+	}
+
+	return response(resp.StatusCode, resp.Header, string(data))
+}
+
+// setHeaders sets the headers given by a hash of `str` names to values,
+// which are converted to strings, on h
+func setHeaders(h http.Header, headers *object.Hash) error {
+	if headers == nil {
+		return nil
+	}
+	for _, pair := range headers.Pairs() {
+		key, ok := pair.Key.(*object.String)
+		if !ok {
+			return fmt.Errorf("expected header names to be `str` got `%s`", pair.Key.Type())
+		}
+		h.Set(key.Value, pair.Value.String())
+	}
+	return nil
+}
+
+// headersHash returns the headers h as a hash of names to values with the
+// values of repeated headers joined by commas
+func headersHash(h http.Header) *object.Hash {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hash := object.NewHash(len(names))
+	for _, name := range names {
+		hash.Set(&object.String{Value: name}, &object.String{Value: strings.Join(h[name], ", ")})
+	}
+	return hash
+}
+
+// response returns a response as a hash of its `status`, `headers` and `body`
+func response(status int, headers http.Header, body string) *object.Hash {
+	hash := object.NewHash(3)
+	hash.Set(&object.String{Value: "status"}, &object.Integer{Value: int64(status)})
+	hash.Set(&object.String{Value: "headers"}, headersHash(headers))
+	hash.Set(&object.String{Value: "body"}, &object.String{Value: body})
+	return hash
+}
+
+// httpResponse is the response to a request made to a server
+type httpResponse struct {
+	status  int
+	headers http.Header
+	body    string
+}
+
+// httpRequest is a request made to a server, handled by calling the
+// handler of its route as a task and sending the response on reply
+type httpRequest struct {
+	handler object.Object
+	request *object.Hash
+	reply   chan httpResponse
+}
+
+// httpServer is a server returned by `http.listen()` which handles the
+// requests it accepts, in the background, while `serve()` is running
+type httpServer struct {
+	server   *http.Server
+	listener net.Listener
+	requests chan *httpRequest
+	done     chan struct{}
+	once     sync.Once
+	serving  bool
+}
+
+// HTTPListen ...
+func HTTPListen(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"http.listen", args,
+		typing.ExactArgs(2),
+		typing.WithTypes(object.STRING, object.HASH),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	address := args[0].(*object.String).Value
+	routes := args[1].(*object.Hash)
+
+	s := &httpServer{
+		requests: make(chan *httpRequest),
+		done:     make(chan struct{}),
+	}
+
+	mux := http.NewServeMux()
+	for _, pair := range routes.Pairs() {
+		pattern, ok := pair.Key.(*object.String)
+		if !ok {
+			return newError(
+				"TypeError: http.listen() expected routes to be `str` got `%s`",
+				pair.Key.Type(),
+			)
+		}
+		switch pair.Value.(type) {
+		case *object.Closure, *object.Function, *object.Builtin:
+		default:
+			return newError(
+				"TypeError: http.listen() expected the handler of route '%s' to be `fn` got `%s`",
+				pattern.Value, pair.Value.Type(),
+			)
+		}
+		if err := handle(mux, pattern.Value, s.handler(pattern.Value, pair.Value)); err != nil {
+			return newError("ValueError: %s", err)
+		}
+	}
+
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return newError("SocketError: %s", err)
+	}
+	s.listener = ln
+	s.server = &http.Server{Handler: mux}
+
+	server := object.NewHash(3)
+	server.Set(&object.String{Value: "address"}, &object.String{Value: ln.Addr().String()})
+	server.Set(&object.String{Value: "serve"}, &object.Builtin{
+		Name: "serve", Fn: s.serve, Signature: "serve()", Capability: object.NetworkCapability,
+	})
+	server.Set(&object.String{Value: "close"}, &object.Builtin{
+		Name: "close", Fn: s.close, Signature: "close()", Capability: object.NetworkCapability,
+	})
+	return server
+}
+
+// handle registers handler for the route pattern on mux and returns an
+// error, rather than panicking, if the pattern is invalid
+func handle(mux *http.ServeMux, pattern string, handler http.Handler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid route '%s': %v", pattern, r)
+		}
+	}()
+	mux.Handle(pattern, handler)
+	return nil
+}
+
+// handler returns the handler of the route pattern which passes requests
+// to `serve()` and waits for the response of the route's handler fn
+func (s *httpServer) handler(pattern string, fn object.Object) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		req := &httpRequest{
+			handler: fn,
+			request: request(pattern, r, string(body)),
+			reply:   make(chan httpResponse, 1),
+		}
+
+		select {
+		case s.requests <- req:
+		case <-s.done:
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+
+		resp := <-req.reply
+		for name, values := range resp.headers {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(resp.status)
+		io.WriteString(w, resp.body)
+	}
+}
+
+// request returns the request r to the route pattern as a hash
+func request(pattern string, r *http.Request, body string) *object.Hash {
+	query := r.URL.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	queryHash := object.NewHash(len(keys))
+	for _, key := range keys {
+		queryHash.Set(&object.String{Value: key}, &object.String{Value: query.Get(key)})
+	}
+
+	params := object.NewHash(0)
+	for _, match := range wildcard.FindAllStringSubmatch(pattern, -1) {
+		params.Set(&object.String{Value: match[1]}, &object.String{Value: r.PathValue(match[1])})
+	}
+
+	hash := object.NewHash(7)
+	hash.Set(&object.String{Value: "method"}, &object.String{Value: r.Method})
+	hash.Set(&object.String{Value: "path"}, &object.String{Value: r.URL.Path})
+	hash.Set(&object.String{Value: "query"}, queryHash)
+	hash.Set(&object.String{Value: "params"}, params)
+	hash.Set(&object.String{Value: "headers"}, headersHash(r.Header))
+	hash.Set(&object.String{Value: "body"}, &object.String{Value: body})
+	hash.Set(&object.String{Value: "remote"}, &object.String{Value: r.RemoteAddr})
+	return hash
+}
+
+// serve accepts and handles requests, calling the handler of each as a
+// task, until the server is closed
+func (s *httpServer) serve(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"serve", args,
+		typing.ExactArgs(0),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	if s.serving {
+		return newError("HTTPError: the server is already serving")
+	}
+	s.serving = true
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- s.server.Serve(s.listener)
+	}()
+
+	for {
+		ctx.Release()
+		select {
+		case req := <-s.requests:
+			ctx.Acquire()
+			ctx.Spawn(func() {
+				req.reply <- handleRequest(ctx, req)
+			})
+		case err := <-errs:
+			ctx.Acquire()
+			if errors.Is(err, http.ErrServerClosed) {
+				return &object.Null{}
+			}
+			return newError("HTTPError: %s", err)
+		}
+	}
+}
+
+// close stops the server from accepting requests and closes its connections
+func (s *httpServer) close(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"close", args,
+		typing.ExactArgs(0),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	s.once.Do(func() {
+		close(s.done)
+		s.server.Close()
+		// The listener is only closed by the server once it is serving
+		s.listener.Close()
+	})
+
+	return &object.Null{}
+}
+
+// handleRequest calls the handler of the request and returns its response.
+// Errors not caught by the handler are printed to the standard error of the
+// execution context and result in an internal server error.
+func handleRequest(ctx *object.Context, req *httpRequest) httpResponse {
+	result := ctx.Call(req.handler, req.request)
+	if err, ok := result.(*object.Error); ok && !err.Caught {
+		fmt.Fprintln(ctx.Stderr, err.String())
+		return internalServerError()
+	}
+
+	resp, err := toResponse(result)
+	if err != nil {
+		fmt.Fprintf(ctx.Stderr, "TypeError: %s\n", err)
+		return internalServerError()
+	}
+	return resp
+}
+
+// internalServerError returns the response for a handler that failed
+func internalServerError() httpResponse {
+	return httpResponse{
+		status: http.StatusInternalServerError,
+		body:   http.StatusText(http.StatusInternalServerError) + "\n",
+	}
+}
+
+// toResponse returns the response for the value returned by a handler,
+// either a `str` body, `null` for an empty body, or a hash of the `status`,
+// `headers` and `body` of the response
+func toResponse(result object.Object) (httpResponse, error) {
+	resp := httpResponse{status: http.StatusOK, headers: http.Header{}}
+
+	switch result := result.(type) {
+	case *object.Null:
+		return resp, nil
+	case *object.String:
+		resp.body = result.Value
+		return resp, nil
+	case *object.Hash:
+		if pair, ok := result.Get(&object.String{Value: "status"}); ok {
+			status, ok := pair.Value.(*object.Integer)
+			if !ok || status.Value < 100 || status.Value > 999 {
+				return resp, fmt.Errorf("invalid response status `%s`", pair.Value.Inspect())
+			}
+			resp.status = int(status.Value)
+		}
+		if pair, ok := result.Get(&object.String{Value: "headers"}); ok {
+			headers, ok := pair.Value.(*object.Hash)
+			if !ok {
+				return resp, fmt.Errorf("expected response headers to be `hash` got `%s`", pair.Value.Type())
+			}
+			if err := setHeaders(resp.headers, headers); err != nil {
+				return resp, err
+			}
+		}
+		if pair, ok := result.Get(&object.String{Value: "body"}); ok {
+			body, ok := pair.Value.(*object.String)
+			if !ok {
+				return resp, fmt.Errorf("expected response body to be `str` got `%s`", pair.Value.Type())
+			}
+			resp.body = body.Value
+		}
+		return resp, nil
+	default:
+		return resp, fmt.Errorf("expected handler to return `str`, `hash` or `null` got `%s`", result.Type())
+	}
+}
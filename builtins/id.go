@@ -8,12 +8,12 @@ import (
 )
 
 // IdOf ...
-func IdOf(args ...object.Object) object.Object {
+func IdOf(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"id", args,
 		typing.ExactArgs(1),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	arg := args[0]
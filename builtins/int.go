@@ -8,12 +8,12 @@ import (
 )
 
 // Int ...
-func Int(args ...object.Object) object.Object {
+func Int(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"int", args,
 		typing.ExactArgs(1),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	switch arg := args[0].(type) {
@@ -24,6 +24,8 @@ func Int(args ...object.Object) object.Object {
 		return &object.Integer{Value: 0}
 	case *object.Integer:
 		return arg
+	case *object.Float:
+		return &object.Integer{Value: int64(arg.Value)}
 	case *object.String:
 		n, err := strconv.ParseInt(arg.Value, 10, 64)
 		if err != nil {
@@ -0,0 +1,24 @@
+package builtins
+
+import (
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/typing"
+)
+
+// Items ...
+func Items(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"items", args,
+		typing.ExactArgs(1),
+		typing.WithTypes(object.HASH),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	hash := args[0].(*object.Hash)
+	elements := make([]object.Object, hash.Len())
+	for i, pair := range hash.Pairs() {
+		elements[i] = &object.Array{Elements: []object.Object{pair.Key, pair.Value}}
+	}
+	return &object.Array{Elements: elements}
+}
@@ -8,13 +8,13 @@ import (
 )
 
 // Join ...
-func Join(args ...object.Object) object.Object {
+func Join(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"join", args,
 		typing.ExactArgs(2),
 		typing.WithTypes(object.ARRAY, object.STRING),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	arr := args[0].(*object.Array)
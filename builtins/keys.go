@@ -0,0 +1,20 @@
+package builtins
+
+import (
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/typing"
+)
+
+// Keys ...
+func Keys(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"keys", args,
+		typing.ExactArgs(1),
+		typing.WithTypes(object.HASH),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	hash := args[0].(*object.Hash)
+	return &object.Array{Elements: hash.Keys()}
+}
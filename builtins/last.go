@@ -6,13 +6,13 @@ import (
 )
 
 // Last ...
-func Last(args ...object.Object) object.Object {
+func Last(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"last", args,
 		typing.ExactArgs(1),
 		typing.WithTypes(object.ARRAY),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	arr := args[0].(*object.Array)
@@ -6,12 +6,12 @@ import (
 )
 
 // Len ...
-func Len(args ...object.Object) object.Object {
+func Len(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"len", args,
 		typing.ExactArgs(1),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	if size, ok := args[0].(object.Sizeable); ok {
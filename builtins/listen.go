@@ -8,13 +8,13 @@ import (
 )
 
 // Listen ...
-func Listen(args ...object.Object) object.Object {
+func Listen(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"listen", args,
 		typing.ExactArgs(2),
 		typing.WithTypes(object.INTEGER, object.INTEGER),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	fd := int(args[0].(*object.Integer).Value)
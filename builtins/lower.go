@@ -8,13 +8,13 @@ import (
 )
 
 // Lower ...
-func Lower(args ...object.Object) object.Object {
+func Lower(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"lower", args,
 		typing.ExactArgs(1),
 		typing.WithTypes(object.STRING),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	str := args[0].(*object.String)
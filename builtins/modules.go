@@ -0,0 +1,37 @@
+package builtins
+
+import (
+	"sort"
+
+	. "github.com/prologic/monkey-lang/object"
+)
+
+// Modules are the modules implemented in Go which are imported by name
+// with `import()` as Monkey modules are, e.g: `http := import("http")`,
+// unless a Monkey module of the same name is found first. Their attributes
+// are builtins and, unlike those of Monkey modules, are not capitalized.
+var Modules = map[string]map[string]*Builtin{
+	"net":  NetModule,
+	"http": HTTPModule,
+}
+
+// LookupModule returns the attributes of the module implemented in Go given by
+// name, false is returned if there is no such module
+func LookupModule(name string) (*Hash, bool) {
+	module, ok := Modules[name]
+	if !ok {
+		return nil, false
+	}
+
+	var names []string
+	for name := range module {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	attrs := NewHash(len(names))
+	for _, name := range names {
+		attrs.Set(&String{Value: name}, module[name])
+	}
+	return attrs, true
+}
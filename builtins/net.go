@@ -0,0 +1,227 @@
+package builtins
+
+import (
+	"net"
+	"os"
+	"syscall"
+
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/typing"
+)
+
+// NetModule is the `net` module of helpers for TCP and UDP sockets which
+// return file descriptors used with `read()`, `write()`, `accept()`,
+// `close()` and the event loop
+var NetModule = map[string]*object.Builtin{
+	"dial":     &object.Builtin{Name: "net.dial", Fn: NetDial, Signature: "net.dial(network, address)", Capability: object.NetworkCapability},
+	"listen":   &object.Builtin{Name: "net.listen", Fn: NetListen, Signature: "net.listen(network, address)", Capability: object.NetworkCapability},
+	"address":  &object.Builtin{Name: "net.address", Fn: NetAddress, Signature: "net.address(fd)", Capability: object.NetworkCapability},
+	"peer":     &object.Builtin{Name: "net.peer", Fn: NetPeer, Signature: "net.peer(fd)", Capability: object.NetworkCapability},
+	"sendto":   &object.Builtin{Name: "net.sendto", Fn: NetSendTo, Signature: "net.sendto(fd, data, address)", Capability: object.NetworkCapability},
+	"recvfrom": &object.Builtin{Name: "net.recvfrom", Fn: NetRecvFrom, Signature: "net.recvfrom(fd[, n])", Capability: object.NetworkCapability},
+}
+
+// networks are the networks supported by the net module
+var networks = map[string]bool{
+	"tcp": true, "tcp4": true, "tcp6": true,
+	"udp": true, "udp4": true, "udp6": true,
+}
+
+// filer is a connection or listener whose file descriptor can be used
+type filer interface {
+	File() (*os.File, error)
+	Close() error
+}
+
+// fileDescriptor closes the connection or listener c and returns a
+// duplicate of its file descriptor in blocking mode owned by the program
+func fileDescriptor(c filer) (int, error) {
+	defer c.Close()
+
+	f, err := c.File()
+	if err != nil {
+		return -1, err
+	}
+	defer f.Close()
+
+	return syscall.Dup(int(f.Fd()))
+}
+
+// NetDial ...
+func NetDial(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"net.dial", args,
+		typing.ExactArgs(2),
+		typing.WithTypes(object.STRING, object.STRING),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	network := args[0].(*object.String).Value
+	address := args[1].(*object.String).Value
+	if !networks[network] {
+		return newError("ValueError: unknown network '%s'", network)
+	}
+
+	ctx.Release()
+	conn, err := net.Dial(network, address)
+	ctx.Acquire()
+	if err != nil {
+		return newError("SocketError: %s", err)
+	}
+
+	fd, err := fileDescriptor(conn.(filer))
+	if err != nil {
+		return newError("SocketError: %s", err)
+	}
+
+	return &object.Integer{Value: int64(fd)}
+}
+
+// NetListen ...
+func NetListen(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"net.listen", args,
+		typing.ExactArgs(2),
+		typing.WithTypes(object.STRING, object.STRING),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	network := args[0].(*object.String).Value
+	address := args[1].(*object.String).Value
+	if !networks[network] {
+		return newError("ValueError: unknown network '%s'", network)
+	}
+
+	var (
+		c   filer
+		err error
+	)
+	if network[:3] == "udp" {
+		var conn net.PacketConn
+		conn, err = net.ListenPacket(network, address)
+		if err == nil {
+			c = conn.(filer)
+		}
+	} else {
+		var ln net.Listener
+		ln, err = net.Listen(network, address)
+		if err == nil {
+			c = ln.(filer)
+		}
+	}
+	if err != nil {
+		return newError("SocketError: %s", err)
+	}
+
+	fd, err := fileDescriptor(c)
+	if err != nil {
+		return newError("SocketError: %s", err)
+	}
+
+	return &object.Integer{Value: int64(fd)}
+}
+
+// NetAddress ...
+func NetAddress(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"net.address", args,
+		typing.ExactArgs(1),
+		typing.WithTypes(object.INTEGER),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	fd := int(args[0].(*object.Integer).Value)
+
+	sa, err := syscall.Getsockname(fd)
+	if err != nil {
+		return newError("SocketError: %s", err)
+	}
+
+	return &object.String{Value: formatSockaddr(sa)}
+}
+
+// NetPeer ...
+func NetPeer(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"net.peer", args,
+		typing.ExactArgs(1),
+		typing.WithTypes(object.INTEGER),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	fd := int(args[0].(*object.Integer).Value)
+
+	sa, err := syscall.Getpeername(fd)
+	if err != nil {
+		return newError("SocketError: %s", err)
+	}
+
+	return &object.String{Value: formatSockaddr(sa)}
+}
+
+// NetSendTo ...
+func NetSendTo(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"net.sendto", args,
+		typing.ExactArgs(3),
+		typing.WithTypes(object.INTEGER, object.STRING, object.STRING),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	fd := int(args[0].(*object.Integer).Value)
+	data := []byte(args[1].(*object.String).Value)
+	address := args[2].(*object.String).Value
+
+	sa, err := sockaddrOf(fd, address)
+	if err != nil {
+		return newError("ValueError: %s", err)
+	}
+
+	ctx.Release()
+	err = syscall.Sendto(fd, data, 0, sa)
+	ctx.Acquire()
+	if err != nil {
+		return newError("SocketError: %s", err)
+	}
+
+	return &object.Integer{Value: int64(len(data))}
+}
+
+// NetRecvFrom ...
+func NetRecvFrom(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"net.recvfrom", args,
+		typing.RangeOfArgs(1, 2),
+		typing.WithTypes(object.INTEGER, object.INTEGER),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	fd := int(args[0].(*object.Integer).Value)
+	n := DefaultBufferSize
+	if len(args) == 2 {
+		n = int(args[1].(*object.Integer).Value)
+	}
+
+	buf := make([]byte, n)
+	ctx.Release()
+	n, from, err := syscall.Recvfrom(fd, buf, 0)
+	ctx.Acquire()
+	if err == syscall.EAGAIN {
+		// Nothing to receive on the non-blocking socket yet
+		return &object.Null{}
+	}
+	if err != nil {
+		return newError("SocketError: %s", err)
+	}
+
+	return &object.Array{Elements: []object.Object{
+		&object.String{Value: string(buf[:n])},
+		&object.String{Value: formatSockaddr(from)},
+	}}
+}
@@ -0,0 +1,45 @@
+package builtins
+
+import (
+	"fmt"
+
+	"github.com/prologic/monkey-lang/object"
+)
+
+// toFloat converts the numeric argument #n of the builtin name to a float64
+// promoting integers as needed
+func toFloat(name string, n int, arg object.Object) (float64, error) {
+	switch arg := arg.(type) {
+	case *object.Integer:
+		return float64(arg.Value), nil
+	case *object.Float:
+		return arg.Value, nil
+	default:
+		return 0, fmt.Errorf(
+			"TypeError: %s() expected argument #%d to be `int` or `float` got `%s`",
+			name, n, arg.Type(),
+		)
+	}
+}
+
+// extremum returns the smallest (sign < 0) or largest (sign > 0) number in
+// the array argument of the builtin name. Integers and floats may be mixed
+// and the original element is returned unchanged.
+func extremum(name string, a *object.Array, sign int) object.Object {
+	if len(a.Elements) == 0 {
+		return newError("ValueError: %s() arg is an empty array", name)
+	}
+
+	var result object.Object
+	for n, e := range a.Elements {
+		if e.Type() != object.INTEGER && e.Type() != object.FLOAT {
+			return newError(
+				"item #%d not an `int` or `float` got=%s", n, e.Type(),
+			)
+		}
+		if result == nil || e.(object.Comparable).Compare(result) == sign {
+			result = e
+		}
+	}
+	return result
+}
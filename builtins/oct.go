@@ -9,13 +9,13 @@ import (
 )
 
 // Oct ...
-func Oct(args ...object.Object) object.Object {
+func Oct(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"oct", args,
 		typing.ExactArgs(1),
 		typing.WithTypes(object.INTEGER),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	i := args[0].(*object.Integer)
@@ -47,13 +47,13 @@ func parseMode(mode string) (int, error) {
 }
 
 // Open ...
-func Open(args ...object.Object) object.Object {
+func Open(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"open", args,
 		typing.RangeOfArgs(1, 2),
 		typing.WithTypes(object.STRING, object.STRING),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	var (
@@ -70,7 +70,7 @@ func Open(args ...object.Object) object.Object {
 
 	flag, err := parseMode(mode)
 	if err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	fd, err := syscall.Open(filename, flag, perm)
@@ -6,13 +6,13 @@ import (
 )
 
 // Ord ...
-func Ord(args ...object.Object) object.Object {
+func Ord(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"ord", args,
 		typing.ExactArgs(1),
 		typing.WithTypes(object.STRING),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	s := args[0].(*object.String)
@@ -6,13 +6,13 @@ import (
 )
 
 // Pop ...
-func Pop(args ...object.Object) object.Object {
+func Pop(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"pop", args,
 		typing.ExactArgs(1),
 		typing.WithTypes(object.ARRAY),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	arr := args[0].(*object.Array)
@@ -1,6 +1,8 @@
 package builtins
 
 import (
+	"math"
+
 	"github.com/prologic/monkey-lang/object"
 	"github.com/prologic/monkey-lang/typing"
 )
@@ -18,17 +20,28 @@ func pow(x, y int64) int64 {
 }
 
 // Pow ...
-func Pow(args ...object.Object) object.Object {
+func Pow(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"pow", args,
 		typing.ExactArgs(2),
-		typing.WithTypes(object.INTEGER, object.INTEGER),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
+	}
+
+	x, ok := args[0].(*object.Integer)
+	y, ok2 := args[1].(*object.Integer)
+	if ok && ok2 && y.Value >= 0 {
+		return &object.Integer{Value: pow(x.Value, y.Value)}
 	}
 
-	x := args[0].(*object.Integer)
-	y := args[1].(*object.Integer)
-	value := pow(x.Value, y.Value)
-	return &object.Integer{Value: value}
+	// Any float argument (or a negative exponent) yields a float result
+	fx, err := toFloat("pow", 1, args[0])
+	if err != nil {
+		return newError("%s", err)
+	}
+	fy, err := toFloat("pow", 2, args[1])
+	if err != nil {
+		return newError("%s", err)
+	}
+	return &object.Float{Value: math.Pow(fx, fy)}
 }
@@ -8,15 +8,15 @@ import (
 )
 
 // Print ...
-func Print(args ...object.Object) object.Object {
+func Print(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"print", args,
 		typing.MinimumArgs(1),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
-	fmt.Println(args[0].String())
+	fmt.Fprintln(ctx.Stdout, args[0].String())
 
 	return nil
 }
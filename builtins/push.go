@@ -6,13 +6,13 @@ import (
 )
 
 // Push ...
-func Push(args ...object.Object) object.Object {
+func Push(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"push", args,
 		typing.ExactArgs(2),
 		typing.WithTypes(object.ARRAY),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	arr := args[0].(*object.Array)
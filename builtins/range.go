@@ -0,0 +1,32 @@
+package builtins
+
+import (
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/typing"
+)
+
+// RangeOf ...
+func RangeOf(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"range", args,
+		typing.RangeOfArgs(1, 3),
+		typing.WithTypes(object.INTEGER, object.INTEGER, object.INTEGER),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	r := &object.Range{Step: 1}
+	if len(args) == 1 {
+		r.Stop = args[0].(*object.Integer).Value
+	} else {
+		r.Start = args[0].(*object.Integer).Value
+		r.Stop = args[1].(*object.Integer).Value
+	}
+	if len(args) == 3 {
+		r.Step = args[2].(*object.Integer).Value
+		if r.Step == 0 {
+			return newError("ValueError: range() arg 3 must not be zero")
+		}
+	}
+	return r
+}
@@ -11,13 +11,13 @@ import (
 const DefaultBufferSize = 4096
 
 // Read ...
-func Read(args ...object.Object) object.Object {
+func Read(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"read", args,
 		typing.RangeOfArgs(1, 2),
 		typing.WithTypes(object.INTEGER, object.INTEGER),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	var (
@@ -32,7 +32,13 @@ func Read(args ...object.Object) object.Object {
 	}
 
 	buf := make([]byte, n)
+	ctx.Release()
 	n, err := syscall.Read(fd, buf)
+	ctx.Acquire()
+	if err == syscall.EAGAIN {
+		// Nothing to read from the non-blocking file descriptor yet
+		return &object.Null{}
+	}
 	if err != nil {
 		return newError("IOError: %s", err)
 	}
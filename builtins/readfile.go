@@ -8,13 +8,13 @@ import (
 )
 
 // ReadFile ...
-func ReadFile(args ...object.Object) object.Object {
+func ReadFile(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"readfile", args,
 		typing.ExactArgs(1),
 		typing.WithTypes(object.STRING),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	filename := args[0].(*object.String).Value
@@ -0,0 +1,24 @@
+package builtins
+
+import (
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/typing"
+)
+
+// Recv ...
+func Recv(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"recv", args,
+		typing.ExactArgs(1),
+		typing.WithTypes(object.CHANNEL),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	obj, ok := args[0].(*object.Channel).Recv(ctx)
+	if !ok {
+		return &object.Null{}
+	}
+
+	return obj
+}
@@ -0,0 +1,32 @@
+package builtins
+
+import (
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/typing"
+)
+
+// Register ...
+func Register(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"register", args,
+		typing.ExactArgs(3),
+		typing.WithTypes(object.INTEGER, object.STRING),
+	); err != nil {
+		return newError("%s", err)
+	}
+	if err := checkFunction("register", args, 2); err != nil {
+		return err
+	}
+
+	fd := int(args[0].(*object.Integer).Value)
+	events, err := object.ParseEvents(args[1].(*object.String).Value)
+	if err != nil {
+		return newError("ValueError: %s", err)
+	}
+
+	if err := ctx.Loop().Register(fd, events, args[2]); err != nil {
+		return newError("IOError: %s", err)
+	}
+
+	return &object.Null{}
+}
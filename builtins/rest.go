@@ -6,13 +6,13 @@ import (
 )
 
 // Rest ...
-func Rest(args ...object.Object) object.Object {
+func Rest(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"rest", args,
 		typing.ExactArgs(1),
 		typing.WithTypes(object.ARRAY),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	arr := args[0].(*object.Array)
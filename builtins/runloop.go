@@ -0,0 +1,26 @@
+package builtins
+
+import (
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/typing"
+)
+
+// RunLoop ...
+func RunLoop(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"run_loop", args,
+		typing.ExactArgs(0),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	// Errors raised by callbacks are raised again by run_loop()
+	switch err := ctx.Loop().Run(ctx).(type) {
+	case nil:
+		return &object.Null{}
+	case *object.Error:
+		return err
+	default:
+		return newError("RuntimeError: %s", err)
+	}
+}
@@ -8,13 +8,13 @@ import (
 )
 
 // Seek ...
-func Seek(args ...object.Object) object.Object {
+func Seek(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"seek", args,
 		typing.RangeOfArgs(1, 3),
 		typing.WithTypes(object.INTEGER, object.INTEGER, object.INTEGER),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	var (
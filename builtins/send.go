@@ -0,0 +1,23 @@
+package builtins
+
+import (
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/typing"
+)
+
+// Send ...
+func Send(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"send", args,
+		typing.ExactArgs(2),
+		typing.WithTypes(object.CHANNEL),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	if err := args[0].(*object.Channel).Send(ctx, args[1]); err != nil {
+		return newError("ChannelError: %s", err)
+	}
+
+	return &object.Null{}
+}
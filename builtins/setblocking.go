@@ -0,0 +1,28 @@
+package builtins
+
+import (
+	"syscall"
+
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/typing"
+)
+
+// SetBlocking ...
+func SetBlocking(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"setblocking", args,
+		typing.ExactArgs(2),
+		typing.WithTypes(object.INTEGER, object.BOOLEAN),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	fd := int(args[0].(*object.Integer).Value)
+	blocking := args[1].(*object.Boolean).Value
+
+	if err := syscall.SetNonblock(fd, !blocking); err != nil {
+		return newError("IOError: %s", err)
+	}
+
+	return &object.Null{}
+}
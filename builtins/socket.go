@@ -9,13 +9,13 @@ import (
 )
 
 // Socket ...
-func Socket(args ...object.Object) object.Object {
+func Socket(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"socket", args,
 		typing.ExactArgs(1),
 		typing.WithTypes(object.STRING),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	var (
@@ -4,13 +4,14 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"syscall"
 )
 
 func parseAddress(address string) (ip net.IP, port int, err error) {
 	var (
 		h string
 		p string
-		n int64
+		n uint64
 	)
 
 	h, p, err = net.SplitHostPort(address)
@@ -39,7 +40,7 @@ func parseAddress(address string) (ip net.IP, port int, err error) {
 		}
 	}
 
-	n, err = strconv.ParseInt(p, 10, 16)
+	n, err = strconv.ParseUint(p, 10, 16)
 	if err != nil {
 		return
 	}
@@ -66,3 +67,43 @@ func parseV6Address(address string) (addr [16]byte, port int, err error) {
 	copy(addr[:], ip.To16()[:16])
 	return
 }
+
+// sockaddrOf returns the socket address of address in the same address
+// family as the socket given by fd
+func sockaddrOf(fd int, address string) (syscall.Sockaddr, error) {
+	sockaddr, err := syscall.Getsockname(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	switch sockaddr.(type) {
+	case *syscall.SockaddrInet4:
+		addr, port, err := parseV4Address(address)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid IPv4 address '%s': %s", address, err)
+		}
+		return &syscall.SockaddrInet4{Addr: addr, Port: port}, nil
+	case *syscall.SockaddrInet6:
+		addr, port, err := parseV6Address(address)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid IPv6 address '%s': %s", address, err)
+		}
+		return &syscall.SockaddrInet6{Addr: addr, Port: port}, nil
+	default:
+		return nil, fmt.Errorf("Invalid socket type %T for bind '%s'", sockaddr, address)
+	}
+}
+
+// formatSockaddr returns the socket address sa as a "host:port" address
+func formatSockaddr(sa syscall.Sockaddr) string {
+	switch sa := sa.(type) {
+	case *syscall.SockaddrInet4:
+		return net.JoinHostPort(net.IP(sa.Addr[:]).String(), strconv.Itoa(sa.Port))
+	case *syscall.SockaddrInet6:
+		return net.JoinHostPort(net.IP(sa.Addr[:]).String(), strconv.Itoa(sa.Port))
+	case *syscall.SockaddrUnix:
+		return sa.Name
+	default:
+		return ""
+	}
+}
@@ -8,13 +8,13 @@ import (
 )
 
 // Sorted ...
-func Sorted(args ...object.Object) object.Object {
+func Sorted(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
-		"sort", args,
+		"sorted", args,
 		typing.ExactArgs(1),
 		typing.WithTypes(object.ARRAY),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	arr := args[0].(*object.Array)
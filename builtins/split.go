@@ -8,13 +8,13 @@ import (
 )
 
 // Split ...
-func Split(args ...object.Object) object.Object {
+func Split(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"split", args,
 		typing.RangeOfArgs(1, 2),
 		typing.WithTypes(object.STRING, object.STRING),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	var sep string
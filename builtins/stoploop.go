@@ -0,0 +1,20 @@
+package builtins
+
+import (
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/typing"
+)
+
+// StopLoop ...
+func StopLoop(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"stop_loop", args,
+		typing.ExactArgs(0),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	ctx.Loop().Stop()
+
+	return &object.Null{}
+}
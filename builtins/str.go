@@ -6,12 +6,12 @@ import (
 )
 
 // Str ...
-func Str(args ...object.Object) object.Object {
+func Str(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"str", args,
 		typing.ExactArgs(1),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	return &object.String{Value: args[0].String()}
@@ -0,0 +1,43 @@
+package builtins
+
+import (
+	"time"
+
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/typing"
+)
+
+// Timer ...
+func Timer(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"timer", args,
+		typing.RangeOfArgs(2, 3),
+		typing.WithTypes(object.INTEGER),
+	); err != nil {
+		return newError("%s", err)
+	}
+	if err := checkFunction("timer", args, 1); err != nil {
+		return err
+	}
+
+	ms := args[0].(*object.Integer).Value
+	if ms < 0 {
+		return newError("ValueError: timer() delay must not be negative")
+	}
+
+	repeat := false
+	if len(args) == 3 {
+		b, ok := args[2].(*object.Boolean)
+		if !ok {
+			return newError(
+				"TypeError: timer() expected argument #3 to be `bool` got `%s`",
+				args[2].Type(),
+			)
+		}
+		repeat = b.Value
+	}
+
+	id := ctx.Loop().AddTimer(time.Duration(ms)*time.Millisecond, repeat, args[1])
+
+	return &object.Integer{Value: id}
+}
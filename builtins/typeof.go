@@ -6,12 +6,12 @@ import (
 )
 
 // TypeOf ...
-func TypeOf(args ...object.Object) object.Object {
+func TypeOf(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"type", args,
 		typing.ExactArgs(1),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	return &object.String{Value: string(args[0].Type())}
@@ -0,0 +1,21 @@
+package builtins
+
+import (
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/typing"
+)
+
+// Unregister ...
+func Unregister(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"unregister", args,
+		typing.ExactArgs(1),
+		typing.WithTypes(object.INTEGER),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	fd := int(args[0].(*object.Integer).Value)
+
+	return &object.Boolean{Value: ctx.Loop().Unregister(fd)}
+}
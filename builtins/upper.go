@@ -8,13 +8,13 @@ import (
 )
 
 // Upper ...
-func Upper(args ...object.Object) object.Object {
+func Upper(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"upper", args,
 		typing.ExactArgs(1),
 		typing.WithTypes(object.STRING),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	return &object.String{Value: strings.ToUpper(args[0].(*object.String).Value)}
@@ -0,0 +1,20 @@
+package builtins
+
+import (
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/typing"
+)
+
+// Values ...
+func Values(ctx *object.Context, args ...object.Object) object.Object {
+	if err := typing.Check(
+		"values", args,
+		typing.ExactArgs(1),
+		typing.WithTypes(object.HASH),
+	); err != nil {
+		return newError("%s", err)
+	}
+
+	hash := args[0].(*object.Hash)
+	return &object.Array{Elements: hash.Values()}
+}
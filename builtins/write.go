@@ -8,19 +8,25 @@ import (
 )
 
 // Write ...
-func Write(args ...object.Object) object.Object {
+func Write(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"write", args,
 		typing.ExactArgs(2),
 		typing.WithTypes(object.INTEGER, object.STRING),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	fd := int(args[0].(*object.Integer).Value)
 	data := []byte(args[1].(*object.String).Value)
 
+	ctx.Release()
 	n, err := syscall.Write(fd, data)
+	ctx.Acquire()
+	if err == syscall.EAGAIN {
+		// No room to write to the non-blocking file descriptor yet
+		return &object.Null{}
+	}
 	if err != nil {
 		return newError("IOError: %s", err)
 	}
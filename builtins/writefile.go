@@ -8,13 +8,13 @@ import (
 )
 
 // WriteFile ...
-func WriteFile(args ...object.Object) object.Object {
+func WriteFile(ctx *object.Context, args ...object.Object) object.Object {
 	if err := typing.Check(
 		"writefile", args,
 		typing.ExactArgs(2),
 		typing.WithTypes(object.STRING, object.STRING),
 	); err != nil {
-		return newError(err.Error())
+		return newError("%s", err)
 	}
 
 	filename := args[0].(*object.String).Value
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/prologic/monkey-lang/typecheck"
+)
+
+// checkFiles implements the check command which reports type errors and
+// returns the exit status
+func checkFiles(args []string) int {
+	return reportSources("check", args, func(filename string, src []byte) ([]string, error) {
+		errors, err := typecheck.Source(filename, src)
+		if err != nil {
+			return nil, err
+		}
+		var result []string
+		for _, e := range errors {
+			result = append(result, e.Error())
+		}
+		return result, nil
+	})
+}
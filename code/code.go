@@ -117,6 +117,25 @@ const (
 	Call
 	Return
 	ReturnValue
+	// GetIter ...
+	GetIter
+	// IterNext ...
+	IterNext
+	// SetupTry ...
+	SetupTry
+	// PopTry ...
+	PopTry
+	// Throw ...
+	Throw
+	// TailCall calls a function in tail position reusing the current frame
+	TailCall
+	// Spawn runs a function with its arguments as a new task
+	Spawn
+	// Select waits for one of the cases of a select expression to proceed
+	// and pushes the value received (if any) and the index of the case
+	Select
+	// JumpIfNotCase jumps unless the case selected by Select is the operand
+	JumpIfNotCase
 )
 
 var definitions = map[Opcode]*Definition{
@@ -164,6 +183,15 @@ var definitions = map[Opcode]*Definition{
 	Jump:             {"Jump", []int{2}},
 	Call:             {"Call", []int{1}},
 	Return:           {"Return", []int{}},
+	GetIter:          {"GetIter", []int{}},
+	IterNext:         {"IterNext", []int{2}},
+	SetupTry:         {"SetupTry", []int{2}},
+	PopTry:           {"PopTry", []int{}},
+	Throw:            {"Throw", []int{}},
+	TailCall:         {"TailCall", []int{1}},
+	Spawn:            {"Spawn", []int{1}},
+	Select:           {"Select", []int{2, 1}},
+	JumpIfNotCase:    {"JumpIfNotCase", []int{2, 2}},
 }
 
 func Lookup(op byte) (*Definition, error) {
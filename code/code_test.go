@@ -2,6 +2,8 @@ package code
 
 import (
 	"testing"
+
+	"github.com/prologic/monkey-lang/token"
 )
 
 func TestMake(t *testing.T) {
@@ -90,3 +92,45 @@ func TestReadOperands(t *testing.T) {
 		}
 	}
 }
+
+func TestSourceMap(t *testing.T) {
+	a := token.Position{Line: 1, Column: 1}
+	b := token.Position{Line: 1, Column: 5}
+	c := token.Position{Line: 2, Column: 1}
+
+	var sm SourceMap
+	sm = sm.Add(0, a)
+	sm = sm.Add(3, a)
+	sm = sm.Add(4, b)
+	sm = sm.Add(6, token.Position{})
+	sm = sm.Add(7, c)
+
+	if len(sm) != 3 {
+		t.Fatalf("source map has wrong length. want=%d, got=%d", 3, len(sm))
+	}
+
+	tests := []struct {
+		offset   int
+		expected token.Position
+	}{
+		{-1, token.Position{}},
+		{0, a},
+		{3, a},
+		{4, b},
+		{6, b},
+		{7, c},
+		{100, c},
+	}
+
+	for _, tt := range tests {
+		if pos := sm.Lookup(tt.offset); pos != tt.expected {
+			t.Errorf("wrong position for offset %d. want=%s, got=%s",
+				tt.offset, tt.expected, pos)
+		}
+	}
+
+	sm = sm.Truncate(4)
+	if pos := sm.Lookup(7); pos != a {
+		t.Errorf("wrong position after truncate. want=%s, got=%s", a, pos)
+	}
+}
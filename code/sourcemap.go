@@ -0,0 +1,59 @@
+package code
+
+import (
+	"sort"
+
+	"github.com/prologic/monkey-lang/token"
+)
+
+// SourcePos associates the instruction starting at Offset (and all
+// instructions following it up until the next SourcePos) with the
+// position in the source code it was compiled from
+type SourcePos struct {
+	Offset int
+	Pos    token.Position
+}
+
+// SourceMap is a table of source positions ordered by instruction offset
+// used to map instructions back to the source code they were compiled from
+type SourceMap []SourcePos
+
+// Add records pos as the source position of the instruction at offset.
+// Consecutive instructions with the same position share a single entry.
+func (sm SourceMap) Add(offset int, pos token.Position) SourceMap {
+	if !pos.IsValid() {
+		return sm
+	}
+
+	if n := len(sm); n > 0 {
+		if sm[n-1].Pos == pos {
+			return sm
+		}
+		if sm[n-1].Offset == offset {
+			sm[n-1].Pos = pos
+			return sm
+		}
+	}
+
+	return append(sm, SourcePos{Offset: offset, Pos: pos})
+}
+
+// Truncate removes all entries for instructions at or after offset
+func (sm SourceMap) Truncate(offset int) SourceMap {
+	i := sort.Search(len(sm), func(i int) bool {
+		return sm[i].Offset >= offset
+	})
+	return sm[:i]
+}
+
+// Lookup returns the source position of the instruction at offset or an
+// invalid position if there is none
+func (sm SourceMap) Lookup(offset int) token.Position {
+	i := sort.Search(len(sm), func(i int) bool {
+		return sm[i].Offset > offset
+	})
+	if i == 0 {
+		return token.Position{}
+	}
+	return sm[i-1].Pos
+}
@@ -6,13 +6,13 @@ package compiler
 import (
 	"fmt"
 	"log"
-	"sort"
 	"strings"
 
 	"github.com/prologic/monkey-lang/ast"
 	"github.com/prologic/monkey-lang/builtins"
 	"github.com/prologic/monkey-lang/code"
 	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/token"
 )
 
 type EmittedInstruction struct {
@@ -20,22 +20,48 @@ type EmittedInstruction struct {
 	Position int
 }
 
+// loop tracks the jump targets of a loop being compiled so that `break` and
+// `continue` statements can be compiled into jumps
+type loop struct {
+	continuePos int   // position of the loop's condition
+	breaks      []int // positions of `Jump`(s) to patch with the loop's end
+	tries       int   // number of enclosing try blocks outside the loop
+}
+
+// tryBlock tracks a `try` (or `catch`) block being compiled so that `break`,
+// `continue` and `return` statements leaving it can first pop its handler
+// and run its `finally` block
+type tryBlock struct {
+	handler bool                // whether a handler was set up by `SetupTry`
+	finally *ast.BlockStatement // the `finally` block (if any)
+}
+
 type Scope struct {
 	instructions        code.Instructions
+	sourceMap           code.SourceMap
 	lastInstruction     EmittedInstruction
 	previousInstruction EmittedInstruction
+	loops               []*loop     // loops being compiled, innermost last
+	tries               []*tryBlock // try blocks being compiled, innermost last
 }
 
 type Compiler struct {
 	Debug bool
 
+	// Optimize enables the optimization of the compiled instructions
+	Optimize bool
+
 	l         int
+	pos       token.Position // position of the node being compiled
 	constants []object.Object
 
 	scopes     []Scope
 	scopeIndex int
 
 	symbolTable *SymbolTable
+
+	// symbol tables of the scopes of compiled functions (for debuggers)
+	functionSymbols map[*object.CompiledFunction]*SymbolTable
 }
 
 func New() *Compiler {
@@ -58,6 +84,8 @@ func New() *Compiler {
 		scopeIndex: 0,
 
 		symbolTable: symbolTable,
+
+		functionSymbols: make(map[*object.CompiledFunction]*SymbolTable),
 	}
 }
 
@@ -104,6 +132,112 @@ func (c *Compiler) leaveScope() code.Instructions {
 	return instructions
 }
 
+// bindSymbol resolves the symbol name is bound to by a bind expression (or
+// loop variable) defining it in the current scope if necessary
+func (c *Compiler) bindSymbol(name string) Symbol {
+	symbol, ok := c.symbolTable.Resolve(name)
+	if !ok {
+		return c.symbolTable.Define(name)
+	}
+
+	// Local shadowing of previously defined "free" variable in a
+	// function now begin rehound to a locally scopped variable.
+	// Builtins are shadowed in the same way.
+	if symbol.Scope == FreeScope || symbol.Scope == BuiltinScope {
+		symbol = c.symbolTable.Define(name)
+	}
+
+	return symbol
+}
+
+func (c *Compiler) emitBind(s Symbol) {
+	if s.Scope == GlobalScope {
+		c.emit(code.BindGlobal, s.Index)
+	} else {
+		c.emit(code.BindLocal, s.Index)
+	}
+}
+
+func (c *Compiler) enterLoop(continuePos int) {
+	c.scopes[c.scopeIndex].loops = append(
+		c.scopes[c.scopeIndex].loops,
+		&loop{continuePos: continuePos, tries: len(c.scopes[c.scopeIndex].tries)},
+	)
+}
+
+// leaveLoop patches all the `break` jumps of the innermost loop to jump to
+// breakPos
+func (c *Compiler) leaveLoop(breakPos int) {
+	loops := c.scopes[c.scopeIndex].loops
+	for _, pos := range loops[len(loops)-1].breaks {
+		c.changeOperand(pos, breakPos)
+	}
+	c.scopes[c.scopeIndex].loops = loops[:len(loops)-1]
+}
+
+// currentLoop returns the innermost loop of the current scope or nil if not
+// inside a loop. Loops do not extend into nested function literals.
+func (c *Compiler) currentLoop() *loop {
+	loops := c.scopes[c.scopeIndex].loops
+	if len(loops) == 0 {
+		return nil
+	}
+	return loops[len(loops)-1]
+}
+
+func (c *Compiler) enterTry(handler bool, finally *ast.BlockStatement) {
+	c.scopes[c.scopeIndex].tries = append(
+		c.scopes[c.scopeIndex].tries,
+		&tryBlock{handler: handler, finally: finally},
+	)
+}
+
+func (c *Compiler) leaveTry() {
+	tries := c.scopes[c.scopeIndex].tries
+	c.scopes[c.scopeIndex].tries = tries[:len(tries)-1]
+}
+
+// unwindTries emits the instructions to leave the try blocks of the current
+// scope down to depth, innermost first, popping their handlers and running
+// their `finally` blocks
+func (c *Compiler) unwindTries(depth int) error {
+	tries := c.scopes[c.scopeIndex].tries
+	defer func() { c.scopes[c.scopeIndex].tries = tries }()
+
+	for i := len(tries) - 1; i >= depth; i-- {
+		// A finally block is compiled outside of its own try block and
+		// nested try blocks must not overwrite the outer ones
+		c.scopes[c.scopeIndex].tries = tries[:i:i]
+
+		if tries[i].handler {
+			c.emit(code.PopTry)
+		}
+		if err := c.compileFinally(tries[i].finally); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// compileFinally compiles a `finally` block (if any) discarding its value
+func (c *Compiler) compileFinally(finally *ast.BlockStatement) error {
+	if finally == nil {
+		return nil
+	}
+
+	c.l++
+	err := c.Compile(finally)
+	c.l--
+	if err != nil {
+		return err
+	}
+
+	c.emit(code.Pop)
+
+	return nil
+}
+
 func (c *Compiler) addConstant(obj object.Object) int {
 	c.constants = append(c.constants, obj)
 	return len(c.constants) - 1
@@ -122,6 +256,10 @@ func (c *Compiler) currentInstructions() code.Instructions {
 	return c.scopes[c.scopeIndex].instructions
 }
 
+func (c *Compiler) currentSourceMap() code.SourceMap {
+	return c.scopes[c.scopeIndex].sourceMap
+}
+
 func (c *Compiler) setLastInstruction(op code.Opcode, pos int) {
 	previous := c.scopes[c.scopeIndex].lastInstruction
 	last := EmittedInstruction{Opcode: op, Position: pos}
@@ -146,9 +284,30 @@ func (c *Compiler) removeLastPop() {
 	new := old[:last.Position]
 
 	c.scopes[c.scopeIndex].instructions = new
+	c.scopes[c.scopeIndex].sourceMap = c.currentSourceMap().Truncate(last.Position)
 	c.scopes[c.scopeIndex].lastInstruction = previous
 }
 
+// tailCall replaces the `Call` instruction ins, which must be followed by a
+// `Return`, with a `TailCall`. Calls in the main program are not tail calls
+// as there is no frame to reuse and neither are calls in try blocks as the
+// frame is still needed to handle errors.
+func (c *Compiler) tailCall(ins EmittedInstruction) {
+	if ins.Opcode != code.Call || c.scopeIndex == 0 || len(c.scopes[c.scopeIndex].tries) > 0 {
+		return
+	}
+
+	c.currentInstructions()[ins.Position] = byte(code.TailCall)
+
+	scope := &c.scopes[c.scopeIndex]
+	if scope.lastInstruction.Position == ins.Position {
+		scope.lastInstruction.Opcode = code.TailCall
+	}
+	if scope.previousInstruction.Position == ins.Position {
+		scope.previousInstruction.Opcode = code.TailCall
+	}
+}
+
 func (c *Compiler) replaceLastPopWithReturn() {
 	lastPos := c.scopes[c.scopeIndex].lastInstruction.Position
 	c.replaceInstruction(lastPos, code.Make(code.Return))
@@ -164,6 +323,82 @@ func (c *Compiler) replaceInstruction(pos int, newInstruction []byte) {
 	}
 }
 
+// compileSelect compiles a select expression. The channel of each case, the
+// value sent (or null) and whether it is sent are pushed for `Select`, which
+// pushes the value received and the index of the case selected (or the
+// number of cases for the default). The body of each case is guarded by a
+// `JumpIfNotCase` which pops the index if its case was selected.
+func (c *Compiler) compileSelect(node *ast.SelectExpression) error {
+	for _, sc := range node.Cases {
+		c.l++
+		err := c.Compile(sc.Channel)
+		if err == nil && sc.Value != nil {
+			err = c.Compile(sc.Value)
+		}
+		c.l--
+		if err != nil {
+			return err
+		}
+
+		if sc.Value != nil {
+			c.emit(code.LoadTrue)
+		} else {
+			c.emit(code.LoadNull)
+			c.emit(code.LoadFalse)
+		}
+	}
+
+	hasDefault := 0
+	if node.Default != nil {
+		hasDefault = 1
+	}
+	c.emit(code.Select, len(node.Cases), hasDefault)
+
+	// Emit `Jump`(s) with a bogus value patched with the end of the select
+	jumps := []int{}
+	for i, sc := range node.Cases {
+		// Emit an `JumpIfNotCase` with a bogus value patched with the
+		// position of the next case
+		jumpPos := c.emit(code.JumpIfNotCase, 0xFFFF, i)
+
+		if sc.Name != nil {
+			c.emitBind(c.bindSymbol(sc.Name.Value))
+		}
+		c.emit(code.Pop)
+
+		c.l++
+		err := c.Compile(sc.Body)
+		c.l--
+		if err != nil {
+			return err
+		}
+
+		jumps = append(jumps, c.emit(code.Jump, 0xFFFF))
+		c.replaceInstruction(jumpPos, code.Make(code.JumpIfNotCase, len(c.currentInstructions()), i))
+	}
+
+	// Pop off the index and value for the default
+	c.emit(code.Pop)
+	c.emit(code.Pop)
+	if node.Default != nil {
+		c.l++
+		err := c.Compile(node.Default)
+		c.l--
+		if err != nil {
+			return err
+		}
+	} else {
+		c.emit(code.LoadNull)
+	}
+
+	afterSelectPos := len(c.currentInstructions())
+	for _, pos := range jumps {
+		c.changeOperand(pos, afterSelectPos)
+	}
+
+	return nil
+}
+
 func (c *Compiler) changeOperand(opPos int, operand int) {
 	op := code.Opcode(c.currentInstructions()[opPos])
 	newInstruction := code.Make(op, operand)
@@ -176,6 +411,7 @@ func (c *Compiler) addInstruction(ins []byte) int {
 	updatedInstructions := append(c.currentInstructions(), ins...)
 
 	c.scopes[c.scopeIndex].instructions = updatedInstructions
+	c.scopes[c.scopeIndex].sourceMap = c.currentSourceMap().Add(posNewInstruction, c.pos)
 
 	return posNewInstruction
 }
@@ -188,6 +424,13 @@ func (c *Compiler) Compile(node ast.Node) error {
 		)
 	}
 
+	// Track the position of the node being compiled so that emitted
+	// instructions can be mapped back to the source
+	if pos := node.Pos(); pos.IsValid() {
+		defer func(pos token.Position) { c.pos = pos }(c.pos)
+		c.pos = pos
+	}
+
 	switch node := node.(type) {
 
 	case *ast.Program:
@@ -200,19 +443,8 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 
 	case *ast.BindExpression:
-		var symbol Symbol
-
 		if ident, ok := node.Left.(*ast.Identifier); ok {
-			symbol, ok = c.symbolTable.Resolve(ident.Value)
-			if !ok {
-				symbol = c.symbolTable.Define(ident.Value)
-			} else {
-				// Local shadowing of previously defined "free" variable in a
-				// function now begin rehound to a locally scopped variable.
-				if symbol.Scope == FreeScope {
-					symbol = c.symbolTable.Define(ident.Value)
-				}
-			}
+			symbol := c.bindSymbol(ident.Value)
 
 			c.l++
 			err := c.Compile(node.Value)
@@ -221,20 +453,16 @@ func (c *Compiler) Compile(node ast.Node) error {
 				return err
 			}
 
-			if symbol.Scope == GlobalScope {
-				c.emit(code.BindGlobal, symbol.Index)
-			} else {
-				c.emit(code.BindLocal, symbol.Index)
-			}
+			c.emitBind(symbol)
 		} else {
-			return fmt.Errorf("expected identifier got=%s", node.Left)
+			return fmt.Errorf("%s: expected identifier got=%s", node.Pos(), node.Left)
 		}
 
 	case *ast.AssignmentExpression:
 		if ident, ok := node.Left.(*ast.Identifier); ok {
 			symbol, ok := c.symbolTable.Resolve(ident.Value)
 			if !ok {
-				return fmt.Errorf("undefined variable %s", ident.Value)
+				return fmt.Errorf("%s: undefined variable %s", ident.Pos(), ident.Value)
 			}
 
 			c.l++
@@ -273,13 +501,13 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 			c.emit(code.SetItem)
 		} else {
-			return fmt.Errorf("expected identifier or index expression got=%s", node.Left)
+			return fmt.Errorf("%s: expected identifier or index expression got=%s", node.Pos(), node.Left)
 		}
 
 	case *ast.Identifier:
 		symbol, ok := c.symbolTable.Resolve(node.Value)
 		if !ok {
-			return fmt.Errorf("undefined variable %s", node.Value)
+			return fmt.Errorf("%s: undefined variable %s", node.Pos(), node.Value)
 		}
 
 		c.loadSymbol(symbol)
@@ -371,6 +599,8 @@ func (c *Compiler) Compile(node ast.Node) error {
 		// Emit an `JumpIfFalse` with a bogus value
 		jumpIfFalsePos := c.emit(code.JumpIfFalse, 0xFFFF)
 
+		c.enterLoop(jumpConditionPos)
+
 		c.l++
 		err = c.Compile(node.Consequence)
 		if err != nil {
@@ -385,6 +615,183 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 		afterConsequencePos := c.emit(code.LoadNull)
 		c.changeOperand(jumpIfFalsePos, afterConsequencePos)
+		c.leaveLoop(afterConsequencePos)
+
+	case *ast.ForExpression:
+		c.l++
+		err := c.Compile(node.Iterable)
+		c.l--
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.GetIter)
+
+		// Emit an `IterNext` with a bogus value which pushes the next key
+		// and value or jumps to the end of the loop once exhausted
+		iterNextPos := c.emit(code.IterNext, 0xFFFF)
+
+		c.emitBind(c.bindSymbol(node.Value.Value))
+		c.emit(code.Pop)
+
+		if node.Key != nil {
+			c.emitBind(c.bindSymbol(node.Key.Value))
+		}
+		c.emit(code.Pop)
+
+		c.enterLoop(iterNextPos)
+
+		c.l++
+		err = c.Compile(node.Body)
+		c.l--
+		if err != nil {
+			return err
+		}
+
+		// Pop off the LoadNull(s) from ast.BlockStatement(s)
+		c.emit(code.Pop)
+
+		c.emit(code.Jump, iterNextPos)
+
+		// Pop off the iterator
+		afterBodyPos := c.emit(code.Pop)
+		c.changeOperand(iterNextPos, afterBodyPos)
+		c.leaveLoop(afterBodyPos)
+
+		c.emit(code.LoadNull)
+
+	case *ast.BreakStatement:
+		loop := c.currentLoop()
+		if loop == nil {
+			return fmt.Errorf("%s: 'break' outside loop", node.Pos())
+		}
+
+		if err := c.unwindTries(loop.tries); err != nil {
+			return err
+		}
+
+		// Emit an `Jump` with a bogus value patched at the end of the loop
+		loop.breaks = append(loop.breaks, c.emit(code.Jump, 0xFFFF))
+
+	case *ast.ContinueStatement:
+		loop := c.currentLoop()
+		if loop == nil {
+			return fmt.Errorf("%s: 'continue' outside loop", node.Pos())
+		}
+
+		if err := c.unwindTries(loop.tries); err != nil {
+			return err
+		}
+
+		c.emit(code.Jump, loop.continuePos)
+
+	case *ast.TryExpression:
+		// Emit an `SetupTry` with a bogus value patched with the position
+		// of the handler, the catch block or else the finally block
+		setupTryPos := c.emit(code.SetupTry, 0xFFFF)
+
+		c.enterTry(true, node.Finally)
+		c.l++
+		err := c.Compile(node.Block)
+		c.l--
+		c.leaveTry()
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.PopTry)
+		if err := c.compileFinally(node.Finally); err != nil {
+			return err
+		}
+
+		// Emit `Jump`(s) with a bogus value patched with the end of the try
+		jumps := []int{c.emit(code.Jump, 0xFFFF)}
+
+		if node.Catch != nil {
+			// The handler pushes the error being caught
+			c.changeOperand(setupTryPos, len(c.currentInstructions()))
+
+			if node.Finally != nil {
+				setupTryPos = c.emit(code.SetupTry, 0xFFFF)
+				c.enterTry(true, node.Finally)
+			}
+
+			if node.CatchParam != nil {
+				c.emitBind(c.bindSymbol(node.CatchParam.Value))
+			}
+			c.emit(code.Pop)
+
+			c.l++
+			err := c.Compile(node.Catch)
+			c.l--
+			if err != nil {
+				return err
+			}
+
+			if node.Finally != nil {
+				c.leaveTry()
+				c.emit(code.PopTry)
+				if err := c.compileFinally(node.Finally); err != nil {
+					return err
+				}
+				jumps = append(jumps, c.emit(code.Jump, 0xFFFF))
+			}
+		}
+
+		if node.Finally != nil {
+			// Run the finally block and re-throw the error not caught
+			c.changeOperand(setupTryPos, len(c.currentInstructions()))
+			if err := c.compileFinally(node.Finally); err != nil {
+				return err
+			}
+			c.emit(code.Throw)
+		}
+
+		afterTryPos := len(c.currentInstructions())
+		for _, pos := range jumps {
+			c.changeOperand(pos, afterTryPos)
+		}
+
+	case *ast.ThrowExpression:
+		c.l++
+		err := c.Compile(node.Value)
+		c.l--
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.Throw)
+
+	case *ast.SpawnExpression:
+		// The function and arguments of a call are evaluated by the task
+		// spawning it, other values are functions called without arguments
+		fn, args := node.Value, []ast.Expression{}
+		if call, ok := node.Value.(*ast.CallExpression); ok {
+			fn, args = call.Function, call.Arguments
+		}
+
+		c.l++
+		err := c.Compile(fn)
+		c.l--
+		if err != nil {
+			return err
+		}
+
+		for _, a := range args {
+			c.l++
+			err := c.Compile(a)
+			c.l--
+			if err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.Spawn, len(args))
+
+	case *ast.SelectExpression:
+		if err := c.compileSelect(node); err != nil {
+			return err
+		}
 
 	case *ast.ImportExpression:
 		c.l++
@@ -412,7 +819,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		case "-":
 			c.emit(code.Minus)
 		default:
-			return fmt.Errorf("unknown operator %s", node.Operator)
+			return fmt.Errorf("%s: unknown operator %s", node.Pos(), node.Operator)
 		}
 
 	case *ast.InfixExpression:
@@ -486,7 +893,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		case "!=":
 			c.emit(code.NotEqual)
 		default:
-			return fmt.Errorf("unknown operator %s", node.Operator)
+			return fmt.Errorf("%s: unknown operator %s", node.Pos(), node.Operator)
 		}
 
 	case *ast.IndexExpression:
@@ -507,15 +914,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		c.emit(code.GetItem)
 
 	case *ast.HashLiteral:
-		keys := []ast.Expression{}
-		for k := range node.Pairs {
-			keys = append(keys, k)
-		}
-		sort.Slice(keys, func(i, j int) bool {
-			return keys[i].String() < keys[j].String()
-		})
-
-		for _, k := range keys {
+		for _, k := range node.Keys {
 			c.l++
 			err := c.Compile(k)
 			c.l--
@@ -552,13 +951,17 @@ func (c *Compiler) Compile(node ast.Node) error {
 		integer := &object.Integer{Value: node.Value}
 		c.emit(code.LoadConstant, c.addConstant(integer))
 
+	case *ast.FloatLiteral:
+		float := &object.Float{Value: node.Value}
+		c.emit(code.LoadConstant, c.addConstant(float))
+
 	case *ast.FunctionLiteral:
 		c.enterScope()
 
 		if node.Name != "" {
 			symbol, ok := c.symbolTable.Resolve(node.Name)
 			if !ok {
-				return fmt.Errorf("undefined variable %s", node.Name)
+				return fmt.Errorf("%s: undefined variable %s", node.Pos(), node.Name)
 			}
 
 			// Redefine the symbol for the name assign to this closure as a
@@ -593,19 +996,28 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.emit(code.Return)
 		}
 
+		symbolTable := c.symbolTable
 		freeSymbols := c.symbolTable.FreeSymbols
 		numLocals := c.symbolTable.numDefinitions
+		sourceMap := c.currentSourceMap()
 		instructions := c.leaveScope()
 
+		if c.Optimize {
+			instructions, sourceMap = c.optimize(instructions, sourceMap, true)
+		}
+
 		for _, s := range freeSymbols {
 			c.loadSymbol(s)
 		}
 
 		compiledFn := &object.CompiledFunction{
+			Name:          node.Name,
 			Instructions:  instructions,
+			SourceMap:     sourceMap,
 			NumLocals:     numLocals,
 			NumParameters: len(node.Parameters),
 		}
+		c.functionSymbols[compiledFn] = symbolTable
 
 		fnIndex := c.addConstant(compiledFn)
 		c.emit(code.MakeClosure, fnIndex, len(freeSymbols))
@@ -637,6 +1049,14 @@ func (c *Compiler) Compile(node ast.Node) error {
 			return err
 		}
 
+		if err := c.unwindTries(0); err != nil {
+			return err
+		}
+
+		if c.lastInstructionIs(code.Call) {
+			c.tailCall(c.scopes[c.scopeIndex].lastInstruction)
+		}
+
 		c.emit(code.Return)
 
 	case *ast.Null:
@@ -653,14 +1073,38 @@ func (c *Compiler) Compile(node ast.Node) error {
 	return nil
 }
 
+// Symbols returns the symbol table of the scope of the compiled function fn,
+// naming its local and free variables, or the global symbol table if fn was
+// not compiled by this compiler, e.g: the main function of the program
+func (c *Compiler) Symbols(fn *object.CompiledFunction) *SymbolTable {
+	if symbolTable, ok := c.functionSymbols[fn]; ok {
+		return symbolTable
+	}
+
+	symbolTable := c.symbolTable
+	for symbolTable.Outer != nil {
+		symbolTable = symbolTable.Outer
+	}
+	return symbolTable
+}
+
+// Bytecode returns the compiled bytecode, the instructions of the main
+// program are optimized first if optimizations are enabled
 func (c *Compiler) Bytecode() *Bytecode {
+	if c.Optimize {
+		scope := &c.scopes[c.scopeIndex]
+		scope.instructions, scope.sourceMap = c.optimize(scope.instructions, scope.sourceMap, false)
+	}
+
 	return &Bytecode{
 		Instructions: c.currentInstructions(),
+		SourceMap:    c.currentSourceMap(),
 		Constants:    c.constants,
 	}
 }
 
 type Bytecode struct {
 	Instructions code.Instructions
+	SourceMap    code.SourceMap
 	Constants    []object.Object
 }
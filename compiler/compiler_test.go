@@ -80,6 +80,13 @@ func testConstants(
 				return fmt.Errorf("constant %d - testIntegerObject failed: %s",
 					i, err)
 			}
+
+		case float64:
+			err := testFloatObject(constant, actual[i])
+			if err != nil {
+				return fmt.Errorf("constant %d - testFloatObject failed: %s",
+					i, err)
+			}
 		}
 	}
 
@@ -137,6 +144,21 @@ func testIntegerObject(expected int64, actual object.Object) error {
 	return nil
 }
 
+func testFloatObject(expected float64, actual object.Object) error {
+	result, ok := actual.(*object.Float)
+	if !ok {
+		return fmt.Errorf("object is not Float. got=%T (%+v)",
+			actual, actual)
+	}
+
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value. got=%g, want=%g",
+			result.Value, expected)
+	}
+
+	return nil
+}
+
 func concatInstructions(s []code.Instructions) code.Instructions {
 	out := code.Instructions{}
 
@@ -334,6 +356,32 @@ func TestIntegerArithmetic(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func TestFloatArithmetic(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "1.5 + 2",
+			expectedConstants: []interface{}{1.5, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.LoadConstant, 0),
+				code.Make(code.LoadConstant, 1),
+				code.Make(code.Add),
+				code.Make(code.Pop),
+			},
+		},
+		{
+			input:             "-2.5",
+			expectedConstants: []interface{}{2.5},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.LoadConstant, 0),
+				code.Make(code.Minus),
+				code.Make(code.Pop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
 func TestBooleanExpressions(t *testing.T) {
 	tests := []compilerTestCase{
 		{
@@ -526,11 +574,206 @@ func TestIteration(t *testing.T) {
 				code.Make(code.Pop),
 			},
 		},
+		{
+			input: `
+			while (true) { break; continue };
+            `,
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.LoadTrue),
+				// 0001
+				code.Make(code.JumpIfFalse, 15),
+				// 0004
+				code.Make(code.Jump, 15),
+				// 0007
+				code.Make(code.Jump, 0),
+				// 0010
+				code.Make(code.LoadNull),
+				// 0011
+				code.Make(code.Pop),
+				// 0012
+				code.Make(code.Jump, 0),
+				// 0015
+				code.Make(code.LoadNull),
+				// 0016
+				code.Make(code.Pop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestForIteration(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `
+			for x in [1] { x };
+            `,
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.LoadConstant, 0),
+				// 0003
+				code.Make(code.MakeArray, 1),
+				// 0006
+				code.Make(code.GetIter),
+				// 0007
+				code.Make(code.IterNext, 22),
+				// 0010
+				code.Make(code.BindGlobal, 0),
+				// 0013
+				code.Make(code.Pop),
+				// 0014
+				code.Make(code.Pop),
+				// 0015
+				code.Make(code.LoadGlobal, 0),
+				// 0018
+				code.Make(code.Pop),
+				// 0019
+				code.Make(code.Jump, 7),
+				// 0022
+				code.Make(code.Pop),
+				// 0023
+				code.Make(code.LoadNull),
+				// 0024
+				code.Make(code.Pop),
+			},
+		},
+		{
+			input: `
+			for i, x in [] { break };
+            `,
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.MakeArray, 0),
+				// 0003
+				code.Make(code.GetIter),
+				// 0004
+				code.Make(code.IterNext, 23),
+				// 0007
+				code.Make(code.BindGlobal, 0),
+				// 0010
+				code.Make(code.Pop),
+				// 0011
+				code.Make(code.BindGlobal, 1),
+				// 0014
+				code.Make(code.Pop),
+				// 0015
+				code.Make(code.Jump, 23),
+				// 0018
+				code.Make(code.LoadNull),
+				// 0019
+				code.Make(code.Pop),
+				// 0020
+				code.Make(code.Jump, 4),
+				// 0023
+				code.Make(code.Pop),
+				// 0024
+				code.Make(code.LoadNull),
+				// 0025
+				code.Make(code.Pop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestTryExpressions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `
+			try { 1 } catch (e) { 2 };
+            `,
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.SetupTry, 10),
+				// 0003
+				code.Make(code.LoadConstant, 0),
+				// 0006
+				code.Make(code.PopTry),
+				// 0007
+				code.Make(code.Jump, 17),
+				// 0010
+				code.Make(code.BindGlobal, 0),
+				// 0013
+				code.Make(code.Pop),
+				// 0014
+				code.Make(code.LoadConstant, 1),
+				// 0017
+				code.Make(code.Pop),
+			},
+		},
+		{
+			input: `
+			try { 1 } finally { 2 };
+            `,
+			expectedConstants: []interface{}{1, 2, 2},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.SetupTry, 14),
+				// 0003
+				code.Make(code.LoadConstant, 0),
+				// 0006
+				code.Make(code.PopTry),
+				// 0007
+				code.Make(code.LoadConstant, 1),
+				// 0010
+				code.Make(code.Pop),
+				// 0011
+				code.Make(code.Jump, 19),
+				// 0014
+				code.Make(code.LoadConstant, 2),
+				// 0017
+				code.Make(code.Pop),
+				// 0018
+				code.Make(code.Throw),
+				// 0019
+				code.Make(code.Pop),
+			},
+		},
+		{
+			input: `
+			throw "oops";
+            `,
+			expectedConstants: []interface{}{"oops"},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.LoadConstant, 0),
+				// 0003
+				code.Make(code.Throw),
+				// 0004
+				code.Make(code.Pop),
+			},
+		},
 	}
 
 	runCompilerTests(t, tests)
 }
 
+func TestBreakContinueOutsideLoop(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"break", "1:1: 'break' outside loop"},
+		{"if (true) { continue }", "1:13: 'continue' outside loop"},
+		{"while (true) { fn() { break } }", "1:23: 'break' outside loop"},
+	}
+
+	for _, tt := range tests {
+		compiler := New()
+		err := compiler.Compile(parse(tt.input))
+		assert.EqualError(err, tt.expected)
+	}
+}
+
 func TestGlobalBindExpressions(t *testing.T) {
 	tests := []compilerTestCase2{
 		{
@@ -671,6 +914,19 @@ func TestHashLiterals(t *testing.T) {
 				code.Make(code.Pop),
 			},
 		},
+		// Pairs are compiled in the order of the source
+		{
+			input:             `{"b": 1, "a": 2}`,
+			expectedConstants: []interface{}{"b", 1, "a", 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.LoadConstant, 0),
+				code.Make(code.LoadConstant, 1),
+				code.Make(code.LoadConstant, 2),
+				code.Make(code.LoadConstant, 3),
+				code.Make(code.MakeHash, 4),
+				code.Make(code.Pop),
+			},
+		},
 	}
 
 	runCompilerTests(t, tests)
@@ -772,6 +1028,30 @@ func TestFunctions(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func TestTailCalls(t *testing.T) {
+	tests := []compilerTestCase2{
+		{
+			input:        "f := fn() { return f() }",
+			constants:    []interface{}{Instructions("0000 SetSelf 0\n0002 LoadFree 0\n0004 TailCall 0\n0006 Return\n")},
+			instructions: "0000 LoadGlobal 0\n0003 MakeClosure 0 1\n0007 BindGlobal 0\n0010 Pop\n",
+		},
+		{
+			// Calls whose value is used are not tail calls
+			input:        "f := fn(x) { return 1 + f(x) }",
+			constants:    []interface{}{1, Instructions("0000 SetSelf 0\n0002 LoadConstant 0\n0005 LoadFree 0\n0007 LoadLocal 0\n0009 Call 1\n0011 Add\n0012 Return\n")},
+			instructions: "0000 LoadGlobal 0\n0003 MakeClosure 1 1\n0007 BindGlobal 0\n0010 Pop\n",
+		},
+		{
+			// Neither are calls in try blocks or the main program
+			input:        "f := fn() { try { return f() } catch (e) { } }; f()",
+			constants:    []interface{}{Instructions("0000 SetSelf 0\n0002 SetupTry 15\n0005 LoadFree 0\n0007 Call 0\n0009 PopTry\n0010 Return\n0011 PopTry\n0012 Jump 17\n0015 BindLocal 0\n0017 LoadNull\n0018 Return\n")},
+			instructions: "0000 LoadGlobal 0\n0003 MakeClosure 0 1\n0007 BindGlobal 0\n0010 Pop\n0011 LoadGlobal 0\n0014 Call 0\n0016 Pop\n",
+		},
+	}
+
+	runCompilerTests2(t, tests)
+}
+
 func TestFunctionsWithoutReturn(t *testing.T) {
 	tests := []compilerTestCase{
 		{
@@ -1017,11 +1297,11 @@ func TestBuiltins(t *testing.T) {
             `,
 			expectedConstants: []interface{}{1},
 			expectedInstructions: []code.Instructions{
-				code.Make(code.LoadBuiltin, 22),
+				code.Make(code.LoadBuiltin, 28),
 				code.Make(code.MakeArray, 0),
 				code.Make(code.Call, 1),
 				code.Make(code.Pop),
-				code.Make(code.LoadBuiltin, 33),
+				code.Make(code.LoadBuiltin, 39),
 				code.Make(code.MakeArray, 0),
 				code.Make(code.LoadConstant, 0),
 				code.Make(code.Call, 2),
@@ -1032,9 +1312,9 @@ func TestBuiltins(t *testing.T) {
 			input: `fn() { return len([]) }`,
 			expectedConstants: []interface{}{
 				[]code.Instructions{
-					code.Make(code.LoadBuiltin, 22),
+					code.Make(code.LoadBuiltin, 28),
 					code.Make(code.MakeArray, 0),
-					code.Make(code.Call, 1),
+					code.Make(code.TailCall, 1),
 					code.Make(code.Return),
 				},
 			},
@@ -1115,6 +1395,94 @@ func TestClosures(t *testing.T) {
 	runCompilerTests2(t, tests)
 }
 
+func TestSpawnAndSelectExpressions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `
+			c := 1; spawn len(c);
+            `,
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.LoadConstant, 0),
+				// 0003
+				code.Make(code.BindGlobal, 0),
+				// 0006
+				code.Make(code.Pop),
+				// 0007
+				code.Make(code.LoadBuiltin, 28),
+				// 0009
+				code.Make(code.LoadGlobal, 0),
+				// 0012
+				code.Make(code.Spawn, 1),
+				// 0014
+				code.Make(code.Pop),
+			},
+		},
+		{
+			input: `
+			c := 1;
+			select {
+				case v := recv(c) { v }
+				case send(c, 2) { 3 }
+				default { 4 }
+			};
+            `,
+			expectedConstants: []interface{}{1, 2, 3, 4},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.LoadConstant, 0),
+				// 0003
+				code.Make(code.BindGlobal, 0),
+				// 0006
+				code.Make(code.Pop),
+				// 0007
+				code.Make(code.LoadGlobal, 0),
+				// 0010
+				code.Make(code.LoadNull),
+				// 0011
+				code.Make(code.LoadFalse),
+				// 0012
+				code.Make(code.LoadGlobal, 0),
+				// 0015
+				code.Make(code.LoadConstant, 1),
+				// 0018
+				code.Make(code.LoadTrue),
+				// 0019
+				code.Make(code.Select, 2, 1),
+				// 0023
+				code.Make(code.JumpIfNotCase, 38, 0),
+				// 0028
+				code.Make(code.BindGlobal, 1),
+				// 0031
+				code.Make(code.Pop),
+				// 0032
+				code.Make(code.LoadGlobal, 1),
+				// 0035
+				code.Make(code.Jump, 55),
+				// 0038
+				code.Make(code.JumpIfNotCase, 50, 1),
+				// 0043
+				code.Make(code.Pop),
+				// 0044
+				code.Make(code.LoadConstant, 2),
+				// 0047
+				code.Make(code.Jump, 55),
+				// 0050
+				code.Make(code.Pop),
+				// 0051
+				code.Make(code.Pop),
+				// 0052
+				code.Make(code.LoadConstant, 3),
+				// 0055
+				code.Make(code.Pop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
 func TestImportExpressions(t *testing.T) {
 	tests := []compilerTestCase2{
 		{
@@ -1126,3 +1494,55 @@ func TestImportExpressions(t *testing.T) {
 
 	runCompilerTests2(t, tests)
 }
+
+func TestCompilerErrorPositions(t *testing.T) {
+	assert := assert.New(t)
+
+	input := "x := 1\ny = 2"
+
+	l := lexer.NewWithFilename(input, "test.monkey")
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	compiler := New()
+	err := compiler.Compile(program)
+	assert.EqualError(err, "test.monkey:2:1: undefined variable y")
+}
+
+func TestSourceMap(t *testing.T) {
+	assert := assert.New(t)
+
+	input := "x := 1\nx + 2"
+
+	l := lexer.NewWithFilename(input, "test.monkey")
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	compiler := New()
+	err := compiler.Compile(program)
+	assert.NoError(err)
+
+	bytecode := compiler.Bytecode()
+	assert.Equal(
+		"0000 LoadConstant 0\n0003 BindGlobal 0\n0006 Pop\n"+
+			"0007 LoadGlobal 0\n0010 LoadConstant 1\n0013 Add\n0014 Pop\n",
+		bytecode.Instructions.String(),
+	)
+
+	tests := []struct {
+		offset   int
+		expected string
+	}{
+		{0, "test.monkey:1:6"},
+		{3, "test.monkey:1:3"},
+		{6, "test.monkey:1:1"},
+		{7, "test.monkey:2:1"},
+		{10, "test.monkey:2:5"},
+		{13, "test.monkey:2:3"},
+		{14, "test.monkey:2:1"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(tt.expected, bytecode.SourceMap.Lookup(tt.offset).String())
+	}
+}
@@ -0,0 +1,266 @@
+package compiler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/prologic/monkey-lang/code"
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/token"
+)
+
+// BytecodeExtension is the file extension of serialized bytecode files
+const BytecodeExtension = ".mbc"
+
+// BytecodeVersion is the version of the serialized bytecode format. It must
+// be incremented whenever the format or the instruction set changes in an
+// incompatible way.
+const BytecodeVersion = 8
+
+// BytecodeMagic is the header every serialized bytecode file starts with
+var BytecodeMagic = []byte("\x00MBC")
+
+// ErrInvalidBytecode is returned when decoding something that is not a
+// serialized bytecode file
+var ErrInvalidBytecode = errors.New("invalid bytecode file")
+
+// Tags identifying the type of each serialized constant
+const (
+	tagInteger byte = iota + 1
+	tagFloat
+	tagString
+	tagCompiledFunction
+)
+
+// Encode writes the bytecode to w in the binary bytecode container format:
+//
+//	magic | version | main instructions | main source map | constants
+//
+// Integers are written as varints, strings and instructions are length
+// prefixed and each constant is prefixed with a tag identifying its type.
+func (b *Bytecode) Encode(w io.Writer) error {
+	e := &encoder{w: bufio.NewWriter(w)}
+
+	e.write(BytecodeMagic)
+	e.uvarint(BytecodeVersion)
+	e.bytes(b.Instructions)
+	e.sourceMap(b.SourceMap)
+
+	e.uvarint(uint64(len(b.Constants)))
+	for _, constant := range b.Constants {
+		e.constant(constant)
+	}
+
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+// DecodeBytecode reads bytecode previously written by Encode from r
+func DecodeBytecode(r io.Reader) (*Bytecode, error) {
+	d := &decoder{r: bufio.NewReader(r)}
+
+	magic := make([]byte, len(BytecodeMagic))
+	if _, err := io.ReadFull(d.r, magic); err != nil || !bytes.Equal(magic, BytecodeMagic) {
+		return nil, ErrInvalidBytecode
+	}
+
+	if version := d.uvarint(); d.err == nil && version != BytecodeVersion {
+		return nil, fmt.Errorf(
+			"unsupported bytecode version %d (expected %d)",
+			version, BytecodeVersion,
+		)
+	}
+
+	bytecode := &Bytecode{}
+	bytecode.Instructions = d.bytes()
+	bytecode.SourceMap = d.sourceMap()
+
+	n := d.length()
+	for i := 0; i < n && d.err == nil; i++ {
+		bytecode.Constants = append(bytecode.Constants, d.constant())
+	}
+
+	if d.err != nil {
+		if d.err == io.EOF || d.err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("%s: unexpected end of file", ErrInvalidBytecode)
+		}
+		return nil, d.err
+	}
+	return bytecode, nil
+}
+
+// encoder writes the primitives of the bytecode format and records the
+// first error encountered so that callers only need to check once
+type encoder struct {
+	w   *bufio.Writer
+	err error
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (e *encoder) write(p []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(p)
+	}
+}
+
+func (e *encoder) uvarint(x uint64) {
+	n := binary.PutUvarint(e.buf[:], x)
+	e.write(e.buf[:n])
+}
+
+func (e *encoder) varint(x int64) {
+	n := binary.PutVarint(e.buf[:], x)
+	e.write(e.buf[:n])
+}
+
+func (e *encoder) bytes(p []byte) {
+	e.uvarint(uint64(len(p)))
+	e.write(p)
+}
+
+func (e *encoder) string(s string) {
+	e.bytes([]byte(s))
+}
+
+func (e *encoder) sourceMap(sm code.SourceMap) {
+	e.uvarint(uint64(len(sm)))
+	for _, sp := range sm {
+		e.uvarint(uint64(sp.Offset))
+		e.string(sp.Pos.Filename)
+		e.uvarint(uint64(sp.Pos.Line))
+		e.uvarint(uint64(sp.Pos.Column))
+	}
+}
+
+func (e *encoder) constant(obj object.Object) {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		e.write([]byte{tagInteger})
+		e.varint(obj.Value)
+	case *object.Float:
+		e.write([]byte{tagFloat})
+		binary.LittleEndian.PutUint64(e.buf[:8], math.Float64bits(obj.Value))
+		e.write(e.buf[:8])
+	case *object.String:
+		e.write([]byte{tagString})
+		e.string(obj.Value)
+	case *object.CompiledFunction:
+		e.write([]byte{tagCompiledFunction})
+		e.string(obj.Name)
+		e.uvarint(uint64(obj.NumLocals))
+		e.uvarint(uint64(obj.NumParameters))
+		e.bytes(obj.Instructions)
+		e.sourceMap(obj.SourceMap)
+	default:
+		if e.err == nil {
+			e.err = fmt.Errorf("cannot encode constant of type %s", obj.Type())
+		}
+	}
+}
+
+// decoder reads the primitives of the bytecode format and records the
+// first error encountered so that callers only need to check once
+type decoder struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	var x uint64
+	x, d.err = binary.ReadUvarint(d.r)
+	return x
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	var x int64
+	x, d.err = binary.ReadVarint(d.r)
+	return x
+}
+
+// length reads a length or count and guards against corrupt values
+func (d *decoder) length() int {
+	n := d.uvarint()
+	if d.err == nil && n > math.MaxInt32 {
+		d.err = fmt.Errorf("%s: length %d out of range", ErrInvalidBytecode, n)
+	}
+	return int(n)
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.length()
+	if d.err != nil {
+		return nil
+	}
+	// Copy rather than preallocate so a corrupt length cannot allocate a huge
+	// buffer up front
+	var buf bytes.Buffer
+	_, d.err = io.CopyN(&buf, d.r, int64(n))
+	return buf.Bytes()
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes())
+}
+
+func (d *decoder) sourceMap() code.SourceMap {
+	n := d.length()
+	var sm code.SourceMap
+	for i := 0; i < n && d.err == nil; i++ {
+		offset := d.length()
+		filename := d.string()
+		line := d.length()
+		column := d.length()
+		sm = append(sm, code.SourcePos{
+			Offset: offset,
+			Pos:    token.Position{Filename: filename, Line: line, Column: column},
+		})
+	}
+	return sm
+}
+
+func (d *decoder) constant() object.Object {
+	if d.err != nil {
+		return nil
+	}
+
+	var tag byte
+	tag, d.err = d.r.ReadByte()
+	if d.err != nil {
+		return nil
+	}
+
+	switch tag {
+	case tagInteger:
+		return &object.Integer{Value: d.varint()}
+	case tagFloat:
+		var buf [8]byte
+		_, d.err = io.ReadFull(d.r, buf[:])
+		return &object.Float{Value: math.Float64frombits(binary.LittleEndian.Uint64(buf[:]))}
+	case tagString:
+		return &object.String{Value: d.string()}
+	case tagCompiledFunction:
+		fn := &object.CompiledFunction{}
+		fn.Name = d.string()
+		fn.NumLocals = d.length()
+		fn.NumParameters = d.length()
+		fn.Instructions = d.bytes()
+		fn.SourceMap = d.sourceMap()
+		return fn
+	default:
+		d.err = fmt.Errorf("%s: unknown constant tag %d", ErrInvalidBytecode, tag)
+		return nil
+	}
+}
@@ -0,0 +1,63 @@
+package compiler
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prologic/monkey-lang/lexer"
+	"github.com/prologic/monkey-lang/parser"
+)
+
+func TestBytecodeEncoding(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	input := `x := 1.5
+add := fn(a, b) { c := a + b; c }
+print(add(x, -42), "foo")`
+
+	l := lexer.NewWithFilename(input, "test.monkey")
+	p := parser.New(l)
+	program := p.ParseProgram()
+	require.Empty(p.Errors())
+
+	c := New()
+	require.NoError(c.Compile(program))
+	expected := c.Bytecode()
+
+	var buf bytes.Buffer
+	require.NoError(expected.Encode(&buf))
+	assert.True(bytes.HasPrefix(buf.Bytes(), BytecodeMagic))
+
+	actual, err := DecodeBytecode(&buf)
+	require.NoError(err)
+
+	assert.Equal(expected.Instructions, actual.Instructions)
+	assert.Equal(expected.SourceMap, actual.SourceMap)
+	assert.Equal(expected.Constants, actual.Constants)
+}
+
+func TestBytecodeDecodingErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	c := New()
+	assert.NoError(c.Compile(parse(`fn(x) { x * 2 }(21)`)))
+
+	var buf bytes.Buffer
+	assert.NoError(c.Bytecode().Encode(&buf))
+	valid := buf.Bytes()
+
+	_, err := DecodeBytecode(bytes.NewReader([]byte("x := 1")))
+	assert.Equal(ErrInvalidBytecode, err)
+
+	_, err = DecodeBytecode(bytes.NewReader(valid[:len(valid)-3]))
+	assert.EqualError(err, "invalid bytecode file: unexpected end of file")
+
+	version := append([]byte{}, valid...)
+	version[len(BytecodeMagic)] = BytecodeVersion + 1
+	_, err = DecodeBytecode(bytes.NewReader(version))
+	assert.EqualError(err, "unsupported bytecode version 9 (expected 8)")
+}
@@ -0,0 +1,541 @@
+package compiler
+
+// The optimizer rewrites the instructions of compiled functions and the main
+// program when optimizations are enabled. Instructions are decoded into a
+// list whose jumps refer to the instructions they target, which is rewritten
+// by passes that fold constant expressions, thread and fold jumps, remove
+// unreachable code and rewrite short sequences of instructions until none of
+// them makes any more changes, and is then encoded again.
+
+import (
+	"math"
+
+	"github.com/prologic/monkey-lang/code"
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/token"
+)
+
+// instruction is a decoded instruction
+type instruction struct {
+	op       code.Opcode
+	operands []int
+	pos      token.Position
+
+	// target is the instruction a jump targets, the end of the instructions
+	// is represented by an instruction with the opcode `Noop` which is
+	// always last
+	target *instruction
+}
+
+// isJump returns true if the first operand of the instruction is the
+// position of an instruction
+func isJump(op code.Opcode) bool {
+	switch op {
+	case code.Jump, code.JumpIfFalse, code.IterNext, code.SetupTry, code.JumpIfNotCase:
+		return true
+	}
+	return false
+}
+
+// isPure returns true if the instruction only pushes a value onto the stack
+// without any other effect
+func isPure(op code.Opcode) bool {
+	switch op {
+	case code.LoadConstant, code.LoadTrue, code.LoadFalse, code.LoadNull,
+		code.LoadGlobal, code.LoadLocal, code.LoadFree, code.LoadBuiltin:
+		return true
+	}
+	return false
+}
+
+// optimizer optimizes a list of instructions
+type optimizer struct {
+	c    *Compiler
+	list []*instruction
+
+	// function is true if the instructions are those of a function, the
+	// value last popped by the main program is the result of the program
+	// (e.g: in the REPL) so pure instructions whose value is popped are only
+	// removed from functions
+	function bool
+
+	// targeted are the instructions targeted by jumps and moved are the
+	// instructions removed by a pass and the instructions which replace
+	// them as the target of jumps
+	targeted map[*instruction]bool
+	moved    map[*instruction]*instruction
+
+	// folded are the indexes of the constants added by folding, which are
+	// only loaded by the instruction replacing the operation folded
+	folded map[int]bool
+}
+
+// optimize returns the optimized instructions and source map of a function
+// (or the main program)
+func (c *Compiler) optimize(
+	ins code.Instructions,
+	sourceMap code.SourceMap,
+	function bool,
+) (code.Instructions, code.SourceMap) {
+	o := &optimizer{c: c, function: function, folded: make(map[int]bool)}
+	o.decode(ins, sourceMap)
+
+	passes := []func() bool{o.fold, o.jumps, o.dead, o.peephole}
+	for changed := true; changed; {
+		changed = false
+		for _, pass := range passes {
+			o.targeted = make(map[*instruction]bool)
+			o.moved = make(map[*instruction]*instruction)
+			for _, in := range o.list {
+				if isJump(in.op) {
+					o.targeted[in.target] = true
+				}
+			}
+
+			if pass() {
+				o.retarget()
+				changed = true
+			}
+		}
+	}
+
+	return o.encode()
+}
+
+// decode decodes the instructions into the list of instructions
+func (o *optimizer) decode(ins code.Instructions, sourceMap code.SourceMap) {
+	offsets := make(map[int]*instruction)
+	for i := 0; i < len(ins); {
+		def, _ := code.Lookup(ins[i])
+		operands, read := code.ReadOperands(def, ins[i+1:])
+		in := &instruction{
+			op:       code.Opcode(ins[i]),
+			operands: operands,
+			pos:      sourceMap.Lookup(i),
+		}
+		offsets[i] = in
+		o.list = append(o.list, in)
+		i += 1 + read
+	}
+
+	end := &instruction{op: code.Noop}
+	offsets[len(ins)] = end
+	o.list = append(o.list, end)
+
+	for _, in := range o.list {
+		if isJump(in.op) {
+			in.target = offsets[in.operands[0]]
+		}
+	}
+}
+
+// encode encodes the list of instructions
+func (o *optimizer) encode() (code.Instructions, code.SourceMap) {
+	offsets := make(map[*instruction]int)
+	offset := 0
+	for _, in := range o.list {
+		offsets[in] = offset
+		if in != o.end() {
+			def, _ := code.Lookup(byte(in.op))
+			offset++
+			for _, width := range def.OperandWidths {
+				offset += width
+			}
+		}
+	}
+
+	ins := code.Instructions{}
+	sourceMap := code.SourceMap{}
+	for _, in := range o.list[:len(o.list)-1] {
+		operands := in.operands
+		if isJump(in.op) {
+			operands = append([]int{offsets[in.target]}, operands[1:]...)
+		}
+		sourceMap = sourceMap.Add(len(ins), in.pos)
+		ins = append(ins, code.Make(in.op, operands...)...)
+	}
+
+	return ins, sourceMap
+}
+
+// end returns the instruction representing the end of the instructions
+func (o *optimizer) end() *instruction {
+	return o.list[len(o.list)-1]
+}
+
+// move records that jumps targeting the instruction removed (or replaced)
+// target the instruction to instead
+func (o *optimizer) move(removed, to *instruction) {
+	o.moved[removed] = to
+	if o.targeted[removed] {
+		o.targeted[to] = true
+	}
+}
+
+// retarget updates the targets of jumps to instructions removed by a pass
+func (o *optimizer) retarget() {
+	for _, in := range o.list {
+		if !isJump(in.op) {
+			continue
+		}
+		for {
+			to, ok := o.moved[in.target]
+			if !ok {
+				break
+			}
+			in.target = to
+		}
+	}
+}
+
+// constant returns the constant value pushed by the instruction (if any)
+func (o *optimizer) constant(in *instruction) (object.Object, bool) {
+	switch in.op {
+	case code.LoadTrue:
+		return &object.Boolean{Value: true}, true
+	case code.LoadFalse:
+		return &object.Boolean{Value: false}, true
+	case code.LoadConstant:
+		switch obj := o.c.constants[in.operands[0]].(type) {
+		case *object.Integer, *object.Float, *object.String:
+			return obj, true
+		}
+	}
+	return nil, false
+}
+
+// load returns an instruction pushing the constant value folded from the
+// operands, the constant of an operand which was itself folded is reused
+func (o *optimizer) load(obj object.Object, operands ...*instruction) *instruction {
+	pos := operands[0].pos
+
+	if b, ok := obj.(*object.Boolean); ok {
+		if b.Value {
+			return &instruction{op: code.LoadTrue, pos: pos}
+		}
+		return &instruction{op: code.LoadFalse, pos: pos}
+	}
+
+	for _, operand := range operands {
+		if operand.op == code.LoadConstant && o.folded[operand.operands[0]] {
+			index := operand.operands[0]
+			o.c.constants[index] = obj
+			return &instruction{op: code.LoadConstant, operands: []int{index}, pos: pos}
+		}
+	}
+
+	index := o.c.addConstant(obj)
+	o.folded[index] = true
+	return &instruction{op: code.LoadConstant, operands: []int{index}, pos: pos}
+}
+
+// fold replaces operations on constant values with their results, e.g:
+// `2 * 60 * 60` is replaced by `7200`
+func (o *optimizer) fold() bool {
+	changed := false
+	list := []*instruction{}
+
+	for _, in := range o.list {
+		list = append(list, in)
+
+		for {
+			n := len(list)
+			if n >= 2 && !o.targeted[list[n-1]] {
+				if operand, ok := o.constant(list[n-2]); ok {
+					if result, ok := foldUnary(list[n-1].op, operand); ok {
+						folded := o.load(result, list[n-2])
+						o.move(list[n-2], folded)
+						list = append(list[:n-2], folded)
+						changed = true
+						continue
+					}
+				}
+			}
+
+			if n >= 3 && !o.targeted[list[n-1]] && !o.targeted[list[n-2]] {
+				left, ok1 := o.constant(list[n-3])
+				right, ok2 := o.constant(list[n-2])
+				if ok1 && ok2 {
+					if result, ok := foldBinary(list[n-1].op, left, right); ok {
+						folded := o.load(result, list[n-3], list[n-2])
+						o.move(list[n-3], folded)
+						list = append(list[:n-3], folded)
+						changed = true
+						continue
+					}
+				}
+			}
+
+			break
+		}
+	}
+
+	o.list = list
+	return changed
+}
+
+// foldUnary returns the result of the unary operation on a constant value,
+// as computed by the virtual machine, if it can be computed
+func foldUnary(op code.Opcode, operand object.Object) (object.Object, bool) {
+	switch op {
+	case code.Not:
+		if b, ok := operand.(*object.Boolean); ok {
+			return &object.Boolean{Value: !b.Value}, true
+		}
+		return &object.Boolean{Value: false}, true
+	case code.Minus:
+		switch obj := operand.(type) {
+		case *object.Integer:
+			return &object.Integer{Value: -obj.Value}, true
+		case *object.Float:
+			return &object.Float{Value: -obj.Value}, true
+		}
+	case code.BitwiseNOT:
+		if i, ok := operand.(*object.Integer); ok {
+			return &object.Integer{Value: ^i.Value}, true
+		}
+	}
+	return nil, false
+}
+
+// foldBinary returns the result of the binary operation on constant values,
+// as computed by the virtual machine, if it can be computed without error
+func foldBinary(op code.Opcode, left, right object.Object) (object.Object, bool) {
+	switch op {
+	case code.Equal:
+		return &object.Boolean{Value: left.(object.Comparable).Compare(right) == 0}, true
+	case code.NotEqual:
+		return &object.Boolean{Value: left.(object.Comparable).Compare(right) != 0}, true
+	case code.GreaterThanEqual:
+		return &object.Boolean{Value: left.(object.Comparable).Compare(right) > -1}, true
+	case code.GreaterThan:
+		return &object.Boolean{Value: left.(object.Comparable).Compare(right) == 1}, true
+	}
+
+	switch left := left.(type) {
+	case *object.Boolean:
+		if right, ok := right.(*object.Boolean); ok {
+			switch op {
+			case code.Or:
+				return &object.Boolean{Value: left.Value || right.Value}, true
+			case code.And:
+				return &object.Boolean{Value: left.Value && right.Value}, true
+			}
+		}
+
+	case *object.Integer:
+		switch right := right.(type) {
+		case *object.Integer:
+			return foldInteger(op, left.Value, right.Value)
+		case *object.Float:
+			return foldFloat(op, float64(left.Value), right.Value)
+		}
+
+	case *object.Float:
+		switch right := right.(type) {
+		case *object.Integer:
+			return foldFloat(op, left.Value, float64(right.Value))
+		case *object.Float:
+			return foldFloat(op, left.Value, right.Value)
+		}
+
+	case *object.String:
+		if right, ok := right.(*object.String); ok && op == code.Add {
+			return &object.String{Value: left.Value + right.Value}, true
+		}
+	}
+
+	return nil, false
+}
+
+func foldInteger(op code.Opcode, left, right int64) (object.Object, bool) {
+	var result int64
+
+	switch op {
+	case code.Add:
+		result = left + right
+	case code.Sub:
+		result = left - right
+	case code.Mul:
+		result = left * right
+	case code.Div:
+		if right == 0 {
+			return nil, false
+		}
+		result = left / right
+	case code.Mod:
+		if right == 0 {
+			return nil, false
+		}
+		result = left % right
+	case code.BitwiseOR:
+		result = left | right
+	case code.BitwiseXOR:
+		result = left ^ right
+	case code.BitwiseAND:
+		result = left & right
+	case code.LeftShift:
+		result = left << uint64(right)
+	case code.RightShift:
+		result = left >> uint64(right)
+	default:
+		return nil, false
+	}
+
+	return &object.Integer{Value: result}, true
+}
+
+func foldFloat(op code.Opcode, left, right float64) (object.Object, bool) {
+	var result float64
+
+	switch op {
+	case code.Add:
+		result = left + right
+	case code.Sub:
+		result = left - right
+	case code.Mul:
+		result = left * right
+	case code.Div:
+		result = left / right
+	case code.Mod:
+		result = math.Mod(left, right)
+	default:
+		return nil, false
+	}
+
+	return &object.Float{Value: result}, true
+}
+
+// jumps threads jumps to unconditional jumps through to their final target,
+// folds conditional jumps on constant values and removes jumps to the next
+// instruction
+func (o *optimizer) jumps() bool {
+	changed := false
+
+	for _, in := range o.list {
+		if !isJump(in.op) {
+			continue
+		}
+		target := in.target
+		seen := map[*instruction]bool{in: true}
+		for target.op == code.Jump && !seen[target] {
+			seen[target] = true
+			target = target.target
+		}
+		if target != in.target {
+			in.target = target
+			changed = true
+		}
+	}
+
+	list := []*instruction{}
+	for i := 0; i < len(o.list); i++ {
+		in := o.list[i]
+
+		if i+1 < len(o.list) {
+			next := o.list[i+1]
+			if next.op == code.JumpIfFalse && !o.targeted[next] {
+				if value, ok := o.constant(in); ok || in.op == code.LoadNull {
+					if b, ok := value.(*object.Boolean); in.op == code.LoadNull || (ok && !b.Value) {
+						// The jump is always taken
+						jump := &instruction{op: code.Jump, operands: []int{0}, pos: next.pos, target: next.target}
+						o.move(in, jump)
+						list = append(list, jump)
+					} else {
+						// The jump is never taken
+						o.move(in, o.list[i+2])
+						o.move(next, o.list[i+2])
+					}
+					i++
+					changed = true
+					continue
+				}
+			}
+		}
+
+		if in.op == code.Jump && i+1 < len(o.list) && in.target == o.list[i+1] {
+			o.move(in, o.list[i+1])
+			changed = true
+			continue
+		}
+
+		list = append(list, in)
+	}
+
+	o.list = list
+	return changed
+}
+
+// dead removes instructions which can never be executed, e.g: code after a
+// `return` statement
+func (o *optimizer) dead() bool {
+	reachable := make(map[*instruction]bool)
+	index := make(map[*instruction]int)
+	for i, in := range o.list {
+		index[in] = i
+	}
+
+	work := []*instruction{o.list[0]}
+	for len(work) > 0 {
+		in := work[len(work)-1]
+		work = work[:len(work)-1]
+		if reachable[in] {
+			continue
+		}
+		reachable[in] = true
+
+		if isJump(in.op) {
+			work = append(work, in.target)
+		}
+		switch in.op {
+		case code.Jump, code.Return, code.Throw:
+			continue
+		}
+		if in != o.end() {
+			work = append(work, o.list[index[in]+1])
+		}
+	}
+
+	changed := false
+	list := []*instruction{}
+	for _, in := range o.list {
+		if reachable[in] || in == o.end() {
+			list = append(list, in)
+		} else {
+			changed = true
+		}
+	}
+
+	o.list = list
+	return changed
+}
+
+// peephole removes instructions which have no effect, `Noop` and (in
+// functions) pure instructions whose value is popped
+func (o *optimizer) peephole() bool {
+	changed := false
+	list := []*instruction{}
+
+	for i := 0; i < len(o.list); i++ {
+		in := o.list[i]
+
+		if in.op == code.Noop && in != o.end() {
+			o.move(in, o.list[i+1])
+			changed = true
+			continue
+		}
+
+		if o.function && isPure(in.op) && o.list[i+1].op == code.Pop && !o.targeted[o.list[i+1]] {
+			o.move(in, o.list[i+2])
+			o.move(o.list[i+1], o.list[i+2])
+			i++
+			changed = true
+			continue
+		}
+
+		list = append(list, in)
+	}
+
+	o.list = list
+	return changed
+}
@@ -0,0 +1,137 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func runOptimizerTests(t *testing.T, tests []compilerTestCase2) {
+	t.Helper()
+
+	for _, tt := range tests {
+		program := parse(tt.input)
+
+		compiler := New()
+		compiler.Optimize = true
+		err := compiler.Compile(program)
+		assert.NoError(t, err)
+
+		bytecode := compiler.Bytecode()
+		assert.Equal(t, tt.instructions, bytecode.Instructions.String(), tt.input)
+
+		testConstants2(t, tt.constants, bytecode.Constants)
+	}
+}
+
+func TestConstantFolding(t *testing.T) {
+	tests := []compilerTestCase2{
+		{
+			input:        "2 * 60 * 60",
+			constants:    []interface{}{2, 60, 60, 7200},
+			instructions: "0000 LoadConstant 3\n0003 Pop\n",
+		},
+		{
+			input:        "x := 2; x * 60",
+			constants:    []interface{}{2, 60},
+			instructions: "0000 LoadConstant 0\n0003 BindGlobal 0\n0006 Pop\n0007 LoadGlobal 0\n0010 LoadConstant 1\n0013 Mul\n0014 Pop\n",
+		},
+		{
+			input:        `-1 + 2 * 3; "a" + "b"`,
+			constants:    []interface{}{1, 2, 3, "a", "b", 5, 6, "ab"},
+			instructions: "0000 LoadConstant 5\n0003 Pop\n0004 LoadConstant 7\n0007 Pop\n",
+		},
+		{
+			input:        "1 < 2.5; !true; true && false",
+			constants:    []interface{}{2.5, 1},
+			instructions: "0000 LoadTrue\n0001 Pop\n0002 LoadFalse\n0003 Pop\n0004 LoadFalse\n0005 Pop\n",
+		},
+		{
+			// Operations raising errors are left to raise them at runtime
+			input:        "1 / 0",
+			constants:    []interface{}{1, 0},
+			instructions: "0000 LoadConstant 0\n0003 LoadConstant 1\n0006 Div\n0007 Pop\n",
+		},
+	}
+
+	runOptimizerTests(t, tests)
+}
+
+func TestJumpOptimizations(t *testing.T) {
+	tests := []compilerTestCase2{
+		{
+			input:        "if (true) { 10 } else { 20 }",
+			constants:    []interface{}{10, 20},
+			instructions: "0000 LoadConstant 0\n0003 Pop\n",
+		},
+		{
+			input:        "if (1 > 2) { 10 } else { 20 }",
+			constants:    []interface{}{1, 2, 10, 20},
+			instructions: "0000 LoadConstant 3\n0003 Pop\n",
+		},
+		{
+			// The jump over the alternative of the inner if is threaded
+			// through to the end of the outer if
+			input:        "x := 1; if (x) { if (x) { 1 } else { 2 } } else { 3 }",
+			constants:    []interface{}{1, 1, 2, 3},
+			instructions: "0000 LoadConstant 0\n0003 BindGlobal 0\n0006 Pop\n0007 LoadGlobal 0\n0010 JumpIfFalse 31\n0013 LoadGlobal 0\n0016 JumpIfFalse 25\n0019 LoadConstant 1\n0022 Jump 34\n0025 LoadConstant 2\n0028 Jump 34\n0031 LoadConstant 3\n0034 Pop\n",
+		},
+	}
+
+	runOptimizerTests(t, tests)
+}
+
+func TestDeadCodeElimination(t *testing.T) {
+	tests := []compilerTestCase2{
+		{
+			input: "fn() { return 1; 2 }",
+			constants: []interface{}{
+				1, 2,
+				Instructions("0000 LoadConstant 0\n0003 Return\n"),
+			},
+			instructions: "0000 MakeClosure 2 0\n0004 Pop\n",
+		},
+		{
+			input:        "while (true) { break; 1 }",
+			constants:    []interface{}{1},
+			instructions: "0000 LoadNull\n0001 Pop\n",
+		},
+	}
+
+	runOptimizerTests(t, tests)
+}
+
+func TestPeepholeOptimizations(t *testing.T) {
+	tests := []compilerTestCase2{
+		{
+			// Values popped without effect are removed from functions
+			input: "fn(x) { x; 1; return x }",
+			constants: []interface{}{
+				1,
+				Instructions("0000 LoadLocal 0\n0002 Return\n"),
+			},
+			instructions: "0000 MakeClosure 1 0\n0004 Pop\n",
+		},
+		{
+			// But kept in the main program as its last value is its result
+			input:        "1; 2",
+			constants:    []interface{}{1, 2},
+			instructions: "0000 LoadConstant 0\n0003 Pop\n0004 LoadConstant 1\n0007 Pop\n",
+		},
+	}
+
+	runOptimizerTests(t, tests)
+}
+
+func TestOptimizedSourceMap(t *testing.T) {
+	program := parse("x := 1\nif (x) {\n  2 * 3\n} else {\n  4\n}")
+
+	compiler := New()
+	compiler.Optimize = true
+	assert.NoError(t, compiler.Compile(program))
+
+	bytecode := compiler.Bytecode()
+	for offset, line := range map[int]int{0: 1, 7: 2, 13: 3, 19: 5} {
+		assert.Equal(t, line, bytecode.SourceMap.Lookup(offset).Line, "offset %d", offset)
+	}
+}
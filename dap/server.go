@@ -0,0 +1,660 @@
+// Package dap implements a Debug Adapter Protocol server which lets editors
+// and IDEs debug programs running on the bytecode virtual machine. The
+// server speaks the protocol over a pair of streams (usually stdin and
+// stdout) and drives a debugger.Debugger to set breakpoints, step through
+// the program and inspect its stack frames, scopes and variables.
+//
+// Only a single program is debugged per session and, as the virtual machine
+// has no threads, it is always reported as a single thread.
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prologic/monkey-lang/debugger"
+	"github.com/prologic/monkey-lang/internal/framing"
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/vm"
+)
+
+// threadID is the id of the only thread
+const threadID = 1
+
+// scopeHandle refers to the variables of a scope of a frame
+type scopeHandle struct {
+	frame *vm.Frame
+	kind  string // one of "Locals", "Free" or "Globals"
+}
+
+// Server is a Debug Adapter Protocol server reading requests from in and
+// writing responses and events to out
+type Server struct {
+	in *bufio.Reader
+
+	wmu sync.Mutex // guards out and seq
+	out io.Writer
+	seq int
+
+	// Offsets of the client's line and column numbers from ours, which
+	// start at 1
+	lineOffset   int
+	columnOffset int
+
+	program  string
+	args     []string
+	noDebug  bool
+	debugger *debugger.Debugger
+	resume   chan func(d *debugger.Debugger)
+	done     chan struct{} // closed once the program has terminated
+
+	mu      sync.Mutex // guards the state below
+	running bool
+	stopped bool
+	quit    bool
+
+	// handles are the scopes and values referred to by the client as
+	// variable references (index + 1), valid while execution is stopped
+	handles []interface{}
+}
+
+// NewServer returns a new server reading requests from in and writing
+// responses and events to out
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:     bufio.NewReader(in),
+		out:    out,
+		resume: make(chan func(d *debugger.Debugger)),
+		done:   make(chan struct{}),
+	}
+}
+
+// Serve handles requests until the client disconnects or closes the input
+func (s *Server) Serve() error {
+	for {
+		content, err := framing.ReadMessage(s.in)
+		if err == io.EOF {
+			s.halt()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(content, &req); err != nil {
+			return fmt.Errorf("error decoding message: %s", err)
+		}
+		if req.Type != "request" {
+			continue
+		}
+
+		s.handle(&req)
+
+		if req.Command == "disconnect" {
+			return nil
+		}
+	}
+}
+
+// handle handles the request req
+func (s *Server) handle(req *request) {
+	switch req.Command {
+	case "initialize":
+		s.initialize(req)
+	case "launch":
+		s.launch(req)
+	case "setBreakpoints":
+		s.setBreakpoints(req)
+	case "configurationDone":
+		s.configurationDone(req)
+	case "threads":
+		s.respond(req, threadsResponseBody{[]thread{{threadID, "main"}}}, nil)
+	case "stackTrace":
+		s.stackTrace(req)
+	case "scopes":
+		s.scopes(req)
+	case "variables":
+		s.variables(req)
+	case "evaluate":
+		s.evaluate(req)
+	case "continue":
+		s.resumeWith(req, (*debugger.Debugger).Continue)
+	case "next":
+		s.resumeWith(req, (*debugger.Debugger).StepOver)
+	case "stepIn":
+		s.resumeWith(req, (*debugger.Debugger).StepInto)
+	case "stepOut":
+		s.resumeWith(req, (*debugger.Debugger).StepOut)
+	case "pause":
+		s.pause(req)
+	case "disconnect":
+		s.halt()
+		s.respond(req, nil, nil)
+	default:
+		s.respond(req, nil, fmt.Errorf("unsupported request %q", req.Command))
+	}
+}
+
+func (s *Server) initialize(req *request) {
+	var args initializeArguments
+	if err := s.arguments(req, &args); err != nil {
+		return
+	}
+
+	if args.LinesStartAt1 != nil && !*args.LinesStartAt1 {
+		s.lineOffset = -1
+	}
+	if args.ColumnsStartAt1 != nil && !*args.ColumnsStartAt1 {
+		s.columnOffset = -1
+	}
+
+	s.respond(req, capabilities{
+		SupportsConfigurationDoneRequest: true,
+		SupportsEvaluateForHovers:        true,
+	}, nil)
+}
+
+func (s *Server) launch(req *request) {
+	var args launchArguments
+	if err := s.arguments(req, &args); err != nil {
+		return
+	}
+
+	if s.debugger != nil {
+		s.respond(req, nil, fmt.Errorf("program %s already launched", s.program))
+		return
+	}
+	if args.Program == "" {
+		s.respond(req, nil, fmt.Errorf("no program given to launch"))
+		return
+	}
+
+	program, err := filepath.Abs(args.Program)
+	if err != nil {
+		s.respond(req, nil, err)
+		return
+	}
+
+	b, err := ioutil.ReadFile(program)
+	if err != nil {
+		s.respond(req, nil, fmt.Errorf("error reading source file: %s", err))
+		return
+	}
+
+	d, err := debugger.New(program, string(b), s.stop)
+	if err != nil {
+		s.respond(req, nil, err)
+		return
+	}
+	d.StopOnEntry = args.StopOnEntry && !args.NoDebug
+
+	s.program = program
+	s.args = args.Args
+	s.noDebug = args.NoDebug
+	s.debugger = d
+
+	s.respond(req, nil, nil)
+	s.event("initialized", nil)
+}
+
+func (s *Server) setBreakpoints(req *request) {
+	var args setBreakpointsArguments
+	if err := s.arguments(req, &args); err != nil {
+		return
+	}
+
+	lines := args.Lines
+	if args.Breakpoints != nil {
+		lines = nil
+		for _, bp := range args.Breakpoints {
+			lines = append(lines, bp.Line)
+		}
+	}
+
+	var message string
+	switch {
+	case s.debugger == nil:
+		message = "program not launched"
+	case s.noDebug:
+		message = "program launched without debugging"
+	case !s.isProgram(args.Source.Path):
+		message = "source is not the launched program"
+	}
+
+	breakpoints := []breakpoint{}
+	if message != "" {
+		for _, line := range lines {
+			breakpoints = append(breakpoints, breakpoint{false, line, message})
+		}
+		s.respond(req, setBreakpointsResponseBody{breakpoints}, nil)
+		return
+	}
+
+	// The request replaces all breakpoints of the source
+	for _, line := range s.debugger.Breakpoints() {
+		s.debugger.ClearBreakpoint(line)
+	}
+	for _, line := range lines {
+		bp := breakpoint{Verified: true, Line: line}
+		if err := s.debugger.SetBreakpoint(line - s.lineOffset); err != nil {
+			bp.Verified, bp.Message = false, err.Error()
+		}
+		breakpoints = append(breakpoints, bp)
+	}
+
+	s.respond(req, setBreakpointsResponseBody{breakpoints}, nil)
+}
+
+func (s *Server) configurationDone(req *request) {
+	if s.debugger == nil {
+		s.respond(req, nil, fmt.Errorf("program not launched"))
+		return
+	}
+
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		s.respond(req, nil, fmt.Errorf("program %s already running", s.program))
+		return
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	s.respond(req, nil, nil)
+	go s.run()
+}
+
+func (s *Server) stackTrace(req *request) {
+	var args stackTraceArguments
+	if err := s.arguments(req, &args); err != nil {
+		return
+	}
+	if !s.isStopped(req) {
+		return
+	}
+
+	frames := s.debugger.Frames()
+
+	stackFrames := []stackFrame{}
+	for id := len(frames) - 1 - args.StartFrame; id >= 0; id-- {
+		if args.Levels > 0 && len(stackFrames) == args.Levels {
+			break
+		}
+		pos := frames[id].SourcePos()
+		stackFrames = append(stackFrames, stackFrame{
+			ID:     id,
+			Name:   frames[id].Name(),
+			Source: &source{Name: filepath.Base(pos.Filename), Path: pos.Filename},
+			Line:   pos.Line + s.lineOffset,
+			Column: pos.Column + s.columnOffset,
+		})
+	}
+
+	s.respond(req, stackTraceResponseBody{stackFrames, len(frames)}, nil)
+}
+
+func (s *Server) scopes(req *request) {
+	var args scopesArguments
+	if err := s.arguments(req, &args); err != nil {
+		return
+	}
+	if !s.isStopped(req) {
+		return
+	}
+
+	frame, err := s.frame(args.FrameID)
+	if err != nil {
+		s.respond(req, nil, err)
+		return
+	}
+
+	scopes := []scope{}
+	// The main program has no locals of its own, its variables are globals
+	if args.FrameID > 0 {
+		scopes = append(scopes, scope{Name: "Locals", VariablesReference: s.reference(scopeHandle{frame, "Locals"})})
+		if len(s.debugger.Free(frame)) > 0 {
+			scopes = append(scopes, scope{Name: "Free", VariablesReference: s.reference(scopeHandle{frame, "Free"})})
+		}
+	}
+	scopes = append(scopes, scope{Name: "Globals", VariablesReference: s.reference(scopeHandle{frame, "Globals"})})
+
+	s.respond(req, scopesResponseBody{scopes}, nil)
+}
+
+func (s *Server) variables(req *request) {
+	var args variablesArguments
+	if err := s.arguments(req, &args); err != nil {
+		return
+	}
+	if !s.isStopped(req) {
+		return
+	}
+
+	s.mu.Lock()
+	ref := args.VariablesReference
+	var h interface{}
+	if ref > 0 && ref <= len(s.handles) {
+		h = s.handles[ref-1]
+	}
+	s.mu.Unlock()
+
+	variables := []variable{}
+	switch h := h.(type) {
+	case scopeHandle:
+		var vars []debugger.Variable
+		switch h.kind {
+		case "Locals":
+			vars = s.debugger.Locals(h.frame)
+		case "Free":
+			vars = s.debugger.Free(h.frame)
+		case "Globals":
+			vars = s.debugger.Globals()
+		}
+		for _, v := range vars {
+			variables = append(variables, s.variable(v.Name, v.Value))
+		}
+	case *object.Array:
+		for i, element := range h.Elements {
+			variables = append(variables, s.variable("["+strconv.Itoa(i)+"]", element))
+		}
+	case *object.Hash:
+		for _, pair := range h.Pairs() {
+			variables = append(variables, s.variable(pair.Key.Inspect(), pair.Value))
+		}
+	default:
+		s.respond(req, nil, fmt.Errorf("invalid variables reference %d", ref))
+		return
+	}
+
+	s.respond(req, variablesResponseBody{variables}, nil)
+}
+
+func (s *Server) evaluate(req *request) {
+	var args evaluateArguments
+	if err := s.arguments(req, &args); err != nil {
+		return
+	}
+	if !s.isStopped(req) {
+		return
+	}
+
+	frames := s.debugger.Frames()
+	id := len(frames) - 1
+	if args.FrameID != nil {
+		id = *args.FrameID
+	}
+	frame, err := s.frame(id)
+	if err != nil {
+		s.respond(req, nil, err)
+		return
+	}
+
+	// Only variables can be evaluated as expressions would have to be
+	// compiled against the frame's symbol table and executed on the stopped
+	// virtual machine
+	v, ok := s.debugger.Lookup(frame, args.Expression)
+	if !ok {
+		s.respond(req, nil, fmt.Errorf("undefined variable %s", args.Expression))
+		return
+	}
+
+	result := s.variable(v.Name, v.Value)
+	s.respond(req, evaluateResponseBody{
+		Result:             result.Value,
+		Type:               result.Type,
+		VariablesReference: result.VariablesReference,
+	}, nil)
+}
+
+// resumeWith resumes execution by calling resume on the debugger, the
+// response is sent before execution resumes so it precedes further events
+func (s *Server) resumeWith(req *request, resume func(d *debugger.Debugger)) {
+	if !s.isStopped(req) {
+		return
+	}
+
+	s.mu.Lock()
+	s.stopped = false
+	s.handles = nil
+	s.mu.Unlock()
+
+	var body interface{}
+	if req.Command == "continue" {
+		body = continueResponseBody{AllThreadsContinued: true}
+	}
+	s.respond(req, body, nil)
+
+	s.resume <- resume
+}
+
+func (s *Server) pause(req *request) {
+	s.mu.Lock()
+	running, stopped := s.running, s.stopped
+	s.mu.Unlock()
+
+	if running && !stopped {
+		s.debugger.Pause()
+	}
+	s.respond(req, nil, nil)
+}
+
+// halt halts the program, if running, and waits for it to terminate
+func (s *Server) halt() {
+	s.mu.Lock()
+	running, stopped := s.running, s.stopped
+	s.quit = true
+	s.stopped = false
+	s.mu.Unlock()
+
+	if !running {
+		return
+	}
+
+	if stopped {
+		s.resume <- (*debugger.Debugger).Quit
+	} else {
+		// The program quits the next time it stops
+		s.debugger.Pause()
+	}
+	<-s.done
+}
+
+// stop is the debugger's handler which notifies the client that execution
+// stopped and blocks until a request resumes execution
+func (s *Server) stop(d *debugger.Debugger, reason debugger.Reason) {
+	s.mu.Lock()
+	if s.quit {
+		s.mu.Unlock()
+		d.Quit()
+		return
+	}
+	s.stopped = true
+	s.mu.Unlock()
+
+	s.event("stopped", stoppedEventBody{
+		Reason:            string(reason),
+		ThreadID:          threadID,
+		AllThreadsStopped: true,
+	})
+
+	resume := <-s.resume
+	resume(d)
+}
+
+// run runs the program sending its output to the client as output events
+// and notifies the client once it has exited and terminated
+func (s *Server) run() {
+	defer close(s.done)
+
+	// The process' standard output and input are (usually) the streams of
+	// the protocol itself, so the program's output is sent to the client
+	// and the program is given no input
+	ctx := object.NewContext()
+	ctx.Stdin = strings.NewReader("")
+	ctx.Stdout = &outputWriter{s: s, category: "stdout"}
+	ctx.Stderr = &outputWriter{s: s, category: "stderr"}
+	ctx.Args = append([]string{s.program}, s.args...)
+
+	exitCode := 0
+	defer func() { s.terminated(exitCode) }()
+
+	// exit() stops the program by exiting its goroutine, as exiting the
+	// process would end the debugging session without notifying the client
+	ctx.Exit = func(status int) {
+		exitCode = status
+		runtime.Goexit()
+	}
+	s.debugger.Context = ctx
+
+	if err := s.debugger.Run(); err != nil {
+		if e, ok := err.(*vm.Error); ok {
+			s.output("stderr", e.StackTrace())
+		} else {
+			s.output("stderr", err.Error()+"\n")
+		}
+		exitCode = 1
+	}
+}
+
+// outputWriter sends the output written to it to the client as output
+// events of its category
+type outputWriter struct {
+	s        *Server
+	category string
+}
+
+func (w *outputWriter) Write(p []byte) (int, error) {
+	w.s.output(w.category, string(p))
+	return len(p), nil
+}
+
+// terminated notifies the client that the program has exited with the exit
+// code and the debugging session is over
+func (s *Server) terminated(exitCode int) {
+	s.event("exited", exitedEventBody{exitCode})
+	s.event("terminated", nil)
+}
+
+// output sends output of the given category to the client
+func (s *Server) output(category, output string) {
+	s.event("output", outputEventBody{category, output})
+}
+
+// isStopped returns true if execution is stopped and otherwise responds to
+// the request with an error
+func (s *Server) isStopped(req *request) bool {
+	s.mu.Lock()
+	stopped := s.stopped
+	s.mu.Unlock()
+
+	if !stopped {
+		s.respond(req, nil, fmt.Errorf("program is not stopped"))
+	}
+	return stopped
+}
+
+// isProgram returns true if path is the path of the launched program
+func (s *Server) isProgram(path string) bool {
+	path, err := filepath.Abs(path)
+	return err == nil && path == s.program
+}
+
+// frame returns the frame with the given id, its index in the call stack
+func (s *Server) frame(id int) (*vm.Frame, error) {
+	frames := s.debugger.Frames()
+	if id < 0 || id >= len(frames) {
+		return nil, fmt.Errorf("invalid frame %d", id)
+	}
+	return frames[id], nil
+}
+
+// reference returns a new variable reference to h
+func (s *Server) reference(h interface{}) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handles = append(s.handles, h)
+	return len(s.handles)
+}
+
+// variable returns the named variable with the given value, arrays and hashes
+// are given a reference to their elements
+func (s *Server) variable(name string, value object.Object) variable {
+	if value == nil {
+		return variable{Name: name, Value: "<unset>"}
+	}
+
+	v := variable{Name: name, Value: value.Inspect(), Type: string(value.Type())}
+	switch value := value.(type) {
+	case *object.Array:
+		if len(value.Elements) > 0 {
+			v.VariablesReference = s.reference(value)
+			v.IndexedVariables = len(value.Elements)
+		}
+	case *object.Hash:
+		if value.Len() > 0 {
+			v.VariablesReference = s.reference(value)
+			v.NamedVariables = value.Len()
+		}
+	}
+	return v
+}
+
+// arguments decodes the arguments of the request into v and otherwise
+// responds to the request with an error
+func (s *Server) arguments(req *request, v interface{}) error {
+	if len(req.Arguments) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(req.Arguments, v); err != nil {
+		err = fmt.Errorf("invalid arguments: %s", err)
+		s.respond(req, nil, err)
+		return err
+	}
+	return nil
+}
+
+// respond sends the response to the request req with the given body or
+// reporting the error err if not nil
+func (s *Server) respond(req *request, body interface{}, err error) {
+	res := &response{
+		Type:       "response",
+		RequestSeq: req.Seq,
+		Success:    err == nil,
+		Command:    req.Command,
+		Body:       body,
+	}
+	if err != nil {
+		res.Message = err.Error()
+	}
+	s.send(func(seq int) interface{} {
+		res.Seq = seq
+		return res
+	})
+}
+
+// event sends the named event with the given body
+func (s *Server) event(name string, body interface{}) {
+	s.send(func(seq int) interface{} {
+		return &event{Seq: seq, Type: "event", Event: name, Body: body}
+	})
+}
+
+// send sends the message returned by msg for the next sequence number
+func (s *Server) send(msg func(seq int) interface{}) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	s.seq++
+	// There is no one to report a failure to write to the client to
+	framing.WriteMessage(s.out, msg(s.seq))
+}
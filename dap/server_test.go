@@ -0,0 +1,333 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prologic/monkey-lang/internal/framing"
+)
+
+const program = `add := fn(x, y) {
+  z := x + y
+  return z
+}
+xs := [1, 2]
+print("hello")
+m := add(xs[0], 2)
+print(m)`
+
+// message is a response or event received by the client
+type message struct {
+	Seq        int             `json:"seq"`
+	Type       string          `json:"type"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Command    string          `json:"command"`
+	Message    string          `json:"message"`
+	Event      string          `json:"event"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// client is a client driving a server in a test
+type client struct {
+	t        *testing.T
+	in       io.WriteCloser
+	messages chan message
+	seq      int
+
+	// output sent by the server so far
+	output map[string]string
+}
+
+func newClient(t *testing.T) *client {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	c := &client{
+		t:        t,
+		in:       clientOut,
+		messages: make(chan message, 100),
+		output:   make(map[string]string),
+	}
+
+	go func() {
+		assert.NoError(t, NewServer(serverIn, serverOut).Serve())
+		serverOut.Close()
+	}()
+
+	go func() {
+		defer close(c.messages)
+		r := bufio.NewReader(clientIn)
+		for {
+			content, err := framing.ReadMessage(r)
+			if err != nil {
+				return
+			}
+			var msg message
+			if err := json.Unmarshal(content, &msg); err != nil {
+				return
+			}
+			c.messages <- msg
+		}
+	}()
+
+	return c
+}
+
+// send sends a request and returns its sequence number
+func (c *client) send(command string, arguments interface{}) int {
+	c.seq++
+	req := map[string]interface{}{
+		"seq":       c.seq,
+		"type":      "request",
+		"command":   command,
+		"arguments": arguments,
+	}
+	require.NoError(c.t, framing.WriteMessage(c.in, req))
+	return c.seq
+}
+
+// next returns the next message that satisfies match, recording any output
+// events received before it
+func (c *client) next(match func(msg message) bool) message {
+	for {
+		select {
+		case msg, ok := <-c.messages:
+			require.True(c.t, ok, "connection closed")
+			if msg.Type == "event" && msg.Event == "output" {
+				var body outputEventBody
+				require.NoError(c.t, json.Unmarshal(msg.Body, &body))
+				c.output[body.Category] += body.Output
+			}
+			if match(msg) {
+				return msg
+			}
+		case <-time.After(5 * time.Second):
+			require.FailNow(c.t, "timed out waiting for message")
+		}
+	}
+}
+
+// request sends a request and decodes the body of its successful response
+// into body (if not nil)
+func (c *client) request(command string, arguments, body interface{}) {
+	res := c.response(command, arguments)
+	require.True(c.t, res.Success, "%s failed: %s", command, res.Message)
+	if body != nil {
+		require.NoError(c.t, json.Unmarshal(res.Body, body))
+	}
+}
+
+// response sends a request and returns its response
+func (c *client) response(command string, arguments interface{}) message {
+	seq := c.send(command, arguments)
+	return c.next(func(msg message) bool {
+		return msg.Type == "response" && msg.RequestSeq == seq
+	})
+}
+
+// event waits for the named event and decodes its body into body (if not
+// nil)
+func (c *client) event(name string, body interface{}) {
+	msg := c.next(func(msg message) bool {
+		return msg.Type == "event" && msg.Event == name
+	})
+	if body != nil {
+		require.NoError(c.t, json.Unmarshal(msg.Body, body))
+	}
+}
+
+// stopped waits for execution to stop and returns the reason and the
+// innermost stack frame
+func (c *client) stopped() (string, stackFrame) {
+	var event stoppedEventBody
+	c.event("stopped", &event)
+
+	var trace stackTraceResponseBody
+	c.request("stackTrace", stackTraceArguments{ThreadID: threadID}, &trace)
+	require.NotEmpty(c.t, trace.StackFrames)
+
+	return event.Reason, trace.StackFrames[0]
+}
+
+// launch launches the program source and returns its path
+func (c *client) launch(source string, stopOnEntry bool) string {
+	path := filepath.Join(c.t.TempDir(), "test.monkey")
+	require.NoError(c.t, ioutil.WriteFile(path, []byte(source), 0644))
+
+	var caps capabilities
+	c.request("initialize", initializeArguments{ClientID: "test"}, &caps)
+	assert.True(c.t, caps.SupportsConfigurationDoneRequest)
+
+	c.request("launch", launchArguments{Program: path, StopOnEntry: stopOnEntry}, nil)
+	c.event("initialized", nil)
+
+	return path
+}
+
+func TestSession(t *testing.T) {
+	assert := assert.New(t)
+
+	c := newClient(t)
+	path := c.launch(program, true)
+
+	var bps setBreakpointsResponseBody
+	c.request("setBreakpoints", setBreakpointsArguments{
+		Source:      source{Path: path},
+		Breakpoints: []sourceBreakpoint{{Line: 2}, {Line: 4}},
+	}, &bps)
+	assert.Equal([]breakpoint{
+		{Verified: true, Line: 2},
+		{Verified: false, Line: 4, Message: "no code at line 4"},
+	}, bps.Breakpoints)
+
+	c.request("configurationDone", nil, nil)
+
+	reason, frame := c.stopped()
+	assert.Equal("entry", reason)
+	assert.Equal("<main>", frame.Name)
+	assert.Equal(1, frame.Line)
+
+	var threads threadsResponseBody
+	c.request("threads", nil, &threads)
+	assert.Equal([]thread{{threadID, "main"}}, threads.Threads)
+
+	c.request("continue", nil, nil)
+
+	reason, frame = c.stopped()
+	assert.Equal("breakpoint", reason)
+	assert.Equal("add", frame.Name)
+	assert.Equal(2, frame.Line)
+	assert.Equal(path, frame.Source.Path)
+
+	var trace stackTraceResponseBody
+	c.request("stackTrace", stackTraceArguments{ThreadID: threadID}, &trace)
+	assert.Equal(2, trace.TotalFrames)
+	assert.Equal("<main>", trace.StackFrames[1].Name)
+	assert.Equal(7, trace.StackFrames[1].Line)
+
+	var scopes scopesResponseBody
+	c.request("scopes", scopesArguments{FrameID: frame.ID}, &scopes)
+	require.Len(t, scopes.Scopes, 3)
+	assert.Equal("Locals", scopes.Scopes[0].Name)
+	assert.Equal("Free", scopes.Scopes[1].Name)
+	assert.Equal("Globals", scopes.Scopes[2].Name)
+
+	var locals variablesResponseBody
+	c.request("variables", variablesArguments{scopes.Scopes[0].VariablesReference}, &locals)
+	assert.Equal([]variable{
+		{Name: "x", Value: "1", Type: "int"},
+		{Name: "y", Value: "2", Type: "int"},
+		{Name: "z", Value: "<unset>"},
+	}, locals.Variables)
+
+	var globals variablesResponseBody
+	c.request("variables", variablesArguments{scopes.Scopes[2].VariablesReference}, &globals)
+	require.Len(t, globals.Variables, 2)
+	assert.Equal("add", globals.Variables[0].Name)
+	xs := globals.Variables[1]
+	assert.Equal("xs", xs.Name)
+	assert.Equal("[1, 2]", xs.Value)
+	assert.Equal(2, xs.IndexedVariables)
+
+	var elements variablesResponseBody
+	c.request("variables", variablesArguments{xs.VariablesReference}, &elements)
+	assert.Equal([]variable{
+		{Name: "[0]", Value: "1", Type: "int"},
+		{Name: "[1]", Value: "2", Type: "int"},
+	}, elements.Variables)
+
+	var result evaluateResponseBody
+	c.request("evaluate", evaluateArguments{Expression: "y"}, &result)
+	assert.Equal("2", result.Result)
+
+	res := c.response("evaluate", evaluateArguments{Expression: "nope"})
+	assert.False(res.Success)
+	assert.Equal("undefined variable nope", res.Message)
+
+	c.request("next", nil, nil)
+	reason, frame = c.stopped()
+	assert.Equal("step", reason)
+	assert.Equal(3, frame.Line)
+
+	c.request("stepOut", nil, nil)
+	reason, frame = c.stopped()
+	assert.Equal("step", reason)
+	assert.Equal("<main>", frame.Name)
+	assert.Equal(8, frame.Line)
+
+	c.request("continue", nil, nil)
+
+	var exited exitedEventBody
+	c.event("exited", &exited)
+	assert.Equal(0, exited.ExitCode)
+	c.event("terminated", nil)
+
+	res = c.response("stackTrace", stackTraceArguments{ThreadID: threadID})
+	assert.False(res.Success)
+	assert.Equal("hello\n3\n", c.output["stdout"])
+
+	c.request("disconnect", nil, nil)
+}
+
+func TestRuntimeError(t *testing.T) {
+	c := newClient(t)
+	c.launch("print(\"before\")\n1 / 0", false)
+	c.request("configurationDone", nil, nil)
+
+	var exited exitedEventBody
+	c.event("exited", &exited)
+	assert.Equal(t, 1, exited.ExitCode)
+	c.event("terminated", nil)
+
+	assert.Equal(t, "before\n", c.output["stdout"])
+	assert.Contains(t, c.output["stderr"], "ZeroDivisionError: integer division or modulo by zero")
+
+	c.request("disconnect", nil, nil)
+}
+
+func TestExit(t *testing.T) {
+	c := newClient(t)
+	c.launch("exit(3)\nprint(\"unreachable\")", false)
+	c.request("configurationDone", nil, nil)
+
+	var exited exitedEventBody
+	c.event("exited", &exited)
+	assert.Equal(t, 3, exited.ExitCode)
+	c.event("terminated", nil)
+	assert.Empty(t, c.output["stdout"])
+
+	c.request("disconnect", nil, nil)
+}
+
+func TestDisconnectWhileStopped(t *testing.T) {
+	c := newClient(t)
+	c.launch(program, true)
+	c.request("configurationDone", nil, nil)
+
+	reason, _ := c.stopped()
+	assert.Equal(t, "entry", reason)
+
+	c.request("disconnect", nil, nil)
+	assert.Empty(t, c.output["stdout"])
+}
+
+func TestPause(t *testing.T) {
+	c := newClient(t)
+	c.launch("i := 0\nwhile (true) {\n  i = i + 1\n}", false)
+	c.request("configurationDone", nil, nil)
+	c.request("pause", nil, nil)
+
+	reason, _ := c.stopped()
+	assert.Equal(t, "pause", reason)
+
+	c.request("disconnect", nil, nil)
+}
@@ -0,0 +1,249 @@
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/vm"
+)
+
+// PROMPT is the prompt of the debugger's console
+const PROMPT = "(mdb) "
+
+const consoleHelp = `Commands:
+  break, b <line>       set a breakpoint at the source line
+  clear <line>          clear the breakpoint at the source line
+  breakpoints           list breakpoints
+  continue, c           continue until the next breakpoint
+  step, s               step to the next line, into function calls
+  next, n               step to the next line, over function calls
+  out, o                step out of the current function
+  backtrace, bt         print the call stack
+  frame, f <n>          select frame n of the call stack (0 is innermost)
+  print, p <name>       print the value of a variable
+  locals                print the local variables of the selected frame
+  free                  print the free variables of the selected frame
+  globals               print the global variables
+  stack                 print the value stack
+  list, l               list the source around the current line
+  help, h               print this help
+  quit, q               quit debugging and halt the program
+
+An empty line repeats the previous command.
+`
+
+// Console is an interactive command line interface to a debugger reading
+// commands from in and writing to out when execution stops
+type Console struct {
+	in  *bufio.Scanner
+	out io.Writer
+
+	frame int    // index of the selected frame, 0 is the innermost frame
+	last  string // the previous command, repeated by an empty line
+}
+
+// NewConsole returns a new console reading commands from in and writing
+// output to out
+func NewConsole(in io.Reader, out io.Writer) *Console {
+	return &Console{in: bufio.NewScanner(in), out: out}
+}
+
+// Handle is a Handler that prints where execution stopped and prompts for
+// commands until execution is resumed. Execution is halted on end of input.
+func (c *Console) Handle(d *Debugger, reason Reason) {
+	c.frame = 0
+	c.where(d, reason)
+
+	for {
+		fmt.Fprint(c.out, PROMPT)
+		if !c.in.Scan() {
+			fmt.Fprintln(c.out)
+			d.Quit()
+			return
+		}
+
+		line := strings.TrimSpace(c.in.Text())
+		if line == "" {
+			line = c.last
+		}
+		c.last = line
+
+		if c.execute(d, line) {
+			return
+		}
+	}
+}
+
+// execute executes the command line and returns true if execution is to be
+// resumed
+func (c *Console) execute(d *Debugger, line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false
+	}
+	command, args := fields[0], fields[1:]
+
+	switch command {
+	case "continue", "c":
+		d.Continue()
+		return true
+	case "step", "s":
+		d.StepInto()
+		return true
+	case "next", "n":
+		d.StepOver()
+		return true
+	case "out", "o":
+		d.StepOut()
+		return true
+	case "quit", "q":
+		d.Quit()
+		return true
+
+	case "break", "b":
+		if line, ok := c.lineArg(args); ok {
+			if err := d.SetBreakpoint(line); err != nil {
+				fmt.Fprintf(c.out, "error: %s\n", err)
+			} else {
+				fmt.Fprintf(c.out, "Breakpoint set at %s:%d\n", d.Filename(), line)
+			}
+		}
+	case "clear":
+		if line, ok := c.lineArg(args); ok {
+			d.ClearBreakpoint(line)
+			fmt.Fprintf(c.out, "Breakpoint cleared at %s:%d\n", d.Filename(), line)
+		}
+	case "breakpoints":
+		for _, line := range d.Breakpoints() {
+			fmt.Fprintf(c.out, "%s:%d\n", d.Filename(), line)
+		}
+
+	case "backtrace", "bt":
+		frames := d.Frames()
+		for i := len(frames) - 1; i >= 0; i-- {
+			n := len(frames) - 1 - i
+			marker := " "
+			if n == c.frame {
+				marker = "*"
+			}
+			fmt.Fprintf(c.out, "%s%2d %s at %s\n", marker, n, frames[i].Name(), frames[i].SourcePos())
+		}
+	case "frame", "f":
+		if len(args) != 1 {
+			fmt.Fprintln(c.out, "error: expected a frame number")
+			break
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 0 || n >= len(d.Frames()) {
+			fmt.Fprintf(c.out, "error: invalid frame %q\n", args[0])
+			break
+		}
+		c.frame = n
+		frame := c.selected(d)
+		fmt.Fprintf(c.out, "#%d %s at %s\n", n, frame.Name(), frame.SourcePos())
+
+	case "print", "p":
+		if len(args) != 1 {
+			fmt.Fprintln(c.out, "error: expected a variable name")
+			break
+		}
+		if v, ok := d.Lookup(c.selected(d), args[0]); ok {
+			fmt.Fprintf(c.out, "%s = %s\n", v.Name, v.Value.Inspect())
+		} else {
+			fmt.Fprintf(c.out, "error: undefined variable %s\n", args[0])
+		}
+	case "locals":
+		c.printVariables(d.Locals(c.selected(d)))
+	case "free":
+		c.printVariables(d.Free(c.selected(d)))
+	case "globals":
+		c.printVariables(d.Globals())
+	case "stack":
+		stack := d.Stack()
+		for i := len(stack) - 1; i >= 0; i-- {
+			fmt.Fprintf(c.out, "%4d %s\n", i, inspect(stack[i]))
+		}
+
+	case "list", "l":
+		current := c.selected(d).SourcePos().Line
+		start, end := current-5, current+5
+		if start < 1 {
+			start = 1
+		}
+		if end > d.Lines() {
+			end = d.Lines()
+		}
+		for n := start; n <= end; n++ {
+			c.printLine(d, n, n == current)
+		}
+
+	case "help", "h":
+		io.WriteString(c.out, consoleHelp)
+
+	default:
+		fmt.Fprintf(c.out, "error: unknown command %q (try help)\n", command)
+	}
+
+	return false
+}
+
+// where prints where and why execution stopped
+func (c *Console) where(d *Debugger, reason Reason) {
+	frame := c.selected(d)
+	pos := frame.SourcePos()
+
+	fmt.Fprintf(c.out, "Stopped at %s in %s (%s)\n", pos, frame.Name(), reason)
+	c.printLine(d, pos.Line, true)
+}
+
+// selected returns the selected frame
+func (c *Console) selected(d *Debugger) *vm.Frame {
+	frames := d.Frames()
+	return frames[len(frames)-1-c.frame]
+}
+
+func (c *Console) lineArg(args []string) (int, bool) {
+	if len(args) != 1 {
+		fmt.Fprintln(c.out, "error: expected a line number")
+		return 0, false
+	}
+
+	// Accept both <line> and <file>:<line>
+	arg := args[0]
+	if i := strings.LastIndex(arg, ":"); i >= 0 {
+		arg = arg[i+1:]
+	}
+
+	line, err := strconv.Atoi(arg)
+	if err != nil || line < 1 {
+		fmt.Fprintf(c.out, "error: invalid line number %q\n", args[0])
+		return 0, false
+	}
+	return line, true
+}
+
+func (c *Console) printLine(d *Debugger, n int, current bool) {
+	marker := " "
+	if current {
+		marker = ">"
+	}
+	fmt.Fprintf(c.out, "%s%4d  %s\n", marker, n, d.Line(n))
+}
+
+func (c *Console) printVariables(vars []Variable) {
+	for _, v := range vars {
+		fmt.Fprintf(c.out, "%s = %s\n", v.Name, inspect(v.Value))
+	}
+}
+
+// inspect returns a stringified version of a value which may be unset
+func inspect(obj object.Object) string {
+	if obj == nil {
+		return "<unset>"
+	}
+	return obj.Inspect()
+}
@@ -0,0 +1,389 @@
+// Package debugger implements a source level debugger for programs executed
+// by the bytecode virtual machine. It supports breakpoints by source line,
+// stepping into, over and out of function calls and inspecting the local,
+// free and global variables of frames by name as well as the value stack.
+package debugger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prologic/monkey-lang/builtins"
+	"github.com/prologic/monkey-lang/code"
+	"github.com/prologic/monkey-lang/compiler"
+	"github.com/prologic/monkey-lang/lexer"
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/parser"
+	"github.com/prologic/monkey-lang/vm"
+)
+
+// Reason is the reason execution stopped
+type Reason string
+
+const (
+	// Entry is the reason for stopping before the first line of the program
+	Entry Reason = "entry"
+	// Breakpoint is the reason for stopping at a breakpoint
+	Breakpoint Reason = "breakpoint"
+	// Step is the reason for stopping after a step
+	Step Reason = "step"
+	// Pause is the reason for stopping after a call to Pause
+	Pause Reason = "pause"
+)
+
+// mode is the mode of execution when resuming
+type mode int
+
+const (
+	modeContinue mode = iota
+	modeStepInto
+	modeStepOver
+	modeStepOut
+)
+
+// Handler is called when execution stops and blocks until execution is to
+// be resumed, after calling one of Continue, StepInto, StepOver, StepOut or
+// Quit, while the state of the program can be inspected
+type Handler func(d *Debugger, reason Reason)
+
+// Variable is a named variable of the program and its value
+type Variable struct {
+	Name  string
+	Scope compiler.SymbolScope
+	Value object.Object
+}
+
+// Debugger runs a program on the virtual machine pausing execution at
+// breakpoints and after steps. Breakpoints can be set and cleared and
+// execution paused from another goroutine while the program is running.
+type Debugger struct {
+	// StopOnEntry stops execution before the first line of the program
+	StopOnEntry bool
+
+	// Context (if not nil) is the execution context the program is run with
+	Context *object.Context
+
+	filename string
+	source   []string
+
+	compiler *compiler.Compiler
+	vm       *vm.VM
+	handler  Handler
+
+	codeLines map[int]bool // lines instructions were compiled from
+
+	mu          sync.Mutex // guards breakpoints and pause
+	breakpoints map[int]bool
+	pause       bool
+
+	mode   mode
+	depth  int // depth of the call stack when stepping over or out
+	entry  bool
+	halted bool
+
+	// The frames seen and the line each is executing, mirroring the call
+	// stack, used to only stop once when execution reaches a new line
+	frames []*vm.Frame
+	lines  []int
+}
+
+// New compiles the program source read from filename and returns a debugger
+// ready to run it which calls handler whenever execution stops
+func New(filename, source string, handler Handler) (*Debugger, error) {
+	l := lexer.NewWithFilename(source, filename)
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("parser errors:\n\t%s", strings.Join(p.Errors(), "\n\t"))
+	}
+
+	c := compiler.New()
+	if err := c.Compile(program); err != nil {
+		return nil, err
+	}
+
+	bytecode := c.Bytecode()
+
+	d := &Debugger{
+		filename: filename,
+		source:   strings.Split(source, "\n"),
+
+		compiler: c,
+		vm:       vm.New(bytecode),
+		handler:  handler,
+
+		codeLines:   make(map[int]bool),
+		breakpoints: make(map[int]bool),
+	}
+	d.vm.Hook = d.hook
+
+	sourceMaps := []code.SourceMap{bytecode.SourceMap}
+	for _, constant := range bytecode.Constants {
+		if fn, ok := constant.(*object.CompiledFunction); ok {
+			sourceMaps = append(sourceMaps, fn.SourceMap)
+		}
+	}
+	for _, sourceMap := range sourceMaps {
+		for _, sp := range sourceMap {
+			if sp.Pos.Filename == filename {
+				d.codeLines[sp.Pos.Line] = true
+			}
+		}
+	}
+
+	return d, nil
+}
+
+// Filename returns the name of the program's source file
+func (d *Debugger) Filename() string {
+	return d.filename
+}
+
+// Line returns the source line n (starting at 1) or an empty string if out
+// of range
+func (d *Debugger) Line(n int) string {
+	if n < 1 || n > len(d.source) {
+		return ""
+	}
+	return d.source[n-1]
+}
+
+// Lines returns the number of source lines
+func (d *Debugger) Lines() int {
+	return len(d.source)
+}
+
+// Run runs the program until it completes, returns a runtime error or is
+// stopped by Quit
+func (d *Debugger) Run() error {
+	d.entry = d.StopOnEntry
+	if d.Context != nil {
+		d.vm.Context = d.Context
+	}
+
+	err := d.vm.Run()
+	if err == vm.ErrHalted {
+		return nil
+	}
+	return err
+}
+
+// SetBreakpoint sets a breakpoint at the source line
+func (d *Debugger) SetBreakpoint(line int) error {
+	if !d.codeLines[line] {
+		return fmt.Errorf("no code at line %d", line)
+	}
+	d.mu.Lock()
+	d.breakpoints[line] = true
+	d.mu.Unlock()
+	return nil
+}
+
+// ClearBreakpoint clears the breakpoint at the source line (if any)
+func (d *Debugger) ClearBreakpoint(line int) {
+	d.mu.Lock()
+	delete(d.breakpoints, line)
+	d.mu.Unlock()
+}
+
+// Breakpoints returns the lines of all breakpoints in ascending order
+func (d *Debugger) Breakpoints() []int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var lines []int
+	for line := range d.breakpoints {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// Continue resumes execution until the next breakpoint
+func (d *Debugger) Continue() {
+	d.mode = modeContinue
+}
+
+// StepInto resumes execution until the next line, stepping into calls
+func (d *Debugger) StepInto() {
+	d.mode = modeStepInto
+}
+
+// StepOver resumes execution until the next line of the current frame or a
+// frame it returns to, stepping over calls
+func (d *Debugger) StepOver() {
+	d.mode = modeStepOver
+	d.depth = len(d.vm.CallStack())
+}
+
+// StepOut resumes execution until the current frame returns
+func (d *Debugger) StepOut() {
+	d.mode = modeStepOut
+	d.depth = len(d.vm.CallStack())
+}
+
+// Quit halts execution of the program
+func (d *Debugger) Quit() {
+	d.halted = true
+}
+
+// Pause stops the running program as soon as it reaches a new line
+func (d *Debugger) Pause() {
+	d.mu.Lock()
+	d.pause = true
+	d.mu.Unlock()
+}
+
+// hook is called by the virtual machine before executing each instruction
+// and stops execution (by calling the handler) once a new line is reached
+// that has a breakpoint or ends a step
+func (d *Debugger) hook(machine *vm.VM) bool {
+	frames := machine.CallStack()
+	depth := len(frames)
+	frame := frames[depth-1]
+
+	if len(d.frames) > depth {
+		d.frames = d.frames[:depth]
+		d.lines = d.lines[:depth]
+	}
+	for len(d.frames) < depth {
+		d.frames = append(d.frames, nil)
+		d.lines = append(d.lines, 0)
+	}
+	if d.frames[depth-1] != frame {
+		d.frames[depth-1] = frame
+		d.lines[depth-1] = 0
+	}
+
+	pos := frame.SourcePos()
+	if !pos.IsValid() || pos.Filename != d.filename || d.lines[depth-1] == pos.Line {
+		return true
+	}
+	d.lines[depth-1] = pos.Line
+
+	d.mu.Lock()
+	breakpoint, pause := d.breakpoints[pos.Line], d.pause
+	d.pause = false
+	d.mu.Unlock()
+
+	var reason Reason
+	switch {
+	case d.entry:
+		reason = Entry
+	case breakpoint:
+		reason = Breakpoint
+	case pause:
+		reason = Pause
+	case d.mode == modeStepInto,
+		d.mode == modeStepOver && depth <= d.depth,
+		d.mode == modeStepOut && depth < d.depth:
+		reason = Step
+	default:
+		return true
+	}
+
+	d.entry = false
+	d.mode = modeContinue
+	d.handler(d, reason)
+
+	return !d.halted
+}
+
+// Frames returns the frames of the call stack, outer most first
+func (d *Debugger) Frames() []*vm.Frame {
+	return d.vm.CallStack()
+}
+
+// Stack returns the values on the value stack, bottom most first
+func (d *Debugger) Stack() []object.Object {
+	return d.vm.Stack()
+}
+
+// Locals returns the local variables, including the parameters, of the
+// frame f in the order they were defined
+func (d *Debugger) Locals(f *vm.Frame) []Variable {
+	var vars []Variable
+	for _, symbol := range d.symbols(f, compiler.LocalScope) {
+		vars = append(vars, Variable{symbol.Name, symbol.Scope, d.value(f, symbol)})
+	}
+	return vars
+}
+
+// Free returns the free variables of the closure executing in frame f
+func (d *Debugger) Free(f *vm.Frame) []Variable {
+	var vars []Variable
+	for _, symbol := range d.symbols(f, compiler.FreeScope) {
+		vars = append(vars, Variable{symbol.Name, symbol.Scope, d.value(f, symbol)})
+	}
+	return vars
+}
+
+// Globals returns the global variables that have been bound
+func (d *Debugger) Globals() []Variable {
+	var vars []Variable
+	for _, symbol := range d.symbols(nil, compiler.GlobalScope) {
+		if value := d.vm.Global(symbol.Index); value != nil {
+			vars = append(vars, Variable{symbol.Name, symbol.Scope, value})
+		}
+	}
+	return vars
+}
+
+// Lookup returns the variable name as seen by frame f, that is a local or
+// free variable of f, a global or a builtin
+func (d *Debugger) Lookup(f *vm.Frame, name string) (Variable, bool) {
+	symbol, ok := d.compiler.Symbols(f.Function()).Store[name]
+	if !ok {
+		symbol, ok = d.compiler.Symbols(nil).Store[name]
+	}
+	if !ok {
+		return Variable{}, false
+	}
+
+	value := d.value(f, symbol)
+	if value == nil {
+		return Variable{}, false
+	}
+	return Variable{symbol.Name, symbol.Scope, value}, true
+}
+
+// symbols returns the symbols of the given scope of the symbol table of the
+// function executing in frame f (or the globals if f is nil) ordered by
+// index
+func (d *Debugger) symbols(f *vm.Frame, scope compiler.SymbolScope) []compiler.Symbol {
+	var fn *object.CompiledFunction
+	if f != nil {
+		fn = f.Function()
+	}
+
+	var symbols []compiler.Symbol
+	for _, symbol := range d.compiler.Symbols(fn).Store {
+		if symbol.Scope == scope {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Slice(symbols, func(i, j int) bool {
+		return symbols[i].Index < symbols[j].Index
+	})
+	return symbols
+}
+
+// value returns the value of the symbol in frame f or nil if unset
+func (d *Debugger) value(f *vm.Frame, symbol compiler.Symbol) object.Object {
+	switch symbol.Scope {
+	case compiler.LocalScope:
+		return d.vm.Local(f, symbol.Index)
+	case compiler.FreeScope:
+		if free := f.Free(); symbol.Index < len(free) {
+			return free[symbol.Index]
+		}
+	case compiler.GlobalScope:
+		return d.vm.Global(symbol.Index)
+	case compiler.BuiltinScope:
+		return builtins.BuiltinsIndex[symbol.Index]
+	}
+	return nil
+}
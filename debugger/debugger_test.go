@@ -0,0 +1,196 @@
+package debugger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const program = `add := fn(x, y) {
+  z := x + y
+  return z
+}
+n := 1
+m := add(n, 2)
+k := m * 2`
+
+type stop struct {
+	reason Reason
+	line   int
+}
+
+// script returns a handler recording each stop and resuming execution by
+// calling the next of the given commands (quitting when none are left)
+func script(stops *[]stop, commands ...func(d *Debugger)) Handler {
+	return func(d *Debugger, reason Reason) {
+		frames := d.Frames()
+		pos := frames[len(frames)-1].SourcePos()
+		*stops = append(*stops, stop{reason, pos.Line})
+
+		if len(commands) == 0 {
+			d.Quit()
+			return
+		}
+		commands[0](d)
+		commands = commands[1:]
+	}
+}
+
+func TestStepping(t *testing.T) {
+	tests := []struct {
+		commands []func(d *Debugger)
+		expected []stop
+	}{
+		{
+			[]func(d *Debugger){
+				(*Debugger).StepOver, (*Debugger).StepOver, (*Debugger).StepOver,
+				(*Debugger).StepOver,
+			},
+			[]stop{{Entry, 1}, {Step, 5}, {Step, 6}, {Step, 7}},
+		},
+		{
+			[]func(d *Debugger){
+				(*Debugger).StepOver, (*Debugger).StepOver, (*Debugger).StepInto,
+				(*Debugger).StepInto, (*Debugger).StepInto, (*Debugger).StepInto,
+				(*Debugger).Continue,
+			},
+			[]stop{{Entry, 1}, {Step, 5}, {Step, 6}, {Step, 1}, {Step, 2}, {Step, 3}, {Step, 7}},
+		},
+		{
+			[]func(d *Debugger){
+				(*Debugger).StepOver, (*Debugger).StepOver, (*Debugger).StepInto,
+				(*Debugger).StepInto, (*Debugger).StepOut, (*Debugger).Continue,
+			},
+			[]stop{{Entry, 1}, {Step, 5}, {Step, 6}, {Step, 1}, {Step, 2}, {Step, 7}},
+		},
+	}
+
+	for _, tt := range tests {
+		var stops []stop
+
+		d, err := New("test.monkey", program, script(&stops, tt.commands...))
+		require.NoError(t, err)
+		d.StopOnEntry = true
+
+		assert.NoError(t, d.Run())
+		assert.Equal(t, tt.expected, stops)
+	}
+}
+
+func TestBreakpoints(t *testing.T) {
+	assert := assert.New(t)
+
+	var (
+		stops  []stop
+		values []string
+	)
+
+	inspect := func(d *Debugger) {
+		frames := d.Frames()
+		frame := frames[len(frames)-1]
+		for _, name := range []string{"x", "y", "n", "m"} {
+			if v, ok := d.Lookup(frame, name); ok {
+				values = append(values, name+"="+v.Value.Inspect())
+			}
+		}
+		d.Continue()
+	}
+
+	d, err := New("test.monkey", program, script(&stops, inspect, inspect))
+	require.NoError(t, err)
+
+	assert.EqualError(d.SetBreakpoint(4), "no code at line 4")
+	assert.NoError(d.SetBreakpoint(3))
+	assert.NoError(d.SetBreakpoint(7))
+	assert.NoError(d.SetBreakpoint(5))
+	d.ClearBreakpoint(5)
+	assert.Equal([]int{3, 7}, d.Breakpoints())
+
+	assert.NoError(d.Run())
+	assert.Equal([]stop{{Breakpoint, 3}, {Breakpoint, 7}}, stops)
+	assert.Equal([]string{"x=1", "y=2", "n=1", "n=1", "m=3"}, values)
+}
+
+func TestVariables(t *testing.T) {
+	assert := assert.New(t)
+
+	input := `a := 1
+f := fn(x) {
+  g := fn(y) {
+    return x + y
+  }
+  z := g(2)
+  return z
+}
+f(a)`
+
+	called := false
+	handler := func(d *Debugger, reason Reason) {
+		called = true
+
+		frames := d.Frames()
+		assert.Len(frames, 3)
+
+		g, f := frames[2], frames[1]
+		assert.Equal([]Variable{{"y", "LOCAL", d.Locals(g)[0].Value}}, d.Locals(g))
+		assert.Equal("2", d.Locals(g)[0].Value.Inspect())
+
+		// g refers to itself and x of f as free variables
+		free := d.Free(g)
+		assert.Len(free, 2)
+		assert.Equal("g", free[0].Name)
+		assert.Equal("x", free[1].Name)
+		assert.Equal("1", free[1].Value.Inspect())
+		assert.Equal("x", d.Locals(f)[0].Name)
+
+		var names []string
+		for _, v := range d.Globals() {
+			names = append(names, v.Name)
+		}
+		assert.ElementsMatch([]string{"a", "f"}, names)
+
+		v, ok := d.Lookup(g, "len")
+		assert.True(ok)
+		assert.Equal("BUILTIN", string(v.Scope))
+
+		_, ok = d.Lookup(g, "nope")
+		assert.False(ok)
+
+		d.Continue()
+	}
+
+	d, err := New("test.monkey", input, handler)
+	require.NoError(t, err)
+	require.NoError(t, d.SetBreakpoint(4))
+
+	assert.NoError(d.Run())
+	assert.True(called)
+}
+
+func TestConsole(t *testing.T) {
+	assert := assert.New(t)
+
+	in := strings.NewReader("b 3\nc\nbt\np x\nlocals\nframe 1\np n\nn\n\nq\n")
+	out := &bytes.Buffer{}
+
+	console := NewConsole(in, out)
+	d, err := New("test.monkey", program, console.Handle)
+	require.NoError(t, err)
+	d.StopOnEntry = true
+
+	assert.NoError(d.Run())
+
+	output := out.String()
+	assert.Contains(output, "Stopped at test.monkey:1:8 in <main> (entry)")
+	assert.Contains(output, "Breakpoint set at test.monkey:3")
+	assert.Contains(output, "Stopped at test.monkey:3:10 in add (breakpoint)")
+	assert.Contains(output, "* 0 add at test.monkey:3:10\n  1 <main> at test.monkey:6:9")
+	assert.Contains(output, "x = 1\ny = 2\nz = 3\n")
+	assert.Contains(output, "#1 <main> at test.monkey:6:9")
+	assert.Contains(output, "n = 1")
+	assert.Contains(output, "Stopped at test.monkey:7:6 in <main> (step)")
+	assert.NotContains(output, "error:")
+}
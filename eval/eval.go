@@ -7,6 +7,7 @@ package eval
 import (
 	"fmt"
 	"io/ioutil"
+	"math"
 	"strings"
 
 	"github.com/prologic/monkey-lang/ast"
@@ -26,6 +27,13 @@ var (
 
 	// NULL is a cached Null object
 	NULL = &object.Null{}
+
+	// BREAK is a cached Break object used to signal a `break` statement
+	BREAK = &object.Break{}
+
+	// CONTINUE is a cached Continue object used to signal a `continue`
+	// statement
+	CONTINUE = &object.Continue{}
 )
 
 func fromNativeBoolean(input bool) *object.Boolean {
@@ -41,8 +49,18 @@ func newError(format string, a ...interface{}) *object.Error {
 
 // EvalModule evaluates the named module and returns a *object.Module object
 func EvalModule(name string) object.Object {
+	return evalModule(name, object.NewContext())
+}
+
+// evalModule evaluates the named module with the execution context ctx.
+// Modules implemented in Go are used if no Monkey module of the same name
+// is found.
+func evalModule(name string, ctx *object.Context) object.Object {
 	filename := utils.FindModule(name)
 	if filename == "" {
+		if attrs, ok := builtins.LookupModule(name); ok {
+			return attrs
+		}
 		return newError("ImportError: no module named '%s'", name)
 	}
 
@@ -51,7 +69,7 @@ func EvalModule(name string) object.Object {
 		return newError("IOError: error reading module '%s': %s", name, err)
 	}
 
-	l := lexer.New(string(b))
+	l := lexer.NewWithFilename(string(b), filename)
 	p := parser.New(l)
 
 	module := p.ParseProgram()
@@ -59,14 +77,39 @@ func EvalModule(name string) object.Object {
 		return newError("ParseError: %s", p.Errors())
 	}
 
-	env := object.NewEnvironment()
-	Eval(module, env)
+	// The module is evaluated by the task importing it which holds the lock
+	// of the program's tasks (if any)
+	env := object.NewEnvironmentWithContext(ctx)
+	evalProgram(module, env)
 
 	return env.ExportedHash()
 }
 
-// Eval evaluates the node and returns an object
+// Eval evaluates the node and returns an object. Errors are annotated with
+// the source position of the inner most node that caused them. Tasks spawned
+// by a program keep running after it is evaluated, while no program is.
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	// The program holds the lock of its tasks while it runs
+	if _, ok := node.(*ast.Program); ok {
+		ctx := env.Context()
+		ctx.Acquire()
+		defer ctx.Release()
+
+		ctx.Call = func(fn object.Object, args ...object.Object) object.Object {
+			return applyFunction(fn, args, ctx)
+		}
+	}
+
+	result := eval(node, env)
+
+	if err, ok := result.(*object.Error); ok && !err.Caught && !err.Position.IsValid() {
+		err.Position = node.Pos()
+	}
+
+	return result
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 
 	case *ast.Program:
@@ -79,6 +122,11 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.BlockStatement:
 		return evalBlockStatement(node, env)
 
+	case *ast.BreakStatement:
+		return BREAK
+	case *ast.ContinueStatement:
+		return CONTINUE
+
 	case *ast.ReturnStatement:
 		val := Eval(node.ReturnValue, env)
 		if isError(val) {
@@ -89,6 +137,8 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	// Expressions
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 	case *ast.Boolean:
@@ -120,6 +170,23 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalIfExpression(node, env)
 	case *ast.WhileExpression:
 		return evalWhileExpression(node, env)
+
+	case *ast.ForExpression:
+		return evalForExpression(node, env)
+
+	case *ast.TryExpression:
+		return evalTryExpression(node, env)
+
+	case *ast.ThrowExpression:
+		value := Eval(node.Value, env)
+		if isError(value) {
+			return value
+		}
+		return evalThrowExpression(value)
+	case *ast.SpawnExpression:
+		return evalSpawnExpression(node, env)
+	case *ast.SelectExpression:
+		return evalSelectExpression(node, env)
 	case *ast.ImportExpression:
 		return evalImportExpression(node, env)
 
@@ -141,7 +208,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
-		return applyFunction(function, args)
+		return applyFunction(function, args, env.Context())
 
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
@@ -168,9 +235,11 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return newError("expected identifier on left got=%T", node.Left)
 
 	case *ast.AssignmentExpression:
-		left := Eval(node.Left, env)
-		if isError(left) {
-			return left
+		// Only variables must be defined before they are assigned to
+		if _, ok := node.Left.(*ast.Identifier); ok {
+			if left := Eval(node.Left, env); isError(left) {
+				return left
+			}
 		}
 
 		value := Eval(node.Value, env)
@@ -186,32 +255,32 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 				return obj
 			}
 
-			if array, ok := obj.(*object.Array); ok {
-				index := Eval(ie.Index, env)
-				if isError(index) {
-					return index
-				}
-				if idx, ok := index.(*object.Integer); ok {
-					array.Elements[idx.Value] = value
-				} else {
-					return newError("cannot index array with %#v", index)
+			index := Eval(ie.Index, env)
+			if isError(index) {
+				return index
+			}
+
+			array, isArray := obj.(*object.Array)
+			idx, isInteger := index.(*object.Integer)
+			if isArray && isInteger {
+				if idx.Value < 0 || idx.Value >= int64(len(array.Elements)) {
+					return newError("IndexError: index out of bounds: %d", idx.Value)
 				}
+				array.Elements[idx.Value] = value
 			} else if hash, ok := obj.(*object.Hash); ok {
-				key := Eval(ie.Index, env)
-				if isError(key) {
-					return key
-				}
-				if hashKey, ok := key.(object.Hashable); ok {
-					hashed := hashKey.HashKey()
-					hash.Pairs[hashed] = object.HashPair{Key: key, Value: value}
-				} else {
-					return newError("cannot index hash with %T", key)
+				hashKey, ok := object.AsHashable(index)
+				if !ok {
+					return newError("TypeError: unusable as hash key: %s", index.Type())
 				}
+				hash.Set(hashKey, value)
 			} else {
-				return newError("object type %T does not support item assignment", obj)
+				return newError(
+					"TypeError: set item operation not supported: left=%s index=%s",
+					obj.Type(), index.Type(),
+				)
 			}
 		} else {
-			return newError("expected identifier or index expression got=%T", left)
+			return newError("expected identifier or index expression got=%T", node.Left)
 		}
 
 		return NULL
@@ -244,7 +313,19 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 		case *object.Return:
 			return result.Value
 		case *object.Error:
-			return result
+			if !result.Caught {
+				return result
+			}
+		case *object.Break:
+			return &object.Error{
+				Message:  "'break' outside loop",
+				Position: statement.Pos(),
+			}
+		case *object.Continue:
+			return &object.Error{
+				Message:  "'continue' outside loop",
+				Position: statement.Pos(),
+			}
 		}
 	}
 
@@ -273,7 +354,8 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 
 		if result != nil {
 			rt := result.Type()
-			if rt == object.RETURN || rt == object.ERROR {
+			if rt == object.RETURN || isError(result) ||
+				rt == object.BREAK || rt == object.CONTINUE {
 				return result
 			}
 		}
@@ -285,28 +367,26 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 func evalPrefixExpression(operator string, right object.Object) object.Object {
 	switch operator {
 	case "!":
-		if right.Type() == object.BOOLEAN {
-			return evalBooleanPrefixOperatorExpression(operator, right)
+		return evalNotOperatorExpression(right)
+	case "-":
+		if right.Type() == object.FLOAT {
+			return evalFloatPrefixOperatorExpression(operator, right)
 		}
 		return evalIntegerPrefixOperatorExpression(operator, right)
-	case "~", "-":
+	case "~":
 		return evalIntegerPrefixOperatorExpression(operator, right)
 	default:
-		return newError("unknown operator: %s%s", operator, right.Type())
+		return newError("TypeError: unknown operator: %s%s", operator, right.Type())
 	}
 }
 
-func evalBooleanPrefixOperatorExpression(operator string, right object.Object) object.Object {
-	if right.Type() != object.BOOLEAN {
-		return newError("unknown operator: %s%s", operator, right.Type())
-	}
-
-	switch right {
-	case TRUE:
-		return FALSE
-	case FALSE:
-		return TRUE
-	case NULL:
+// evalNotOperatorExpression negates booleans, `!null` is true and the
+// negation of any other value is false
+func evalNotOperatorExpression(right object.Object) object.Object {
+	switch right := right.(type) {
+	case *object.Boolean:
+		return fromNativeBoolean(!right.Value)
+	case *object.Null:
 		return TRUE
 	default:
 		return FALSE
@@ -315,19 +395,27 @@ func evalBooleanPrefixOperatorExpression(operator string, right object.Object) o
 
 func evalIntegerPrefixOperatorExpression(operator string, right object.Object) object.Object {
 	if right.Type() != object.INTEGER {
-		return newError("unknown operator: %s%s", operator, right.Type())
+		return newError("TypeError: unknown operator: %s%s", operator, right.Type())
 	}
 
 	value := right.(*object.Integer).Value
 	switch operator {
-	case "!":
-		return FALSE
 	case "~":
 		return &object.Integer{Value: ^value}
 	case "-":
 		return &object.Integer{Value: -value}
 	default:
-		return newError("unknown operator: %s", operator)
+		return newError("TypeError: unknown operator: %s", operator)
+	}
+}
+
+func evalFloatPrefixOperatorExpression(operator string, right object.Object) object.Object {
+	value := right.(*object.Float).Value
+	switch operator {
+	case "-":
+		return &object.Float{Value: -value}
+	default:
+		return newError("TypeError: unknown operator: %s%s", operator, right.Type())
 	}
 }
 
@@ -339,16 +427,16 @@ func evalInfixExpression(
 
 	// {"a": 1} + {"b": 2}
 	case operator == "+" && left.Type() == object.HASH && right.Type() == object.HASH:
-		leftVal := left.(*object.Hash).Pairs
-		rightVal := right.(*object.Hash).Pairs
-		pairs := make(map[object.HashKey]object.HashPair)
-		for k, v := range leftVal {
-			pairs[k] = v
+		leftVal := left.(*object.Hash)
+		rightVal := right.(*object.Hash)
+		hash := object.NewHash(leftVal.Len() + rightVal.Len())
+		for _, pair := range leftVal.Pairs() {
+			hash.Set(pair.Key.(object.Hashable), pair.Value)
 		}
-		for k, v := range rightVal {
-			pairs[k] = v
+		for _, pair := range rightVal.Pairs() {
+			hash.Set(pair.Key.(object.Hashable), pair.Value)
 		}
-		return &object.Hash{Pairs: pairs}
+		return hash
 
 	// [1] + [2]
 	case operator == "+" && left.Type() == object.ARRAY && right.Type() == object.ARRAY:
@@ -407,11 +495,20 @@ func evalInfixExpression(
 		return evalBooleanInfixExpression(operator, left, right)
 	case left.Type() == right.Type() && left.Type() == object.INTEGER:
 		return evalIntegerInfixExpression(operator, left, right)
+	case left.Type() == right.Type() && left.Type() == object.FLOAT:
+		return evalFloatInfixExpression(operator, left, right)
+	// 1 + 2.5 and 2.5 + 1 (int is promoted to float)
+	case left.Type() == object.INTEGER && right.Type() == object.FLOAT:
+		left = &object.Float{Value: float64(left.(*object.Integer).Value)}
+		return evalFloatInfixExpression(operator, left, right)
+	case left.Type() == object.FLOAT && right.Type() == object.INTEGER:
+		right = &object.Float{Value: float64(right.(*object.Integer).Value)}
+		return evalFloatInfixExpression(operator, left, right)
 	case left.Type() == right.Type() && left.Type() == object.STRING:
 		return evalStringInfixExpression(operator, left, right)
 
 	default:
-		return newError("unknown operator: %s %s %s",
+		return newError("TypeError: unknown operator: %s %s %s",
 			left.Type(), operator, right.Type())
 	}
 }
@@ -429,7 +526,31 @@ func evalBooleanInfixExpression(
 	case "||":
 		return fromNativeBoolean(leftVal || rightVal)
 	default:
-		return newError("unknown operator: %s %s %s",
+		return newError("TypeError: unknown operator: %s %s %s",
+			left.Type(), operator, right.Type())
+	}
+}
+
+func evalFloatInfixExpression(
+	operator string,
+	left, right object.Object,
+) object.Object {
+	leftVal := left.(*object.Float).Value
+	rightVal := right.(*object.Float).Value
+
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		return &object.Float{Value: leftVal / rightVal}
+	case "%":
+		return &object.Float{Value: math.Mod(leftVal, rightVal)}
+	default:
+		return newError("TypeError: unknown operator: %s %s %s",
 			left.Type(), operator, right.Type())
 	}
 }
@@ -441,6 +562,10 @@ func evalIntegerInfixExpression(
 	leftVal := left.(*object.Integer).Value
 	rightVal := right.(*object.Integer).Value
 
+	if (operator == "/" || operator == "%") && rightVal == 0 {
+		return newError("ZeroDivisionError: integer division or modulo by zero")
+	}
+
 	switch operator {
 	case "+":
 		return &object.Integer{Value: leftVal + rightVal}
@@ -475,7 +600,8 @@ func evalIntegerInfixExpression(
 	case "!=":
 		return fromNativeBoolean(leftVal != rightVal)
 	default:
-		return NULL
+		return newError("TypeError: unknown operator: %s %s %s",
+			left.Type(), operator, right.Type())
 	}
 }
 
@@ -490,7 +616,7 @@ func evalStringInfixExpression(
 	case "+":
 		return &object.String{Value: leftVal + rightVal}
 	default:
-		return newError("unknown operator: %s %s %s",
+		return newError("TypeError: unknown operator: %s %s %s",
 			left.Type(), operator, right.Type())
 	}
 }
@@ -514,16 +640,32 @@ func evalWhileExpression(we *ast.WhileExpression, env *object.Environment) objec
 	var result object.Object
 
 	for {
+		// Let other tasks run (if any) in loops
+		env.Context().Yield()
+
 		condition := Eval(we.Condition, env)
 		if isError(condition) {
 			return condition
 		}
 
-		if isTruthy(condition) {
-			result = Eval(we.Consequence, env)
-		} else {
+		if !isTruthy(condition) {
 			break
 		}
+
+		result = Eval(we.Consequence, env)
+		if isError(result) {
+			return result
+		}
+		if result != nil {
+			switch result.Type() {
+			case object.RETURN:
+				return result
+			case object.BREAK:
+				return NULL
+			case object.CONTINUE:
+				result = NULL
+			}
+		}
 	}
 
 	if result != nil {
@@ -532,6 +674,182 @@ func evalWhileExpression(we *ast.WhileExpression, env *object.Environment) objec
 	return NULL
 }
 
+func evalForExpression(fe *ast.ForExpression, env *object.Environment) object.Object {
+	obj := Eval(fe.Iterable, env)
+	if isError(obj) {
+		return obj
+	}
+
+	iterable, ok := obj.(object.Iterable)
+	if !ok {
+		return newError("TypeError: object of type '%s' is not iterable", obj.Type())
+	}
+
+	iterator := iterable.Iter()
+	for {
+		env.Context().Yield()
+
+		key, value, ok := iterator.Next()
+		if !ok {
+			break
+		}
+
+		env.Set(fe.Value.Value, value)
+		if fe.Key != nil {
+			env.Set(fe.Key.Value, key)
+		}
+
+		result := Eval(fe.Body, env)
+		if isError(result) {
+			return result
+		}
+		if result != nil {
+			switch result.Type() {
+			case object.RETURN:
+				return result
+			case object.BREAK:
+				return NULL
+			}
+		}
+	}
+
+	return NULL
+}
+
+func evalTryExpression(te *ast.TryExpression, env *object.Environment) object.Object {
+	result := Eval(te.Block, env)
+
+	if err, ok := result.(*object.Error); ok && isError(err) && te.Catch != nil {
+		caught := err.Clone().(*object.Error)
+		caught.Caught = true
+
+		if te.CatchParam != nil {
+			env.Set(te.CatchParam.Value, caught)
+		}
+		result = Eval(te.Catch, env)
+	}
+
+	if te.Finally != nil {
+		// An error, return, break or continue in the finally block takes
+		// precedence over the result of the try or catch block
+		finally := Eval(te.Finally, env)
+		if isError(finally) {
+			return finally
+		}
+		if finally != nil {
+			switch finally.Type() {
+			case object.RETURN, object.BREAK, object.CONTINUE:
+				return finally
+			}
+		}
+	}
+
+	if result == nil {
+		return NULL
+	}
+
+	return result
+}
+
+func evalThrowExpression(value object.Object) object.Object {
+	switch value := value.(type) {
+	case *object.Error:
+		return value.Raise()
+	case *object.String:
+		return newError("%s", value.Value)
+	default:
+		return newError("TypeError: exceptions must be str or error, not %s", value.Type())
+	}
+}
+
+// evalSpawnExpression runs the function of the spawn expression as a new
+// task, errors not caught by the task are printed to the standard error of
+// the execution context as it has no caller to raise them
+func evalSpawnExpression(se *ast.SpawnExpression, env *object.Environment) object.Object {
+	// The function and arguments of a call are evaluated by the task
+	// spawning it, other values are functions called without arguments
+	fnNode, argNodes := se.Value, []ast.Expression{}
+	if call, ok := se.Value.(*ast.CallExpression); ok {
+		fnNode, argNodes = call.Function, call.Arguments
+	}
+
+	fn := Eval(fnNode, env)
+	if isError(fn) {
+		return fn
+	}
+
+	args := evalExpressions(argNodes, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	switch fn := fn.(type) {
+	case *object.Function:
+		if len(args) != len(fn.Parameters) {
+			return newError("TypeError: wrong number of arguments: want=%d, got=%d",
+				len(fn.Parameters), len(args))
+		}
+	case *object.Builtin:
+	default:
+		return newError("TypeError: spawn expected a function, got `%s`", fn.Type())
+	}
+
+	ctx := env.Context()
+	ctx.Spawn(func() {
+		if err, ok := applyFunction(fn, args, ctx).(*object.Error); ok && isError(err) {
+			fmt.Fprintln(ctx.Stderr, err.String())
+		}
+	})
+
+	return NULL
+}
+
+// evalSelectExpression waits for one of the cases of the select expression
+// to proceed, or evaluates the default if none can, and evaluates its block
+func evalSelectExpression(se *ast.SelectExpression, env *object.Environment) object.Object {
+	cases := make([]object.SelectCase, len(se.Cases))
+	for i, sc := range se.Cases {
+		obj := Eval(sc.Channel, env)
+		if isError(obj) {
+			return obj
+		}
+		ch, ok := obj.(*object.Channel)
+		if !ok {
+			return newError("TypeError: select case #%d expected a `channel` got `%s`",
+				i+1, obj.Type())
+		}
+		cases[i].Channel = ch
+
+		if sc.Value != nil {
+			value := Eval(sc.Value, env)
+			if isError(value) {
+				return value
+			}
+			cases[i].Value = value
+		}
+	}
+
+	chosen, obj, err := object.Select(env.Context(), cases, se.Default == nil)
+	if err != nil {
+		return newError("ChannelError: %s", err)
+	}
+
+	var result object.Object
+	if chosen == -1 {
+		result = Eval(se.Default, env)
+	} else {
+		if name := se.Cases[chosen].Name; name != nil {
+			env.Set(name.Value, obj)
+		}
+		result = Eval(se.Cases[chosen].Body, env)
+	}
+
+	if result == nil {
+		return NULL
+	}
+	return result
+}
+
 func evalImportExpression(ie *ast.ImportExpression, env *object.Environment) object.Object {
 	name := Eval(ie.Name, env)
 	if isError(name) {
@@ -539,7 +857,7 @@ func evalImportExpression(ie *ast.ImportExpression, env *object.Environment) obj
 	}
 
 	if s, ok := name.(*object.String); ok {
-		attrs := EvalModule(s.Value)
+		attrs := evalModule(s.Value, env.Context())
 		if isError(attrs) {
 			return attrs
 		}
@@ -561,9 +879,11 @@ func isTruthy(obj object.Object) bool {
 	}
 }
 
+// isError returns true if obj is an error being raised, that is an error
+// that has not been caught by a `catch` block
 func isError(obj object.Object) bool {
-	if obj != nil {
-		return obj.Type() == object.ERROR
+	if err, ok := obj.(*object.Error); ok {
+		return !err.Caught
 	}
 	return false
 }
@@ -580,7 +900,7 @@ func evalIdentifier(
 		return builtin
 	}
 
-	return newError("identifier not found: " + node.Value)
+	return newError("NameError: identifier not found: %s", node.Value)
 }
 
 func evalExpressions(
@@ -600,21 +920,26 @@ func evalExpressions(
 	return result
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+func applyFunction(fn object.Object, args []object.Object, ctx *object.Context) object.Object {
 	switch fn := fn.(type) {
 
 	case *object.Function:
+		if len(args) != len(fn.Parameters) {
+			return newError("TypeError: wrong number of arguments: want=%d, got=%d",
+				len(fn.Parameters), len(args))
+		}
+		ctx.Yield()
 		env := extendFunctionEnv(fn, args)
 		return unwrapReturnValue(Eval(fn.Body, env))
 
 	case *object.Builtin:
-		if result := fn.Fn(args...); result != nil {
+		if result := fn.Call(ctx, args...); result != nil {
 			return result
 		}
 		return NULL
 
 	default:
-		return newError("not a function: %s", fn.Type())
+		return newError("TypeError: not a function: %s", fn.Type())
 	}
 }
 
@@ -632,8 +957,13 @@ func extendFunctionEnv(
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
-	if returnValue, ok := obj.(*object.Return); ok {
-		return returnValue.Value
+	switch obj := obj.(type) {
+	case *object.Return:
+		return obj.Value
+	case *object.Break:
+		return newError("'break' outside loop")
+	case *object.Continue:
+		return newError("'continue' outside loop")
 	}
 
 	return obj
@@ -648,7 +978,10 @@ func evalIndexAssignmentExpression(left, index, value object.Object) object.Obje
 	case left.Type() == object.HASH:
 		return evalHashIndexExpression(left, index)
 	default:
-		return newError("index operator not supported: %s", left.Type())
+		return newError(
+			"TypeError: index operator not supported: left=%s index=%s",
+			left.Type(), index.Type(),
+		)
 	}
 }
 
@@ -662,20 +995,34 @@ func evalIndexExpression(left, index object.Object) object.Object {
 		return evalHashIndexExpression(left, index)
 	case left.Type() == object.MODULE:
 		return evalModuleIndexExpression(left, index)
+	case left.Type() == object.ERROR && index.Type() == object.STRING:
+		return evalErrorIndexExpression(left, index)
 	default:
-		return newError("index operator not supported: %s", left.Type())
+		return newError(
+			"TypeError: index operator not supported: left=%s index=%s",
+			left.Type(), index.Type(),
+		)
 	}
 }
 
+func evalErrorIndexExpression(err, index object.Object) object.Object {
+	attr := err.(*object.Error).Get(index.(*object.String).Value)
+	if attr == nil {
+		return NULL
+	}
+
+	return attr
+}
+
 func evalHashIndexExpression(hash, index object.Object) object.Object {
 	hashObject := hash.(*object.Hash)
 
-	key, ok := index.(object.Hashable)
+	key, ok := object.AsHashable(index)
 	if !ok {
-		return newError("unusable as hash key: %s", index.Type())
+		return newError("TypeError: unusable as hash key: %s", index.Type())
 	}
 
-	pair, ok := hashObject.Pairs[key.HashKey()]
+	pair, ok := hashObject.Get(key)
 	if !ok {
 		return NULL
 	}
@@ -716,17 +1063,18 @@ func evalHashLiteral(
 	node *ast.HashLiteral,
 	env *object.Environment,
 ) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := object.NewHash(len(node.Pairs))
 
-	for keyNode, valueNode := range node.Pairs {
+	for _, keyNode := range node.Keys {
+		valueNode := node.Pairs[keyNode]
 		key := Eval(keyNode, env)
 		if isError(key) {
 			return key
 		}
 
-		hashKey, ok := key.(object.Hashable)
+		hashKey, ok := object.AsHashable(key)
 		if !ok {
-			return newError("unusable as hash key: %s", key.Type())
+			return newError("TypeError: unusable as hash key: %s", key.Type())
 		}
 
 		value := Eval(valueNode, env)
@@ -734,9 +1082,8 @@ func evalHashLiteral(
 			return value
 		}
 
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		hash.Set(hashKey, value)
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return hash
 }
@@ -1,9 +1,11 @@
 package eval
 
 import (
+	"bytes"
 	"errors"
 	"io/ioutil"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -32,6 +34,12 @@ func assertEvaluated(t *testing.T, expected interface{}, actual object.Object) {
 		} else {
 			assert.Equal(expected, actual)
 		}
+	case float64:
+		if f, ok := actual.(*object.Float); ok {
+			assert.Equal(expected.(float64), f.Value)
+		} else {
+			assert.Equal(expected, actual)
+		}
 	case error:
 		if e, ok := actual.(*object.Integer); ok {
 			assert.Equal(expected.(error).Error(), e.Value)
@@ -128,6 +136,49 @@ func testStringObject(t *testing.T, obj object.Object, expected string) bool {
 	return true
 }
 
+func TestFloatExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"1.5", 1.5},
+		{"2.5e2", 250.0},
+		{"-1.5", -1.5},
+		{"1.5 + 2.25", 3.75},
+		{"1 + 2.5", 3.5},
+		{"2.5 - 1", 1.5},
+		{"2 * 1.5", 3.0},
+		{"7 / 2.0", 3.5},
+		{"7.5 % 2", 1.5},
+		{"(1.5 + 0.5) * 2", 4.0},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		assertEvaluated(t, tt.expected, evaluated)
+	}
+}
+
+func TestFloatComparisons(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"1.5 < 2", true},
+		{"2 > 1.5", true},
+		{"1 == 1.0", true},
+		{"1.0 == 1", true},
+		{"1.5 != 1.5", false},
+		{"2.5 <= 2.5", true},
+		{"!1.5", false},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
 func TestStringLiteral(t *testing.T) {
 	input := `"Hello World!"`
 
@@ -252,6 +303,24 @@ func TestWhileExpressions(t *testing.T) {
 		{"n := 10; while (n > 0) { n = n - 1 }; n", 0},
 		{"n := 0; while (n < 10) { n = n + 1 }", nil},
 		{"n := 10; while (n > 0) { n = n - 1 }", nil},
+		{"n := 0; while (true) { n = n + 1; if (n == 5) { break } }; n", 5},
+		{"n := 0; while (true) { break; n = 1 }", nil},
+		{"n := 0; s := 0; while (n < 10) { n = n + 1; if (n % 2 == 0) { continue } s = s + n }; s", 25},
+		{`
+		n := 0
+		i := 0
+		while (i < 3) {
+			i = i + 1
+			j := 0
+			while (true) {
+				j = j + 1
+				if (j > 2) { break }
+				if (j == 1) { continue }
+				n = n + 1
+			}
+		}
+		n`, 3},
+		{"f := fn() { n := 0; while (true) { n = n + 1; if (n == 3) { return n } } }; f()", 3},
 	}
 
 	for _, tt := range tests {
@@ -265,6 +334,220 @@ func TestWhileExpressions(t *testing.T) {
 	}
 }
 
+func TestForExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"for x in [] { }", nil},
+		{"s := 0; for x in [1, 2, 3] { s = s + x }; s", 6},
+		{"s := 0; for i, x in [1, 2, 3] { s = s + i * x }; s", 8},
+		{`s := ""; for c in "héllo" { s = c + s }; s`, "olléh"},
+		{`s := ""; for k, v in {"b": 2, "a": 1} { s = s + k + str(v) }; s`, "b2a1"},
+		{`h := {"b": 2, "a": 1}; h["c"] = 3; h["b"] = 4; s := ""; for k, v in h { s = s + k + str(v) }; s`, "b4a1c3"},
+		{"s := 0; for x in range(10, 0, -3) { s = s + x }; s", 22},
+		{"s := 0; for x in range(10) { if (x == 4) { break } s = s + x }; s", 6},
+		{"s := 0; for x in range(10) { if (x % 2 == 0) { continue } s = s + x }; s", 25},
+		{"s := 0; for x in range(3) { for y in range(3) { if (y > x) { break } s = s + 1 } }; s", 6},
+		{"f := fn() { for x in range(10) { if (x == 4) { return x } } }; f()", 4},
+		{"for x in 1 { }", errors.New("TypeError: object of type 'int' is not iterable")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if err, ok := tt.expected.(error); ok {
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != err.Error() {
+				t.Errorf("wrong error message. expected=%q, got=%q",
+					err, errObj.Message)
+			}
+			continue
+		}
+		assertEvaluated(t, tt.expected, evaluated)
+	}
+}
+
+func TestTryExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"try { 1 } catch { 2 }", 1},
+		{"try { 1 / 0 } catch { 2 }", 2},
+		{`try { throw "oops" } catch (e) { e.message }`, "oops"},
+		{`try { throw "oops" } catch (e) { e.kind }`, "Error"},
+		{`try { 1 / 0 } catch (e) { e.kind }`, "ZeroDivisionError"},
+		{`try { 1 + "a" } catch (e) { e.kind }`, "TypeError"},
+		{`try { 1 + "a" } catch (e) { e.message }`, "unknown operator: int + str"},
+		{`try { [1][1] = 2 } catch (e) { e.kind }`, "IndexError"},
+		{`try { fn(x) { x }() } catch (e) { e.message }`, "wrong number of arguments: want=1, got=0"},
+		{`try { x := 1; x[0] } catch (e) { e.message }`, "index operator not supported: left=int index=int"},
+		{`try { len(1) } catch (e) { e.kind }`, "TypeError"},
+		{`try { throw 1 } catch (e) { e.kind }`, "TypeError"},
+		{`try { throw "ValueError: bad" } catch (e) { e.message }`, "bad"},
+		{`e := try { throw "oops" } catch (e) { e }; type(e)`, "error"},
+		{`try { try { throw "a" } catch (e) { throw e } } catch (e) { e.message }`, "a"},
+		{"x := 0; try { x = 1 } finally { x = x + 1 }; x", 2},
+		{"x := 0; try { try { 1 / 0 } finally { x = 1 } } catch { x = x + 1 }; x", 2},
+		{"x := 0; try { 1 / 0 } catch { x = 1 } finally { x = x * 10 }; x", 10},
+		{"f := fn() { throw \"oops\" }; try { f() } catch (e) { e.message }", "oops"},
+		{"f := fn() { try { return 1 } finally { 2 } }; f()", 1},
+		{"f := fn() { try { return 1 } finally { return 2 } }; f()", 2},
+		{"f := fn() { try { 1 / 0 } catch { return 1 } }; f()", 1},
+		{"x := 0; for i in range(3) { try { continue } finally { x = x + 1 } }; x", 3},
+		{"x := 0; for i in range(3) { try { break } finally { x = x + 1 } }; x", 1},
+		{"s := 0; for i in range(5) { try { if (i == 2) { throw \"skip\" } s = s + i } catch { } }; s", 8},
+		{`throw "oops"`, errors.New("oops")},
+		{`1 % 0`, errors.New("ZeroDivisionError: integer division or modulo by zero")},
+		{`try { throw "a" } finally { 1 }`, errors.New("a")},
+		{`try { throw "a" } catch (e) { throw "b" }`, errors.New("b")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if err, ok := tt.expected.(error); ok {
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != err.Error() {
+				t.Errorf("wrong error message. expected=%q, got=%q",
+					err, errObj.Message)
+			}
+			continue
+		}
+		assertEvaluated(t, tt.expected, evaluated)
+	}
+}
+
+func TestSpawnAndSelectExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"c := channel(); spawn fn() { send(c, 42) }(); recv(c)", 42},
+		{"c := channel(); f := fn(x) { send(c, x * 2) }; for i in range(3) { spawn f(i) }; recv(c) + recv(c) + recv(c)", 6},
+		{"c := channel(1); spawn send(c, 3); recv(c)", 3},
+		{"n := 0; c := channel(1); spawn fn() { for i in range(5000) { n = n + 1 }; send(c, n) }(); while (len(c) == 0) { }; recv(c)", 5000},
+		{"c := channel(); close(c); recv(c)", nil},
+		{`c := channel(1); send(c, 1); select { case send(c, 2) { "sent" } default { "full" } }`, "full"},
+		{"c := channel(); select { case recv(c) { 1 } default { 2 } }", 2},
+		{"c := channel(1); send(c, 5); select { case v := recv(c) { v * 2 } }", 10},
+		{"c := channel(); close(c); select { case v := recv(c) { v } }", nil},
+		{"spawn 1", errors.New("TypeError: spawn expected a function, got `int`")},
+		{"select { case recv(1) { } }", errors.New("TypeError: select case #1 expected a `channel` got `int`")},
+		{"c := channel(); close(c); close(c)", errors.New("ChannelError: close of closed channel")},
+		{"c := channel(); close(c); send(c, 1)", errors.New("ChannelError: send on closed channel")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if err, ok := tt.expected.(error); ok {
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != err.Error() {
+				t.Errorf("wrong error message. expected=%q, got=%q",
+					err, errObj.Message)
+			}
+			continue
+		}
+		assertEvaluated(t, tt.expected, evaluated)
+	}
+}
+
+func TestEventLoop(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`s := [""]; timer(20, fn() { s[0] = s[0] + "c" }); timer(0, fn() { s[0] = s[0] + "a" }); timer(10, fn() { s[0] = s[0] + "b" }); run_loop(); s[0]`, "abc"},
+		{`s := [""]; t := timer(0, fn() { s[0] = "x" }); cancel(t); run_loop(); s[0]`, ""},
+		{`n := [0]; timer(1, fn() { n[0] = n[0] + 1; if (n[0] == 2) { stop_loop() } }, true); run_loop(); n[0]`, 2},
+		{"timer(0, fn() { 1 / 0 }); run_loop()", errors.New("ZeroDivisionError: integer division or modulo by zero")},
+		{"timer(0, fn() { run_loop() }); run_loop()", errors.New("RuntimeError: the event loop is already running")},
+		{"timer(0, fn(x) { x }); run_loop()", errors.New("TypeError: wrong number of arguments: want=1, got=0")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if err, ok := tt.expected.(error); ok {
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != err.Error() {
+				t.Errorf("wrong error message. expected=%q, got=%q",
+					err, errObj.Message)
+			}
+			continue
+		}
+		assertEvaluated(t, tt.expected, evaluated)
+	}
+}
+
+func TestNetAndHTTPModules(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{
+			`net := import("net")
+u := net.listen("udp", "127.0.0.1:0")
+c := net.dial("udp", net.address(u))
+write(c, "ping")
+msg := net.recvfrom(u)
+net.sendto(u, msg[0] + "/pong", msg[1])
+data := read(c)
+close(c); close(u)
+data`,
+			"ping/pong",
+		},
+		{
+			`http := import("http")
+srv := http.listen("127.0.0.1:0", {
+  "GET /hello/{name}": fn(req) { return "Hello " + req.params.name + "!" },
+  "POST /echo": fn(req) { return {"status": 201, "body": req.body} }
+})
+spawn srv.serve()
+base := "http://" + srv.address
+a := http.get(base + "/hello/monkey")
+b := http.post(base + "/echo", "ping")
+c := http.get(base + "/nowhere")
+srv.close()
+join([a.body, str(b.status), b.body, str(c.status)], "|")`,
+			"Hello monkey!|201|ping|404",
+		},
+		{`import("net").dial("unix", "/tmp/x")`, errors.New("ValueError: unknown network 'unix'")},
+		{`import("nosuchmodule")`, errors.New("ImportError: no module named 'nosuchmodule'")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if err, ok := tt.expected.(error); ok {
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != err.Error() {
+				t.Errorf("wrong error message. expected=%q, got=%q",
+					err, errObj.Message)
+			}
+			continue
+		}
+		assertEvaluated(t, tt.expected, evaluated)
+	}
+}
+
 func testNullObject(t *testing.T, obj object.Object) bool {
 	if obj != NULL {
 		t.Errorf("object is not NULL. got=%T (%+v)", obj, obj)
@@ -309,27 +592,27 @@ func TestErrorHandling(t *testing.T) {
 	}{
 		{
 			"5 + true;",
-			"unknown operator: int + bool",
+			"TypeError: unknown operator: int + bool",
 		},
 		{
 			"5 + true; 5;",
-			"unknown operator: int + bool",
+			"TypeError: unknown operator: int + bool",
 		},
 		{
 			"-true",
-			"unknown operator: -bool",
+			"TypeError: unknown operator: -bool",
 		},
 		{
 			"true + false;",
-			"unknown operator: bool + bool",
+			"TypeError: unknown operator: bool + bool",
 		},
 		{
 			"5; true + false; 5",
-			"unknown operator: bool + bool",
+			"TypeError: unknown operator: bool + bool",
 		},
 		{
 			"if (10 > 1) { true + false; }",
-			"unknown operator: bool + bool",
+			"TypeError: unknown operator: bool + bool",
 		},
 		{
 			`
@@ -341,19 +624,35 @@ if (10 > 1) {
   return 1;
 }
 `,
-			"unknown operator: bool + bool",
+			"TypeError: unknown operator: bool + bool",
 		},
 		{
 			"foobar",
-			"identifier not found: foobar",
+			"NameError: identifier not found: foobar",
 		},
 		{
 			`"Hello" - "World"`,
-			"unknown operator: str - str",
+			"TypeError: unknown operator: str - str",
 		},
 		{
 			`{"name": "Monkey"}[fn(x) { x }];`,
-			"unusable as hash key: fn",
+			"TypeError: unusable as hash key: fn",
+		},
+		{
+			`{[1, fn(x) { x }]: 1}`,
+			"TypeError: unusable as hash key: array",
+		},
+		{
+			"break",
+			"'break' outside loop",
+		},
+		{
+			"if (true) { continue }",
+			"'continue' outside loop",
+		},
+		{
+			"while (true) { fn() { break }() }",
+			"'break' outside loop",
 		},
 	}
 
@@ -594,6 +893,31 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`int(false)`, 0},
 		{`int(1)`, 1},
 		{`int("10")`, 10},
+		{`int(2.7)`, 2},
+		{`int(-2.7)`, -2},
+		{`float(1)`, 1.0},
+		{`float(true)`, 1.0},
+		{`float("2.5")`, 2.5},
+		{`float(1.5)`, 1.5},
+		{`str(1.5)`, "1.5"},
+		{`str(2.0)`, "2.0"},
+		{`abs(-5)`, 5},
+		{`abs(-1.5)`, 1.5},
+		{`abs("a")`, errors.New("TypeError: abs() expected argument #1 to be `int` or `float` got `str`")},
+		{`pow(2, 10)`, 1024},
+		{`pow(2.0, 3)`, 8.0},
+		{`pow(4, 0.5)`, 2.0},
+		{`pow(2, -1)`, 0.5},
+		{`min([3, 1, 2])`, 1},
+		{`min([3, 1.5, 2])`, 1.5},
+		{`max([3, 1, 2])`, 3},
+		{`max([3, 4.5, 2])`, 4.5},
+		{`max([])`, errors.New("ValueError: max() arg is an empty array")},
+		{`len(range(10))`, 10},
+		{`len(range(10, 0, -3))`, 4},
+		{`len(range(5, 1))`, 0},
+		{`str(range(1, 5, 2))`, "range(1, 5, 2)"},
+		{`range(1, 5, 0)`, errors.New("ValueError: range() arg 3 must not be zero")},
 		{`str(null)`, "null"},
 		{`str(true)`, "true"},
 		{`str(false)`, "false"},
@@ -611,6 +935,8 @@ func TestBuiltinFunctions(t *testing.T) {
 			testBooleanObject(t, evaluated, expected)
 		case int:
 			testIntegerObject(t, evaluated, int64(expected))
+		case float64:
+			assertEvaluated(t, expected, evaluated)
 		case string:
 			testStringObject(t, evaluated, expected)
 		case error:
@@ -768,12 +1094,12 @@ func TestHashLiterals(t *testing.T) {
 		FALSE.HashKey():                            6,
 	}
 
-	if len(result.Pairs) != len(expected) {
-		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	if result.Len() != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", result.Len())
 	}
 
 	for expectedKey, expectedValue := range expected {
-		pair, ok := result.Pairs[expectedKey]
+		pair, ok := hashPairs(result)[expectedKey]
 		if !ok {
 			t.Errorf("no pair for given key in Pairs")
 		}
@@ -795,12 +1121,12 @@ func TestHashMerging(t *testing.T) {
 		(&object.String{Value: "b"}).HashKey(): 2,
 	}
 
-	if len(result.Pairs) != len(expected) {
-		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	if result.Len() != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", result.Len())
 	}
 
 	for expectedKey, expectedValue := range expected {
-		pair, ok := result.Pairs[expectedKey]
+		pair, ok := hashPairs(result)[expectedKey]
 		if !ok {
 			t.Errorf("no pair for given key in Pairs")
 		}
@@ -872,6 +1198,18 @@ func TestHashIndexExpressions(t *testing.T) {
 			`{false: 5}[false]`,
 			5,
 		},
+		{
+			`{[1, "a"]: 5}[[1, "a"]]`,
+			5,
+		},
+		{
+			`{[1, "a"]: 5}[["a", 1]]`,
+			nil,
+		},
+		{
+			`k := [1]; h := {k: 5}; k[0] = 2; h[[1]]`,
+			5,
+		},
 	}
 
 	for _, tt := range tests {
@@ -885,6 +1223,66 @@ func TestHashIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestContext(t *testing.T) {
+	var stdout bytes.Buffer
+	var status int
+	ctx := &object.Context{
+		Stdin:  strings.NewReader("Alice\n"),
+		Stdout: &stdout,
+		Args:   []string{"-v"},
+		Env:    []string{"GREETING=Hello"},
+		Exit:   func(n int) { status = n },
+	}
+
+	program := parser.New(lexer.New(`name := input("name? ")
+greet := fn(name) { print(getenv("GREETING") + " " + name) }
+greet(name)
+print(args())
+exit(3)`)).ParseProgram()
+	if obj := Eval(program, object.NewEnvironmentWithContext(ctx)); isError(obj) {
+		t.Fatalf("eval error: %s", obj)
+	}
+
+	expected := "name? Hello Alice\n[\"-v\"]\n"
+	if stdout.String() != expected {
+		t.Errorf("stdout: want=%q, got=%q", expected, stdout.String())
+	}
+	if status != 3 {
+		t.Errorf("exit status: want=3, got=%d", status)
+	}
+}
+
+func TestSandbox(t *testing.T) {
+	tests := []struct {
+		input        string
+		capabilities object.Capability
+		expected     string
+	}{
+		{`len("monkey")`, 0, "6"},
+		{`args()`, object.ProcessCapability, "[]"},
+		{`exit()`, object.FilesystemCapability, "PermissionError: exit() requires the process capability"},
+		{`readfile("/dev/null")`, 0, "PermissionError: readfile() requires the filesystem capability"},
+		{`f := fn() { return socket("tcp4") }; f()`, 0, "PermissionError: socket() requires the network capability"},
+		{`try { open("/dev/null") } catch (e) { e.kind }`, 0, "PermissionError"},
+	}
+
+	for _, tt := range tests {
+		ctx := object.NewContext()
+		ctx.Sandbox = true
+		ctx.Capabilities = tt.capabilities
+
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		actual := Eval(program, object.NewEnvironmentWithContext(ctx))
+		if err, ok := actual.(*object.Error); ok && !err.Caught {
+			if err.Message != tt.expected {
+				t.Errorf("%s: want=%q, got=%q", tt.input, tt.expected, err.Message)
+			}
+		} else if actual.String() != tt.expected {
+			t.Errorf("%s: want=%q, got=%q", tt.input, tt.expected, actual.String())
+		}
+	}
+}
+
 func TestImportExpressions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -931,3 +1329,42 @@ func TestExamples(t *testing.T) {
 		testEval(string(b))
 	}
 }
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedPosition string
+	}{
+		{"5 + true;", "test.monkey:1:3"},
+		{"x := 1\nfoobar", "test.monkey:2:1"},
+		{"f := fn(x) {\n  return x - \"a\"\n}\nf(1)", "test.monkey:2:12"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewWithFilename(tt.input, "test.monkey")
+		p := parser.New(l)
+		program := p.ParseProgram()
+		evaluated := Eval(program, object.NewEnvironment())
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)",
+				evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Position.String() != tt.expectedPosition {
+			t.Errorf("wrong error position. expected=%q, got=%q",
+				tt.expectedPosition, errObj.Position)
+		}
+	}
+}
+
+// hashPairs returns the pairs of the hash by their keys
+func hashPairs(h *object.Hash) map[object.HashKey]object.HashPair {
+	pairs := make(map[object.HashKey]object.HashPair)
+	for _, pair := range h.Pairs() {
+		pairs[pair.Key.(object.Hashable).HashKey()] = pair
+	}
+	return pairs
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/prologic/monkey-lang/format"
+)
+
+// formatFiles implements the fmt command which formats the files or the
+// source files in directories given as arguments, or the standard input if
+// none are given, and returns the exit status
+func formatFiles(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	list := flags.Bool("l", false, "list files whose formatting differs")
+	write := flags.Bool("w", false, "write the result to the source file instead of stdout")
+	flags.Usage = func() {
+		name := path.Base(os.Args[0])
+		fmt.Fprintf(flags.Output(), "Usage: %s fmt [options] [<path> ...]\n\n", name)
+		fmt.Fprint(flags.Output(), "Options:\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		if *list || *write {
+			fmt.Fprintln(os.Stderr, "error: cannot use -l or -w with standard input")
+			return 2
+		}
+		src, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		res, err := format.Source("<stdin>", src)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		os.Stdout.Write(res)
+		return 0
+	}
+
+	return walkSources(flags.Args(), func(filename string) error {
+		return formatFile(filename, *list, *write)
+	})
+}
+
+// formatFile formats the file filename and lists its name if its formatting
+// differs, rewrites it or prints the result
+func formatFile(filename string, list, write bool) error {
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	res, err := format.Source(filename, src)
+	if err != nil {
+		return fmt.Errorf("%s: %s", filename, err)
+	}
+
+	if list || write {
+		if bytes.Equal(src, res) {
+			return nil
+		}
+		if list {
+			fmt.Println(filename)
+		}
+		if write {
+			info, err := os.Stat(filename)
+			if err != nil {
+				return err
+			}
+			return ioutil.WriteFile(filename, res, info.Mode().Perm())
+		}
+		return nil
+	}
+
+	_, err = os.Stdout.Write(res)
+	return err
+}
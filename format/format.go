@@ -0,0 +1,424 @@
+// Package format implements the canonical formatting of Monkey source code.
+// Unlike printing the AST, formatting preserves comments and blank lines
+// (collapsing runs of blank lines into one), indents blocks by two spaces,
+// separates binary operators by spaces and only parenthesizes expressions
+// where required by the precedence of operators.
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/prologic/monkey-lang/ast"
+	"github.com/prologic/monkey-lang/lexer"
+	"github.com/prologic/monkey-lang/parser"
+	"github.com/prologic/monkey-lang/token"
+)
+
+// indent is the indentation of each level of blocks
+const indent = "  "
+
+// primary is the precedence of expressions that are never parenthesized
+const primary = parser.INDEX + 1
+
+// Source formats the source code read from filename and returns the result
+// or an error if the source does not parse
+func Source(filename string, src []byte) ([]byte, error) {
+	text := string(src)
+
+	p := parser.New(lexer.NewWithFilename(text, filename))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("parser errors:\n\t%s", strings.Join(p.Errors(), "\n\t"))
+	}
+
+	pr := &printer{
+		lines: strings.Split(text, "\n"),
+		first: make(map[int]token.Position),
+	}
+
+	l := lexer.NewWithFilename(text, filename)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if _, ok := pr.first[tok.Pos.Line]; !ok {
+			pr.first[tok.Pos.Line] = tok.Pos
+		}
+	}
+
+	return []byte(pr.statements(program.Statements, "")), nil
+}
+
+// printer prints the AST of a source
+type printer struct {
+	lines []string               // lines of the source
+	first map[int]token.Position // position of the first token of each line
+}
+
+// statements returns the statements each on a line of its own indented by
+// prefix
+func (p *printer) statements(stmts []ast.Statement, prefix string) string {
+	var out bytes.Buffer
+
+	for i, stmt := range stmts {
+		text := p.statement(stmt, prefix)
+
+		if i > 0 {
+			if c, ok := stmt.(*ast.Comment); ok && p.trailing(c) {
+				out.Truncate(out.Len() - 1)
+				out.WriteString(" " + text + "\n")
+				continue
+			}
+
+			// A statement starting with a token that continues an expression
+			// (e.g. a grouped expression would be a call) is separated from
+			// the previous one by a semicolon
+			if continues(stmts[i-1]) && strings.ContainsAny(text[:1], "-!([") {
+				out.Truncate(out.Len() - 1)
+				out.WriteString(";\n")
+			}
+
+			if p.blankBefore(stmt.Pos()) {
+				out.WriteString("\n")
+			}
+		}
+
+		out.WriteString(prefix + text + "\n")
+	}
+
+	return out.String()
+}
+
+// statement returns the statement which begins at the given indentation
+func (p *printer) statement(stmt ast.Statement, prefix string) string {
+	switch stmt := stmt.(type) {
+	case *ast.Comment:
+		return p.comment(stmt)
+	case *ast.ReturnStatement:
+		return "return " + p.expr(stmt.ReturnValue, prefix)
+	case *ast.BreakStatement:
+		return "break"
+	case *ast.ContinueStatement:
+		return "continue"
+	case *ast.ExpressionStatement:
+		return p.expr(stmt.Expression, prefix)
+	default:
+		return stmt.String()
+	}
+}
+
+// block returns the block whose opening brace is at the given indentation
+func (p *printer) block(block *ast.BlockStatement, prefix string) string {
+	stmts := block.Statements
+	if len(stmts) == 0 {
+		return "{}"
+	}
+
+	out := "{"
+	// A comment following the opening brace stays on its line
+	if c, ok := stmts[0].(*ast.Comment); ok && c.Pos().Line == block.Token.Pos.Line {
+		out += " " + p.comment(c)
+		stmts = stmts[1:]
+	}
+
+	return out + "\n" + p.statements(stmts, prefix+indent) + prefix + "}"
+}
+
+// comment returns the comment with the marker, # or //, used in the source
+func (p *printer) comment(c *ast.Comment) string {
+	marker := "#"
+	pos := c.Pos()
+	if line := p.line(pos.Line); pos.Column-1 < len(line) && line[pos.Column-1] == '/' {
+		marker = "//"
+	}
+	return marker + strings.TrimRight(c.Value, " \t\r")
+}
+
+// expr returns the expression which begins at the given indentation
+func (p *printer) expr(e ast.Expression, prefix string) string {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		return e.Value
+	case *ast.IntegerLiteral:
+		return e.Token.Literal
+	case *ast.FloatLiteral:
+		return e.Token.Literal
+	case *ast.StringLiteral:
+		return quote(e.Value)
+	case *ast.Boolean:
+		return fmt.Sprintf("%t", e.Value)
+	case *ast.Null:
+		return "null"
+
+	case *ast.PrefixExpression:
+		// The operand of a prefix operator is parsed with its precedence
+		return e.Operator + p.operand(e.Right, parser.PREFIX-1, prefix)
+	case *ast.InfixExpression:
+		// Infix operators are left associative
+		precedence := parser.Precedence(e.Token.Type)
+		return p.operand(e.Left, precedence-1, prefix) + " " + e.Operator + " " +
+			p.operand(e.Right, precedence, prefix)
+	case *ast.BindExpression:
+		if e.Type != nil {
+			return p.expr(e.Left, prefix) + ": " + e.Type.String() + " := " + p.expr(e.Value, prefix)
+		}
+		return p.expr(e.Left, prefix) + " := " + p.expr(e.Value, prefix)
+	case *ast.AssignmentExpression:
+		return p.expr(e.Left, prefix) + " = " + p.expr(e.Value, prefix)
+
+	case *ast.CallExpression:
+		return p.operand(e.Function, parser.CALL-1, prefix) +
+			p.list("(", ")", e.Token.Pos, e.Arguments, prefix)
+	case *ast.IndexExpression:
+		left := p.operand(e.Left, parser.CALL-1, prefix)
+		// A selector expression, e.g. foo.bar, indexes by a string literal
+		// parsed from an identifier
+		if name, ok := e.Index.(*ast.StringLiteral); ok && name.Token.Type == token.IDENT {
+			return left + "." + name.Value
+		}
+		return left + "[" + p.expr(e.Index, prefix) + "]"
+
+	case *ast.ArrayLiteral:
+		return p.list("[", "]", e.Token.Pos, e.Elements, prefix)
+	case *ast.HashLiteral:
+		return p.hash(e, prefix)
+
+	case *ast.FunctionLiteral:
+		var params []string
+		for i, param := range e.Parameters {
+			if i < len(e.ParameterTypes) && e.ParameterTypes[i] != nil {
+				params = append(params, param.Value+": "+e.ParameterTypes[i].String())
+			} else {
+				params = append(params, param.Value)
+			}
+		}
+		out := "fn(" + strings.Join(params, ", ") + ") "
+		if e.ReturnType != nil {
+			out += "-> " + e.ReturnType.String() + " "
+		}
+		return out + p.block(e.Body, prefix)
+
+	case *ast.IfExpression:
+		out := "if (" + p.expr(e.Condition, prefix) + ") " + p.block(e.Consequence, prefix)
+		if e.Alternative == nil {
+			return out
+		}
+		// The parser wraps the if expression of `else if` in a block without
+		// braces
+		if e.Alternative.Token.Type != token.LBRACE && len(e.Alternative.Statements) == 1 {
+			if stmt, ok := e.Alternative.Statements[0].(*ast.ExpressionStatement); ok {
+				return out + " else " + p.expr(stmt.Expression, prefix)
+			}
+		}
+		return out + " else " + p.block(e.Alternative, prefix)
+	case *ast.WhileExpression:
+		return "while (" + p.expr(e.Condition, prefix) + ") " + p.block(e.Consequence, prefix)
+	case *ast.ForExpression:
+		out := "for "
+		if e.Key != nil {
+			out += e.Key.Value + ", "
+		}
+		return out + e.Value.Value + " in " + p.expr(e.Iterable, prefix) + " " + p.block(e.Body, prefix)
+	case *ast.TryExpression:
+		out := "try " + p.block(e.Block, prefix)
+		if e.Catch != nil {
+			out += " catch "
+			if e.CatchParam != nil {
+				out += "(" + e.CatchParam.Value + ") "
+			}
+			out += p.block(e.Catch, prefix)
+		}
+		if e.Finally != nil {
+			out += " finally " + p.block(e.Finally, prefix)
+		}
+		return out
+	case *ast.ThrowExpression:
+		return "throw " + p.expr(e.Value, prefix)
+	case *ast.SpawnExpression:
+		return "spawn " + p.expr(e.Value, prefix)
+	case *ast.SelectExpression:
+		return p.selectExpr(e, prefix)
+	case *ast.ImportExpression:
+		return "import(" + p.expr(e.Name, prefix) + ")"
+
+	default:
+		return e.String()
+	}
+}
+
+// selectExpr returns the select expression with each case on a line of its
+// own indented within the select
+func (p *printer) selectExpr(e *ast.SelectExpression, prefix string) string {
+	inner := prefix + indent
+	out := "select {\n"
+	for _, c := range e.Cases {
+		out += inner + "case "
+		switch {
+		case c.Value != nil:
+			out += "send(" + p.expr(c.Channel, inner) + ", " + p.expr(c.Value, inner) + ")"
+		case c.Name != nil:
+			out += c.Name.Value + " := recv(" + p.expr(c.Channel, inner) + ")"
+		default:
+			out += "recv(" + p.expr(c.Channel, inner) + ")"
+		}
+		out += " " + p.block(c.Body, inner) + "\n"
+	}
+	if e.Default != nil {
+		out += inner + "default " + p.block(e.Default, inner) + "\n"
+	}
+	return out + prefix + "}"
+}
+
+// operand returns the expression as the operand of an operator, which is
+// parenthesized unless its precedence is higher than the given precedence
+func (p *printer) operand(e ast.Expression, precedence int, prefix string) string {
+	if precedenceOf(e) > precedence {
+		return p.expr(e, prefix)
+	}
+	return "(" + p.expr(e, prefix) + ")"
+}
+
+// list returns the expressions separated by commas between the open and
+// close brackets, each on a line of its own if the first expression was on
+// a line after the opening bracket at pos in the source
+func (p *printer) list(open, close string, pos token.Position, list []ast.Expression, prefix string) string {
+	if len(list) == 0 {
+		return open + close
+	}
+
+	if start(list[0]).Line == pos.Line {
+		var items []string
+		for _, e := range list {
+			items = append(items, p.expr(e, prefix))
+		}
+		return open + strings.Join(items, ", ") + close
+	}
+
+	var items []string
+	for _, e := range list {
+		items = append(items, prefix+indent+p.expr(e, prefix+indent))
+	}
+	return open + "\n" + strings.Join(items, ",\n") + "\n" + prefix + close
+}
+
+// hash returns the hash literal with its pairs in the order of the source,
+// each on a line of its own if the first pair was on a line after the
+// opening brace in the source
+func (p *printer) hash(h *ast.HashLiteral, prefix string) string {
+	if len(h.Pairs) == 0 {
+		return "{}"
+	}
+
+	keys := h.Keys
+	if start(keys[0]).Line == h.Token.Pos.Line {
+		var pairs []string
+		for _, key := range keys {
+			pairs = append(pairs, p.expr(key, prefix)+": "+p.expr(h.Pairs[key], prefix))
+		}
+		return "{" + strings.Join(pairs, ", ") + "}"
+	}
+
+	var pairs []string
+	for _, key := range keys {
+		inner := prefix + indent
+		pairs = append(pairs, inner+p.expr(key, inner)+": "+p.expr(h.Pairs[key], inner))
+	}
+	return "{\n" + strings.Join(pairs, ",\n") + "\n" + prefix + "}"
+}
+
+// trailing returns true if the comment follows code on the same line
+func (p *printer) trailing(c *ast.Comment) bool {
+	return p.first[c.Pos().Line] != c.Pos()
+}
+
+// blankBefore returns true if the statement starting at pos is preceded by
+// a blank line
+func (p *printer) blankBefore(pos token.Position) bool {
+	return p.first[pos.Line] == pos && pos.Line > 1 &&
+		strings.TrimSpace(p.line(pos.Line-1)) == ""
+}
+
+// line returns the source line n (starting at 1)
+func (p *printer) line(n int) string {
+	if n < 1 || n > len(p.lines) {
+		return ""
+	}
+	return p.lines[n-1]
+}
+
+// continues returns true if the statement ends in an expression that would
+// continue into a following statement starting with an operator
+func continues(stmt ast.Statement) bool {
+	switch stmt.(type) {
+	case *ast.ExpressionStatement, *ast.ReturnStatement:
+		return true
+	}
+	return false
+}
+
+// precedenceOf returns the precedence of the expression
+func precedenceOf(e ast.Expression) int {
+	switch e := e.(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(e.Token.Type)
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	case *ast.BindExpression, *ast.AssignmentExpression:
+		return parser.ASSIGN
+	case *ast.CallExpression:
+		return parser.CALL
+	case *ast.IndexExpression:
+		return parser.INDEX
+	case *ast.ThrowExpression, *ast.SpawnExpression:
+		// The value thrown or spawned extends as far as possible
+		return parser.LOWEST
+	default:
+		return primary
+	}
+}
+
+// start returns the position of the first token of the expression
+func start(e ast.Expression) token.Position {
+	switch e := e.(type) {
+	case *ast.InfixExpression:
+		return start(e.Left)
+	case *ast.BindExpression:
+		return start(e.Left)
+	case *ast.AssignmentExpression:
+		return start(e.Left)
+	case *ast.CallExpression:
+		return start(e.Function)
+	case *ast.IndexExpression:
+		return start(e.Left)
+	}
+	return e.Pos()
+}
+
+// quote returns the string as a string literal escaping quotes, backslashes,
+// control characters and invalid UTF-8
+func quote(s string) string {
+	var out strings.Builder
+	out.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '"':
+			out.WriteString(`\"`)
+		case r == '\\':
+			out.WriteString(`\\`)
+		case r == '\n':
+			out.WriteString(`\n`)
+		case r == '\r':
+			out.WriteString(`\r`)
+		case r == '\t':
+			out.WriteString(`\t`)
+		case r < ' ' || r == 0x7f || r == utf8.RuneError && size == 1:
+			fmt.Fprintf(&out, `\x%02x`, s[i])
+		default:
+			out.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	out.WriteByte('"')
+	return out.String()
+}
@@ -0,0 +1,133 @@
+package format
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prologic/monkey-lang/lexer"
+	"github.com/prologic/monkey-lang/token"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// Spacing and indentation
+		{"x:=1+2", "x := 1 + 2\n"},
+		{"f := fn(x,y){x*y};", "f := fn(x, y) {\n  x * y\n}\n"},
+		{"f := fn() {}", "f := fn() {}\n"},
+		{"if(a){b}else{c}", "if (a) {\n  b\n} else {\n  c\n}\n"},
+		{
+			"if (a) { 1 } else if (b) { 2 } else { 3 }",
+			"if (a) {\n  1\n} else if (b) {\n  2\n} else {\n  3\n}\n",
+		},
+		{"while (i < 3) {\n\t\ti = i + 1\n}", "while (i < 3) {\n  i = i + 1\n}\n"},
+		{"for k,v in xs { print(k, v) }", "for k, v in xs {\n  print(k, v)\n}\n"},
+		{
+			"try { throw \"x\" } catch (e) { print(e) } finally { done() }",
+			"try {\n  throw \"x\"\n} catch (e) {\n  print(e)\n} finally {\n  done()\n}\n",
+		},
+		{"m := import(\"mod\")\nm.Foo(1)", "m := import(\"mod\")\nm.Foo(1)\n"},
+		{"spawn  f(1,2)", "spawn f(1, 2)\n"},
+		{
+			"select{case v:=recv(c){print(v)} case send(d,1){} default{done()}}",
+			"select {\n  case v := recv(c) {\n    print(v)\n  }\n  case send(d, 1) {}\n  default {\n    done()\n  }\n}\n",
+		},
+
+		// Type annotations
+		{"x:int:=1", "x: int := 1\n"},
+		{
+			"f := fn(a:int,b,c:[str])->{str:fn(int)->bool} {}",
+			"f := fn(a: int, b, c: [str]) -> {str: fn(int) -> bool} {}\n",
+		},
+
+		// Parentheses
+		{"(1 + 2) * 3", "(1 + 2) * 3\n"},
+		{"((1 * 2)) + 3", "1 * 2 + 3\n"},
+		{"1 - (2 - 3)", "1 - (2 - 3)\n"},
+		{"(1 - 2) - 3", "1 - 2 - 3\n"},
+		{"-(a + b)", "-(a + b)\n"},
+		{"!(a == b)", "!(a == b)\n"},
+		{"(fn(x) { x })(1)", "fn(x) {\n  x\n}(1)\n"},
+		{"(a + b)[0]", "(a + b)[0]\n"},
+
+		// Literals
+		{`s := "a\tb\"c\\d\x01"`, `s := "a\tb\"c\\d\x01"` + "\n"},
+		{"x := 1.50", "x := 1.50\n"},
+		{"h := {\"b\": 2, \"a\": 1}", "h := {\"b\": 2, \"a\": 1}\n"},
+		{"h := {\n\"b\": 2,\n\"a\": 1}", "h := {\n  \"b\": 2,\n  \"a\": 1\n}\n"},
+		{"xs := [\n1, 2]", "xs := [\n  1,\n  2\n]\n"},
+		{"f(\n1,\n[2, 3])", "f(\n  1,\n  [2, 3]\n)\n"},
+
+		// Comments and blank lines
+		{"#!/usr/bin/env monkey-lang\n// hello  \nx := 1", "#!/usr/bin/env monkey-lang\n// hello\nx := 1\n"},
+		{"x := 1   # one\ny := 2 // two", "x := 1 # one\ny := 2 // two\n"},
+		{"f := fn() { # body\nx\n}", "f := fn() { # body\n  x\n}\n"},
+		{"x := 1\n\n\n\ny := 2\n", "x := 1\n\ny := 2\n"},
+		{"f := fn() {\n\nx\n\n# y\ny\n}", "f := fn() {\n  x\n\n  # y\n  y\n}\n"},
+
+		// Statements that would otherwise continue the previous one
+		{"x := 1;\n(1 + 2) * 3", "x := 1;\n(1 + 2) * 3\n"},
+		{"x := 1;\n-1", "x := 1;\n-1\n"},
+		{"x := 1; y := 2", "x := 1\ny := 2\n"},
+	}
+
+	for _, tt := range tests {
+		actual, err := Source("test.monkey", []byte(tt.input))
+		require.NoError(t, err, tt.input)
+		assert.Equal(t, tt.expected, string(actual), tt.input)
+
+		again, err := Source("test.monkey", actual)
+		require.NoError(t, err, tt.input)
+		assert.Equal(t, string(actual), string(again), "not idempotent: %s", tt.input)
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	_, err := Source("test.monkey", []byte("x := (1 + "))
+	assert.Error(t, err)
+}
+
+func TestExamples(t *testing.T) {
+	matches, err := filepath.Glob("../examples/*.monkey")
+	require.NoError(t, err)
+	require.NotEmpty(t, matches)
+
+	for _, filename := range matches {
+		src, err := ioutil.ReadFile(filename)
+		require.NoError(t, err)
+
+		actual, err := Source(filename, src)
+		require.NoError(t, err, filename)
+
+		again, err := Source(filename, actual)
+		require.NoError(t, err, filename)
+		assert.Equal(t, string(actual), string(again), "not idempotent: %s", filename)
+
+		// Formatting only changes the layout of the program
+		assert.Equal(t, tokens(string(src)), tokens(string(actual)), filename)
+	}
+}
+
+// tokens returns the tokens of the input other than semicolons, with
+// trailing whitespace trimmed from comments
+func tokens(input string) []string {
+	var result []string
+	l := lexer.New(input)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.SEMICOLON:
+		case token.COMMENT:
+			result = append(result, strings.TrimRight(tok.Literal, " \t\r"))
+		default:
+			result = append(result, tok.Literal)
+		}
+	}
+	return result
+}
@@ -0,0 +1,60 @@
+// Package framing implements the base protocol shared by the Language Server
+// Protocol and Debug Adapter Protocol servers, which frames each message with
+// a header, terminated by an empty line, declaring the Content-Length of the
+// JSON content that follows.
+package framing
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// MaxContentLength is the largest Content-Length of a message read, so a bad
+// header cannot make the reader allocate an arbitrary amount of memory
+const MaxContentLength = 16 << 20
+
+// ReadMessage reads the content of the next message from r, io.EOF is
+// returned if r ends before the header of a message
+func ReadMessage(r *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF && len(header) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("error reading message header: %s", err)
+	}
+
+	value := strings.TrimSpace(header.Get("Content-Length"))
+	length, err := strconv.Atoi(value)
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length %q", value)
+	}
+	if length > MaxContentLength {
+		return nil, fmt.Errorf("Content-Length %d exceeds the maximum of %d", length, MaxContentLength)
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return nil, fmt.Errorf("error reading message content: %s", err)
+	}
+	return content, nil
+}
+
+// WriteMessage writes the message v encoded as JSON to w preceded by its
+// header
+func WriteMessage(w io.Writer, v interface{}) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(content)); err != nil {
+		return err
+	}
+	_, err = w.Write(content)
+	return err
+}
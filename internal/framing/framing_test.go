@@ -0,0 +1,53 @@
+package framing
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadWriteMessage(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteMessage(&buf, map[string]int{"seq": 1}))
+	require.NoError(t, WriteMessage(&buf, []string{"a", "b"}))
+	assert.Equal(t, "Content-Length: 9\r\n\r\n{\"seq\":1}Content-Length: 9\r\n\r\n[\"a\",\"b\"]", buf.String())
+
+	r := bufio.NewReader(&buf)
+	content, err := ReadMessage(r)
+	require.NoError(t, err)
+	assert.Equal(t, `{"seq":1}`, string(content))
+
+	content, err = ReadMessage(r)
+	require.NoError(t, err)
+	assert.Equal(t, `["a","b"]`, string(content))
+
+	_, err = ReadMessage(r)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReadMessageErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Content-Length: x\r\n\r\n", `invalid Content-Length "x"`},
+		{"Content-Length: -1\r\n\r\n", `invalid Content-Length "-1"`},
+		{"Content-Type: application/json\r\n\r\n", `invalid Content-Length ""`},
+		{
+			fmt.Sprintf("Content-Length: %d\r\n\r\n", MaxContentLength+1),
+			fmt.Sprintf("Content-Length %d exceeds the maximum of %d", MaxContentLength+1, MaxContentLength),
+		},
+		{"Content-Length: 10\r\n\r\n{}", "error reading message content: unexpected EOF"},
+	}
+
+	for _, tt := range tests {
+		_, err := ReadMessage(bufio.NewReader(strings.NewReader(tt.input)))
+		assert.EqualError(t, err, tt.expected, tt.input)
+	}
+}
@@ -21,11 +21,14 @@ func isLetter(ch byte) bool {
 
 // Lexer represents the lexer and contains the source input and internal state
 type Lexer struct {
+	filename     string
 	input        string
 	position     int  // current position in input (points to current char)
 	readPosition int  // current reading position in input (after current char)
 	ch           byte // current char under examination
 	prevCh       byte // previous char read
+	line         int  // current line of the char under examination
+	column       int  // current column of the char under examination
 }
 
 func newToken(tokenType token.Type, ch byte) token.Token {
@@ -34,12 +37,32 @@ func newToken(tokenType token.Type, ch byte) token.Token {
 
 // New returns a new Lexer
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewWithFilename(input, "")
+}
+
+// NewWithFilename returns a new Lexer whose tokens have positions that
+// refer to the given filename
+func NewWithFilename(input, filename string) *Lexer {
+	l := &Lexer{filename: filename, input: input, line: 1}
 	l.readChar()
 	return l
 }
 
+func (l *Lexer) pos() token.Position {
+	return token.Position{
+		Filename: l.filename,
+		Line:     l.line,
+		Column:   l.column,
+	}
+}
+
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
+
 	l.prevCh = l.ch
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
@@ -59,12 +82,21 @@ func (l *Lexer) peekChar() byte {
 	}
 }
 
+func (l *Lexer) peekNextChar() byte {
+	if l.readPosition+1 >= len(l.input) {
+		return 0
+	}
+	return l.input[l.readPosition+1]
+}
+
 // NextToken returns the next token read from the input stream
 func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
 
 	l.skipWhitespace()
 
+	pos := l.pos()
+
 	switch l.ch {
 	case '#':
 		tok.Type = token.COMMENT
@@ -81,7 +113,14 @@ func (l *Lexer) NextToken() token.Token {
 	case '+':
 		tok = newToken(token.PLUS, l.ch)
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		if l.peekChar() == '>' {
+			ch := l.ch
+			l.readChar()
+			literal := string(ch) + string(l.ch)
+			tok = token.Token{Type: token.ARROW, Literal: literal}
+		} else {
+			tok = newToken(token.MINUS, l.ch)
+		}
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -193,10 +232,11 @@ func (l *Lexer) NextToken() token.Token {
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Pos = pos
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
+			tok.Type, tok.Literal = l.readNumber()
+			tok.Pos = pos
 			return tok
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
@@ -205,6 +245,8 @@ func (l *Lexer) NextToken() token.Token {
 
 	l.readChar()
 
+	tok.Pos = pos
+
 	return tok
 }
 
@@ -216,12 +258,38 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[position:l.position]
 }
 
-func (l *Lexer) readNumber() string {
+// readNumber reads an integer or a floating point number with an optional
+// fractional part and/or exponent, e.g: 42, 3.14, 1e-9 or 2.5E+3
+func (l *Lexer) readNumber() (token.Type, string) {
+	typ := token.Type(token.INT)
 	position := l.position
 	for isDigit(l.ch) {
 		l.readChar()
 	}
-	return l.input[position:l.position]
+
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		typ = token.FLOAT
+		l.readChar()
+		for isDigit(l.ch) {
+			l.readChar()
+		}
+	}
+
+	if l.ch == 'e' || l.ch == 'E' {
+		next := l.peekChar()
+		if isDigit(next) || ((next == '+' || next == '-') && isDigit(l.peekNextChar())) {
+			typ = token.FLOAT
+			l.readChar()
+			if l.ch == '+' || l.ch == '-' {
+				l.readChar()
+			}
+			for isDigit(l.ch) {
+				l.readChar()
+			}
+		}
+	}
+
+	return typ, l.input[position:l.position]
 }
 
 func (l *Lexer) readLine() string {
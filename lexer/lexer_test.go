@@ -37,6 +37,7 @@ d.foo
 &|^~
 !&&||
 <<>>
+-> - >
 `
 
 	tests := []struct {
@@ -139,6 +140,9 @@ d.foo
 		{token.OR, "||"},
 		{token.LeftShift, "<<"},
 		{token.RightShift, ">>"},
+		{token.ARROW, "->"},
+		{token.MINUS, "-"},
+		{token.GT, ">"},
 		{token.EOF, ""},
 	}
 
@@ -200,3 +204,94 @@ c := "\r\n\t"
 	}
 
 }
+
+func TestFloatLiterals(t *testing.T) {
+	input := `3.14 1e-9 2.5E+3 0.5 10 1.foo 2e`
+
+	tests := []struct {
+		expectedType    token.Type
+		expectedLiteral string
+	}{
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, "1e-9"},
+		{token.FLOAT, "2.5E+3"},
+		{token.FLOAT, "0.5"},
+		{token.INT, "10"},
+		{token.INT, "1"},
+		{token.DOT, "."},
+		{token.IDENT, "foo"},
+		{token.INT, "2"},
+		{token.IDENT, "e"},
+		{token.EOF, ""},
+	}
+
+	lexer := New(input)
+
+	for i, test := range tests {
+		token := lexer.NextToken()
+
+		if token.Type != test.expectedType {
+			t.Fatalf("tests[%d] - token type wrong. expected=%q, got=%q",
+				i, test.expectedType, token.Type)
+		}
+
+		if token.Literal != test.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, test.expectedLiteral, token.Literal)
+		}
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := `x := 5;
+if (x > 1) {
+	"foo"
+}`
+
+	tests := []struct {
+		expectedType   token.Type
+		expectedLine   int
+		expectedColumn int
+	}{
+		{token.IDENT, 1, 1},
+		{token.BIND, 1, 3},
+		{token.INT, 1, 6},
+		{token.SEMICOLON, 1, 7},
+		{token.IF, 2, 1},
+		{token.LPAREN, 2, 4},
+		{token.IDENT, 2, 5},
+		{token.GT, 2, 7},
+		{token.INT, 2, 9},
+		{token.RPAREN, 2, 10},
+		{token.LBRACE, 2, 12},
+		{token.STRING, 3, 2},
+		{token.RBRACE, 4, 1},
+		{token.EOF, 4, 2},
+	}
+
+	lexer := NewWithFilename(input, "test.monkey")
+
+	for i, test := range tests {
+		token := lexer.NextToken()
+
+		if token.Type != test.expectedType {
+			t.Fatalf("tests[%d] - token type wrong. expected=%q, got=%q",
+				i, test.expectedType, token.Type)
+		}
+
+		if token.Pos.Filename != "test.monkey" {
+			t.Fatalf("tests[%d] - filename wrong. expected=%q, got=%q",
+				i, "test.monkey", token.Pos.Filename)
+		}
+
+		if token.Pos.Line != test.expectedLine {
+			t.Fatalf("tests[%d] - line wrong. expected=%d, got=%d",
+				i, test.expectedLine, token.Pos.Line)
+		}
+
+		if token.Pos.Column != test.expectedColumn {
+			t.Fatalf("tests[%d] - column wrong. expected=%d, got=%d",
+				i, test.expectedColumn, token.Pos.Column)
+		}
+	}
+}
@@ -0,0 +1,532 @@
+package lsp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/prologic/monkey-lang/ast"
+	"github.com/prologic/monkey-lang/builtins"
+	"github.com/prologic/monkey-lang/compiler"
+	"github.com/prologic/monkey-lang/lexer"
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/parser"
+	"github.com/prologic/monkey-lang/token"
+	"github.com/prologic/monkey-lang/utils"
+)
+
+// kind is the kind of a definition
+type kind int
+
+const (
+	kindVariable kind = iota
+	kindFunction
+	kindModule
+	kindParameter
+)
+
+// definition is a name defined by a binding (`:=`), a function parameter,
+// a for loop variable or the parameter of a catch block
+type definition struct {
+	ident *ast.Identifier
+	kind  kind
+	value ast.Expression // the bound value, nil unless defined by a binding
+
+	// definitions bound in the body of a function, for document symbols
+	children []*definition
+	// end of the body of a function
+	end token.Position
+}
+
+// name returns the defined name
+func (d *definition) name() string {
+	return d.ident.Value
+}
+
+// signature returns the signature of a function, e.g. `add(x, y)` or
+// `add(x: int, y: int) -> int` if annotated, or just the name for other
+// definitions
+func (d *definition) signature() string {
+	fn, ok := d.value.(*ast.FunctionLiteral)
+	if !ok {
+		return d.name()
+	}
+	var params []string
+	for i, param := range fn.Parameters {
+		if i < len(fn.ParameterTypes) && fn.ParameterTypes[i] != nil {
+			params = append(params, param.Value+": "+fn.ParameterTypes[i].String())
+		} else {
+			params = append(params, param.Value)
+		}
+	}
+	signature := fmt.Sprintf("%s(%s)", d.name(), strings.Join(params, ", "))
+	if fn.ReturnType != nil {
+		signature += " -> " + fn.ReturnType.String()
+	}
+	return signature
+}
+
+// module returns the name of the module imported by a definition bound to
+// an import expression
+func (d *definition) module() string {
+	if ie, ok := d.value.(*ast.ImportExpression); ok {
+		if name, ok := ie.Name.(*ast.StringLiteral); ok {
+			return name.Value
+		}
+	}
+	return ""
+}
+
+// scope is a lexical scope, the program or a function body, along with its
+// symbol table
+type scope struct {
+	table  *compiler.SymbolTable
+	parent *scope
+
+	// start and end of the scope, an invalid end is the end of the source
+	start, end token.Position
+
+	defs []*definition
+}
+
+// lookup returns the latest definition of name in the scope
+func (s *scope) lookup(name string) *definition {
+	for i := len(s.defs) - 1; i >= 0; i-- {
+		if s.defs[i].name() == name {
+			return s.defs[i]
+		}
+	}
+	return nil
+}
+
+// contains returns true if pos is within the scope
+func (s *scope) contains(pos token.Position) bool {
+	return !before(pos, s.start) && (!s.end.IsValid() || !before(s.end, pos))
+}
+
+// reference is an identifier referring to a definition or a builtin
+type reference struct {
+	ident   *ast.Identifier
+	def     *definition
+	builtin *object.Builtin
+}
+
+// selector is a selector expression, e.g. `foo.Bar`, whose operand is an
+// identifier
+type selector struct {
+	operand *ast.Identifier
+	name    *ast.StringLiteral
+}
+
+// document is an open text document and the result of analysing it
+type document struct {
+	uri      string
+	filename string
+	text     string
+	lines    []string
+
+	errors []parser.Error
+
+	root    *scope
+	scopes  []*scope
+	symbols []*definition // top level bindings
+	refs    []reference
+	selects []selector
+
+	// position of the closing brace for each opening brace
+	closing map[token.Position]token.Position
+}
+
+// analyze parses the source text of the document at uri and resolves the
+// identifiers it refers to
+func analyze(uri, filename, text string) *document {
+	d := &document{
+		uri:      uri,
+		filename: filename,
+		text:     text,
+		lines:    strings.Split(text, "\n"),
+		closing:  make(map[token.Position]token.Position),
+	}
+
+	d.matchBraces()
+
+	p := parser.New(lexer.NewWithFilename(text, filename))
+	program := p.ParseProgram()
+	d.errors = p.ErrorList()
+
+	table := compiler.NewSymbolTable()
+	for i, builtin := range builtins.BuiltinsIndex {
+		table.DefineBuiltin(i, builtin.Name)
+	}
+	d.root = &scope{table: table}
+	d.scopes = append(d.scopes, d.root)
+
+	for _, statement := range program.Statements {
+		d.walk(statement, d.root, nil)
+	}
+
+	return d
+}
+
+// matchBraces records the position of the matching closing brace of each
+// opening brace in the source
+func (d *document) matchBraces() {
+	var open []token.Position
+
+	l := lexer.NewWithFilename(d.text, d.filename)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.LBRACE:
+			open = append(open, tok.Pos)
+		case token.RBRACE:
+			if len(open) > 0 {
+				d.closing[open[len(open)-1]] = tok.Pos
+				open = open[:len(open)-1]
+			}
+		}
+	}
+}
+
+// walk walks the AST node in scope s, owner is the definition of the
+// function whose body is being walked (nil at the top level)
+func (d *document) walk(node ast.Node, s *scope, owner *definition) {
+	// Nodes are missing (and may be typed nil pointers) if the source did
+	// not parse
+	if node == nil || reflect.ValueOf(node).IsNil() {
+		return
+	}
+
+	switch node := node.(type) {
+	case *ast.ExpressionStatement:
+		d.walk(node.Expression, s, owner)
+	case *ast.ReturnStatement:
+		d.walk(node.ReturnValue, s, owner)
+	case *ast.BlockStatement:
+		for _, statement := range node.Statements {
+			d.walk(statement, s, owner)
+		}
+
+	case *ast.Identifier:
+		d.resolve(node, s)
+
+	case *ast.BindExpression:
+		ident, ok := node.Left.(*ast.Identifier)
+		if !ok {
+			d.walk(node.Left, s, owner)
+			d.walk(node.Value, s, owner)
+			break
+		}
+
+		k := kindVariable
+		switch node.Value.(type) {
+		case *ast.FunctionLiteral:
+			k = kindFunction
+		case *ast.ImportExpression:
+			k = kindModule
+		}
+
+		// The name is bound before the value is evaluated so functions
+		// can refer to themselves
+		def := d.define(ident, k, node.Value, s)
+		if owner != nil {
+			owner.children = append(owner.children, def)
+		} else {
+			d.symbols = append(d.symbols, def)
+		}
+
+		if fn, ok := node.Value.(*ast.FunctionLiteral); ok {
+			d.walkFunction(fn, s, def)
+		} else {
+			d.walk(node.Value, s, owner)
+		}
+
+	case *ast.AssignmentExpression:
+		d.walk(node.Left, s, owner)
+		d.walk(node.Value, s, owner)
+
+	case *ast.FunctionLiteral:
+		d.walkFunction(node, s, owner)
+
+	case *ast.CallExpression:
+		d.walk(node.Function, s, owner)
+		for _, arg := range node.Arguments {
+			d.walk(arg, s, owner)
+		}
+	case *ast.IndexExpression:
+		d.walk(node.Left, s, owner)
+		// A selector expression, e.g. foo.bar, indexes by a string literal
+		// parsed from an identifier
+		if name, ok := node.Index.(*ast.StringLiteral); ok && name.Token.Type == token.IDENT {
+			if operand, ok := node.Left.(*ast.Identifier); ok {
+				d.selects = append(d.selects, selector{operand, name})
+			}
+			break
+		}
+		d.walk(node.Index, s, owner)
+
+	case *ast.PrefixExpression:
+		d.walk(node.Right, s, owner)
+	case *ast.InfixExpression:
+		d.walk(node.Left, s, owner)
+		d.walk(node.Right, s, owner)
+	case *ast.ArrayLiteral:
+		for _, element := range node.Elements {
+			d.walk(element, s, owner)
+		}
+	case *ast.HashLiteral:
+		for _, key := range node.Keys {
+			value := node.Pairs[key]
+			d.walk(key, s, owner)
+			d.walk(value, s, owner)
+		}
+
+	case *ast.IfExpression:
+		d.walk(node.Condition, s, owner)
+		d.walk(node.Consequence, s, owner)
+		d.walk(node.Alternative, s, owner)
+	case *ast.WhileExpression:
+		d.walk(node.Condition, s, owner)
+		d.walk(node.Consequence, s, owner)
+	case *ast.ForExpression:
+		d.walk(node.Iterable, s, owner)
+		if node.Key != nil {
+			d.define(node.Key, kindVariable, nil, s)
+		}
+		if node.Value != nil {
+			d.define(node.Value, kindVariable, nil, s)
+		}
+		d.walk(node.Body, s, owner)
+	case *ast.TryExpression:
+		d.walk(node.Block, s, owner)
+		if node.CatchParam != nil {
+			d.define(node.CatchParam, kindVariable, nil, s)
+		}
+		d.walk(node.Catch, s, owner)
+		d.walk(node.Finally, s, owner)
+	case *ast.ThrowExpression:
+		d.walk(node.Value, s, owner)
+	case *ast.SpawnExpression:
+		d.walk(node.Value, s, owner)
+	case *ast.SelectExpression:
+		for _, sc := range node.Cases {
+			d.walk(sc.Channel, s, owner)
+			d.walk(sc.Value, s, owner)
+			if sc.Name != nil {
+				d.define(sc.Name, kindVariable, nil, s)
+			}
+			d.walk(sc.Body, s, owner)
+		}
+		d.walk(node.Default, s, owner)
+	case *ast.ImportExpression:
+		d.walk(node.Name, s, owner)
+	}
+}
+
+// walkFunction walks the function literal fn in a new scope enclosed by s
+func (d *document) walkFunction(fn *ast.FunctionLiteral, s *scope, owner *definition) {
+	inner := &scope{
+		table:  compiler.NewEnclosedSymbolTable(s.table),
+		parent: s,
+		start:  fn.Token.Pos,
+	}
+	if fn.Body != nil {
+		inner.end = d.closing[fn.Body.Token.Pos]
+	}
+	if owner != nil && owner.value == fn {
+		owner.end = inner.end
+	}
+	d.scopes = append(d.scopes, inner)
+
+	for _, param := range fn.Parameters {
+		d.define(param, kindParameter, nil, inner)
+	}
+	d.walk(fn.Body, inner, owner)
+}
+
+// define defines the identifier in scope s
+func (d *document) define(ident *ast.Identifier, k kind, value ast.Expression, s *scope) *definition {
+	s.table.Define(ident.Value)
+
+	def := &definition{ident: ident, kind: k, value: value}
+	s.defs = append(s.defs, def)
+	d.refs = append(d.refs, reference{ident: ident, def: def})
+	return def
+}
+
+// resolve resolves the identifier in scope s to its definition or builtin
+func (d *document) resolve(ident *ast.Identifier, s *scope) {
+	symbol, ok := s.table.Resolve(ident.Value)
+	if !ok {
+		return
+	}
+
+	if symbol.Scope == compiler.BuiltinScope {
+		d.refs = append(d.refs, reference{ident: ident, builtin: builtins.BuiltinsIndex[symbol.Index]})
+		return
+	}
+
+	for ; s != nil; s = s.parent {
+		if def := s.lookup(ident.Value); def != nil {
+			d.refs = append(d.refs, reference{ident: ident, def: def})
+			return
+		}
+	}
+}
+
+// referenceAt returns the reference at pos
+func (d *document) referenceAt(pos token.Position) (reference, bool) {
+	for _, ref := range d.refs {
+		if at(ref.ident.Token.Pos, ref.ident.Value, pos) {
+			return ref, true
+		}
+	}
+	return reference{}, false
+}
+
+// selectorAt returns the selector whose name is at pos
+func (d *document) selectorAt(pos token.Position) (selector, bool) {
+	for _, sel := range d.selects {
+		if at(sel.name.Token.Pos, sel.name.Value, pos) {
+			return sel, true
+		}
+	}
+	return selector{}, false
+}
+
+// definitionOf returns the definition the identifier refers to
+func (d *document) definitionOf(ident *ast.Identifier) *definition {
+	for _, ref := range d.refs {
+		if ref.ident == ident {
+			return ref.def
+		}
+	}
+	return nil
+}
+
+// visible returns the definitions visible at pos, inner most first
+func (d *document) visible(pos token.Position) []*definition {
+	var inner *scope
+	for _, s := range d.scopes {
+		if s.contains(pos) && (inner == nil || before(inner.start, s.start)) {
+			inner = s
+		}
+	}
+
+	var defs []*definition
+	seen := make(map[string]bool)
+	for s := inner; s != nil; s = s.parent {
+		for i := len(s.defs) - 1; i >= 0; i-- {
+			def := s.defs[i]
+			if seen[def.name()] || !before(def.ident.Token.Pos, pos) {
+				continue
+			}
+			seen[def.name()] = true
+			defs = append(defs, def)
+		}
+	}
+	return defs
+}
+
+// exports returns the exported definitions, those bound at the top level
+// whose names start with an upper case letter, ordered by name
+func (d *document) exports() []*definition {
+	var defs []*definition
+	for _, name := range d.rootNames() {
+		if unicode.IsUpper([]rune(name)[0]) {
+			defs = append(defs, d.root.lookup(name))
+		}
+	}
+	return defs
+}
+
+// rootNames returns the names defined at the top level in order
+func (d *document) rootNames() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, def := range d.root.defs {
+		if !seen[def.name()] {
+			seen[def.name()] = true
+			names = append(names, def.name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// importModule finds, reads and analyzes the module name imported by the
+// document looking in the document's directory first followed by the
+// module search paths
+func (d *document) importModule(name string) (*document, error) {
+	filename := filepath.Join(filepath.Dir(d.filename), name+".monkey")
+	if !utils.Exists(filename) {
+		filename = utils.FindModule(name)
+	}
+	if filename == "" {
+		return nil, fmt.Errorf("no module named '%s'", name)
+	}
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return analyze(fileURI(filename), filename, string(b)), nil
+}
+
+// line returns the source line n (starting at 0)
+func (d *document) line(n int) string {
+	if n < 0 || n >= len(d.lines) {
+		return ""
+	}
+	return d.lines[n]
+}
+
+// position converts a source position to a protocol position
+func (d *document) position(pos token.Position) position {
+	if !pos.IsValid() {
+		return position{}
+	}
+
+	text := d.line(pos.Line - 1)
+	column := pos.Column - 1
+	if column > len(text) {
+		column = len(text)
+	}
+	return position{pos.Line - 1, len(utf16.Encode([]rune(text[:column])))}
+}
+
+// sourcePos converts a protocol position to a source position
+func (d *document) sourcePos(p position) token.Position {
+	text := d.line(p.Line)
+
+	column, units := 0, 0
+	for column < len(text) && units < p.Character {
+		r, size := utf8.DecodeRuneInString(text[column:])
+		column += size
+		units += len(utf16.Encode([]rune{r}))
+	}
+	return token.Position{Filename: d.filename, Line: p.Line + 1, Column: column + 1}
+}
+
+// span returns the range of the text starting at pos
+func (d *document) span(pos token.Position, text string) textRange {
+	end := pos
+	end.Column += len(text)
+	return textRange{d.position(pos), d.position(end)}
+}
+
+// at returns true if pos is within the text starting at start
+func at(start token.Position, text string, pos token.Position) bool {
+	return pos.Line == start.Line &&
+		pos.Column >= start.Column && pos.Column <= start.Column+len(text)
+}
+
+// before returns true if position a is before b
+func before(a, b token.Position) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+}
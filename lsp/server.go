@@ -0,0 +1,447 @@
+// Package lsp implements a Language Server Protocol server which provides
+// editors and IDEs with diagnostics, go to definition, hover information,
+// completion and document symbols for Monkey source files. Documents are
+// analysed with the lexer and parser and names are resolved through the
+// scopes of the compiler's symbol tables.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/prologic/monkey-lang/builtins"
+	"github.com/prologic/monkey-lang/internal/framing"
+)
+
+// Name is the name the server identifies itself by and the source of its
+// diagnostics
+const Name = "monkey-lang"
+
+var (
+	// selectorPrefix matches a partial selector expression, e.g. `foo.ba`,
+	// at the end of a line
+	selectorPrefix = regexp.MustCompile(`([A-Za-z_][A-Za-z0-9_]*)\.([A-Za-z0-9_]*)$`)
+	// identPrefix matches a partial identifier at the end of a line
+	identPrefix = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Server is a Language Server Protocol server reading requests and
+// notifications from in and writing responses and notifications to out
+type Server struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	documents map[string]*document
+	shutdown  bool
+}
+
+// NewServer returns a new server reading requests and notifications from in
+// and writing responses and notifications to out
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:        bufio.NewReader(in),
+		out:       out,
+		documents: make(map[string]*document),
+	}
+}
+
+// Serve handles requests and notifications until the client sends the exit
+// notification or closes the input
+func (s *Server) Serve() error {
+	for {
+		content, err := framing.ReadMessage(s.in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var msg message
+		if err := json.Unmarshal(content, &msg); err != nil {
+			s.send(&response{
+				JSONRPC: "2.0",
+				Error:   &responseError{codeParseError, err.Error()},
+			})
+			continue
+		}
+
+		if msg.Method == "exit" {
+			return nil
+		}
+
+		result, err := s.handle(&msg)
+		if msg.ID == nil {
+			// Notifications have no response
+			continue
+		}
+
+		res := &response{JSONRPC: "2.0", ID: msg.ID}
+		if err == nil {
+			if res.Result, err = json.Marshal(result); err != nil {
+				err = &responseError{codeInternalError, err.Error()}
+			}
+		}
+		if err != nil {
+			code := codeInvalidParams
+			if e, ok := err.(*responseError); ok {
+				code = e.Code
+			}
+			res.Result = nil
+			res.Error = &responseError{code, err.Error()}
+		}
+		s.send(res)
+	}
+}
+
+// handle handles the request or notification msg and returns its result
+func (s *Server) handle(msg *message) (interface{}, error) {
+	if s.shutdown && msg.ID != nil {
+		return nil, &responseError{codeInvalidRequest, "server is shut down"}
+	}
+
+	switch msg.Method {
+	case "initialize":
+		return s.initialize()
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params didOpenTextDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		s.update(params.TextDocument.URI, params.TextDocument.Text)
+		return nil, nil
+	case "textDocument/didChange":
+		var params didChangeTextDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		// Documents are synced in full so the last change is the new text
+		if n := len(params.ContentChanges); n > 0 {
+			s.update(params.TextDocument.URI, params.ContentChanges[n-1].Text)
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var params didCloseTextDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		delete(s.documents, params.TextDocument.URI)
+		s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: []diagnostic{},
+		})
+		return nil, nil
+
+	case "textDocument/definition":
+		return s.positionRequest(msg, s.definition)
+	case "textDocument/hover":
+		return s.positionRequest(msg, s.hover)
+	case "textDocument/completion":
+		return s.positionRequest(msg, s.completion)
+	case "textDocument/documentSymbol":
+		var params documentSymbolParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		d, err := s.document(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return s.documentSymbols(d), nil
+	}
+
+	if msg.ID == nil {
+		// Unknown notifications are ignored
+		return nil, nil
+	}
+	return nil, &responseError{codeMethodNotFound, fmt.Sprintf("method not found: %s", msg.Method)}
+}
+
+func (s *Server) initialize() (interface{}, error) {
+	return initializeResult{
+		Capabilities: serverCapabilities{
+			TextDocumentSync:   textDocumentSyncFull,
+			DefinitionProvider: true,
+			HoverProvider:      true,
+			CompletionProvider: &completionOptions{
+				TriggerCharacters: []string{"."},
+			},
+			DocumentSymbolProvider: true,
+		},
+		ServerInfo: serverInfo{Name},
+	}, nil
+}
+
+// update analyzes the new text of the document at uri and publishes its
+// diagnostics
+func (s *Server) update(uri, text string) {
+	d := analyze(uri, filename(uri), text)
+	s.documents[uri] = d
+
+	diagnostics := []diagnostic{}
+	for _, err := range d.errors {
+		start := d.position(err.Pos)
+		end := start
+		if start.Character < len(d.line(start.Line)) {
+			end.Character++
+		}
+		diagnostics = append(diagnostics, diagnostic{
+			Range:    textRange{start, end},
+			Severity: diagnosticSeverityError,
+			Source:   Name,
+			Message:  err.Message,
+		})
+	}
+
+	s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: diagnostics,
+	})
+}
+
+// positionRequest decodes the parameters of a request for a position in a
+// document and handles it with fn
+func (s *Server) positionRequest(msg *message, fn func(d *document, p position) (interface{}, error)) (interface{}, error) {
+	var params textDocumentPositionParams
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		return nil, err
+	}
+	d, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return fn(d, params.Position)
+}
+
+// definition returns the location of the definition of the identifier at
+// p, which may be in an imported module
+func (s *Server) definition(d *document, p position) (interface{}, error) {
+	pos := d.sourcePos(p)
+
+	if ref, ok := d.referenceAt(pos); ok && ref.def != nil {
+		return location{d.uri, d.span(ref.def.ident.Token.Pos, ref.def.name())}, nil
+	}
+
+	if sel, ok := d.selectorAt(pos); ok {
+		if module, def := s.member(d, sel); def != nil {
+			return location{module.uri, module.span(def.ident.Token.Pos, def.name())}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// hover returns information about the identifier at p
+func (s *Server) hover(d *document, p position) (interface{}, error) {
+	pos := d.sourcePos(p)
+
+	var (
+		text string
+		span textRange
+	)
+	if ref, ok := d.referenceAt(pos); ok {
+		span = d.span(ref.ident.Token.Pos, ref.ident.Value)
+		if ref.builtin != nil {
+			text = codeBlock(ref.builtin.Signature) + "\nbuiltin function"
+		} else {
+			text = describe(ref.def)
+		}
+	} else if sel, ok := d.selectorAt(pos); ok {
+		if _, def := s.member(d, sel); def != nil {
+			span = d.span(sel.name.Token.Pos, sel.name.Value)
+			text = describe(def) + fmt.Sprintf("\nexported by module %s", d.definitionOf(sel.operand).module())
+		}
+	}
+
+	if text == "" {
+		return nil, nil
+	}
+	return hover{markupContent{"markdown", text}, &span}, nil
+}
+
+// completion returns the identifiers visible at p, including builtins, or
+// the names exported by a module following a selector
+func (s *Server) completion(d *document, p position) (interface{}, error) {
+	pos := d.sourcePos(p)
+	text := d.line(p.Line)[:pos.Column-1]
+
+	items := []completionItem{}
+
+	if m := selectorPrefix.FindStringSubmatch(text); m != nil {
+		operand, prefix := m[1], m[2]
+		for _, def := range d.visible(pos) {
+			if def.name() != operand || def.module() == "" {
+				continue
+			}
+			module, err := d.importModule(def.module())
+			if err != nil {
+				break
+			}
+			for _, export := range module.exports() {
+				if strings.HasPrefix(export.name(), prefix) {
+					items = append(items, completion(export))
+				}
+			}
+			break
+		}
+		return completionList{Items: items}, nil
+	}
+
+	prefix := identPrefix.FindString(text)
+
+	seen := make(map[string]bool)
+	for _, def := range d.visible(pos) {
+		seen[def.name()] = true
+		if strings.HasPrefix(def.name(), prefix) {
+			items = append(items, completion(def))
+		}
+	}
+	for _, builtin := range builtins.BuiltinsIndex {
+		if !seen[builtin.Name] && strings.HasPrefix(builtin.Name, prefix) {
+			items = append(items, completionItem{
+				Label:  builtin.Name,
+				Kind:   completionKindFunction,
+				Detail: builtin.Signature,
+			})
+		}
+	}
+
+	return completionList{Items: items}, nil
+}
+
+// documentSymbols returns the bindings of the document, those in the body
+// of a function being children of the function
+func (s *Server) documentSymbols(d *document) []documentSymbol {
+	var symbols func(defs []*definition) []documentSymbol
+	symbols = func(defs []*definition) []documentSymbol {
+		result := []documentSymbol{}
+		for _, def := range defs {
+			symbol := documentSymbol{
+				Name:           def.name(),
+				Kind:           symbolKindVariable,
+				Range:          d.span(def.ident.Token.Pos, def.name()),
+				SelectionRange: d.span(def.ident.Token.Pos, def.name()),
+			}
+			switch def.kind {
+			case kindFunction:
+				symbol.Kind = symbolKindFunction
+				symbol.Detail = def.signature()
+				if def.end.IsValid() {
+					symbol.Range.End = d.span(def.end, "}").End
+				}
+				symbol.Children = symbols(def.children)
+			case kindModule:
+				symbol.Kind = symbolKindModule
+			}
+			result = append(result, symbol)
+		}
+		sort.SliceStable(result, func(i, j int) bool {
+			a, b := result[i].SelectionRange.Start, result[j].SelectionRange.Start
+			return a.Line < b.Line || a.Line == b.Line && a.Character < b.Character
+		})
+		return result
+	}
+	return symbols(d.symbols)
+}
+
+// member returns the module and definition of the exported name selected
+// from an imported module by the selector
+func (s *Server) member(d *document, sel selector) (*document, *definition) {
+	def := d.definitionOf(sel.operand)
+	if def == nil || def.module() == "" {
+		return nil, nil
+	}
+
+	module, err := d.importModule(def.module())
+	if err != nil {
+		return nil, nil
+	}
+	for _, export := range module.exports() {
+		if export.name() == sel.name.Value {
+			return module, export
+		}
+	}
+	return nil, nil
+}
+
+// document returns the open document at uri
+func (s *Server) document(uri string) (*document, error) {
+	d, ok := s.documents[uri]
+	if !ok {
+		return nil, fmt.Errorf("unknown document %s", uri)
+	}
+	return d, nil
+}
+
+// notify sends a notification to the client
+func (s *Server) notify(method string, params interface{}) {
+	s.send(&notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// send sends the message to the client
+func (s *Server) send(v interface{}) {
+	// There is no one to report a failure to write to the client to
+	framing.WriteMessage(s.out, v)
+}
+
+// describe returns a markdown description of the definition
+func describe(def *definition) string {
+	switch def.kind {
+	case kindFunction:
+		return codeBlock(def.signature()) + "\nfunction"
+	case kindModule:
+		return codeBlock(fmt.Sprintf("%s := import(%q)", def.name(), def.module())) + "\nmodule"
+	case kindParameter:
+		return codeBlock(def.name()) + "\nparameter"
+	default:
+		return codeBlock(def.name()) + "\nvariable"
+	}
+}
+
+// completion returns the completion item of the definition
+func completion(def *definition) completionItem {
+	item := completionItem{Label: def.name(), Kind: completionKindVariable}
+	switch def.kind {
+	case kindFunction:
+		item.Kind = completionKindFunction
+		item.Detail = def.signature()
+	case kindModule:
+		item.Kind = completionKindModule
+	}
+	return item
+}
+
+// codeBlock returns the code formatted as a markdown code block
+func codeBlock(code string) string {
+	return "```monkey\n" + code + "\n```"
+}
+
+// filename returns the filename of a file:// uri or the uri itself
+func filename(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// fileURI returns the file:// uri of the filename
+func fileURI(filename string) string {
+	if abs, err := filepath.Abs(filename); err == nil {
+		filename = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(filename)}).String()
+}
@@ -0,0 +1,290 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prologic/monkey-lang/internal/framing"
+)
+
+const program = `math := import("mathlib")
+add := fn(x, y) {
+  z := x + y
+  return z
+}
+n := add(1, 2)
+print(math.Sq(n))`
+
+const module = `Sq := fn(x) { return x * x }
+helper := 1`
+
+// received is a response or notification received by the client
+type received struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *responseError  `json:"error"`
+}
+
+// client is a client driving a server in a test
+type client struct {
+	t        *testing.T
+	in       io.WriteCloser
+	messages chan received
+	id       int
+}
+
+func newClient(t *testing.T) *client {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	c := &client{t: t, in: clientOut, messages: make(chan received, 100)}
+
+	go func() {
+		assert.NoError(t, NewServer(serverIn, serverOut).Serve())
+		serverOut.Close()
+	}()
+
+	go func() {
+		defer close(c.messages)
+		r := bufio.NewReader(clientIn)
+		for {
+			content, err := framing.ReadMessage(r)
+			if err != nil {
+				return
+			}
+			var msg received
+			if err := json.Unmarshal(content, &msg); err != nil {
+				return
+			}
+			c.messages <- msg
+		}
+	}()
+
+	return c
+}
+
+// next returns the next message that satisfies match
+func (c *client) next(match func(msg received) bool) received {
+	for {
+		select {
+		case msg, ok := <-c.messages:
+			require.True(c.t, ok, "connection closed")
+			if match(msg) {
+				return msg
+			}
+		case <-time.After(5 * time.Second):
+			require.FailNow(c.t, "timed out waiting for message")
+		}
+	}
+}
+
+// notify sends a notification
+func (c *client) notify(method string, params interface{}) {
+	msg := map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+	require.NoError(c.t, framing.WriteMessage(c.in, msg))
+}
+
+// call sends a request and decodes the result of its response into result
+func (c *client) call(method string, params, result interface{}) {
+	c.id++
+	id := c.id
+	msg := map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": params}
+	require.NoError(c.t, framing.WriteMessage(c.in, msg))
+
+	res := c.next(func(msg received) bool {
+		return msg.ID != nil && *msg.ID == id
+	})
+	require.Nil(c.t, res.Error, "%s failed", method)
+	require.NotNil(c.t, res.Result, "%s has no result", method)
+	if result != nil {
+		require.NoError(c.t, json.Unmarshal(res.Result, result))
+	}
+}
+
+// diagnostics waits for the diagnostics of a document to be published
+func (c *client) diagnostics() []diagnostic {
+	msg := c.next(func(msg received) bool {
+		return msg.Method == "textDocument/publishDiagnostics"
+	})
+	var params publishDiagnosticsParams
+	require.NoError(c.t, json.Unmarshal(msg.Params, &params))
+	return params.Diagnostics
+}
+
+// open opens the program in a temporary directory alongside the module it
+// imports and returns the program's uri
+func (c *client) open(text string) string {
+	dir := c.t.TempDir()
+	path := filepath.Join(dir, "test.monkey")
+	require.NoError(c.t, ioutil.WriteFile(filepath.Join(dir, "mathlib.monkey"), []byte(module), 0644))
+
+	var result initializeResult
+	c.call("initialize", map[string]interface{}{}, &result)
+	assert.True(c.t, result.Capabilities.HoverProvider)
+	c.notify("initialized", map[string]interface{}{})
+
+	uri := fileURI(path)
+	c.notify("textDocument/didOpen", didOpenTextDocumentParams{
+		textDocumentItem{URI: uri, LanguageID: "monkey", Version: 1, Text: text},
+	})
+	return uri
+}
+
+func (c *client) change(uri, text string) {
+	c.notify("textDocument/didChange", didChangeTextDocumentParams{
+		textDocumentIdentifier{uri}, []textDocumentContentChangeEvent{{text}},
+	})
+}
+
+func positionAt(uri string, line, character int) textDocumentPositionParams {
+	return textDocumentPositionParams{textDocumentIdentifier{uri}, position{line, character}}
+}
+
+func span(line, start, end int) textRange {
+	return textRange{position{line, start}, position{line, end}}
+}
+
+func TestDiagnostics(t *testing.T) {
+	c := newClient(t)
+	uri := c.open(program)
+	assert.Empty(t, c.diagnostics())
+
+	c.change(uri, "x := 1\ny := (x + ")
+	diagnostics := c.diagnostics()
+	require.NotEmpty(t, diagnostics)
+	assert.Equal(t, 1, diagnostics[0].Range.Start.Line)
+	assert.Equal(t, diagnosticSeverityError, diagnostics[0].Severity)
+	assert.Equal(t, Name, diagnostics[0].Source)
+
+	c.change(uri, program)
+	assert.Empty(t, c.diagnostics())
+
+	c.notify("textDocument/didClose", didCloseTextDocumentParams{textDocumentIdentifier{uri}})
+	assert.Empty(t, c.diagnostics())
+
+	c.call("shutdown", nil, nil)
+	c.notify("exit", nil)
+}
+
+func TestDefinition(t *testing.T) {
+	c := newClient(t)
+	uri := c.open(program)
+
+	tests := []struct {
+		line, character int
+		expected        *location
+	}{
+		// add in add(1, 2)
+		{5, 6, &location{uri, span(1, 0, 3)}},
+		// x in x + y refers to the parameter
+		{2, 7, &location{uri, span(1, 10, 11)}},
+		// z in return z
+		{3, 10, &location{uri, span(2, 2, 3)}},
+		// math.Sq refers to the module's definition
+		{6, 12, &location{fileURI(filepath.Join(filepath.Dir(filename(uri)), "mathlib.monkey")), span(0, 0, 2)}},
+		// print is a builtin and has no definition
+		{6, 2, nil},
+	}
+
+	for _, tt := range tests {
+		var result *location
+		c.call("textDocument/definition", positionAt(uri, tt.line, tt.character), &result)
+		assert.Equal(t, tt.expected, result, "at %d:%d", tt.line, tt.character)
+	}
+}
+
+func TestHover(t *testing.T) {
+	c := newClient(t)
+	uri := c.open(program)
+
+	tests := []struct {
+		line, character int
+		expected        string
+	}{
+		{6, 2, "```monkey\nprint(value...)\n```\nbuiltin function"},
+		{5, 6, "```monkey\nadd(x, y)\n```\nfunction"},
+		{2, 11, "```monkey\ny\n```\nparameter"},
+		{0, 1, "```monkey\nmath := import(\"mathlib\")\n```\nmodule"},
+		{6, 12, "```monkey\nSq(x)\n```\nfunction\nexported by module mathlib"},
+	}
+
+	for _, tt := range tests {
+		var result hover
+		c.call("textDocument/hover", positionAt(uri, tt.line, tt.character), &result)
+		assert.Equal(t, tt.expected, result.Contents.Value, "at %d:%d", tt.line, tt.character)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	c := newClient(t)
+	uri := c.open(program)
+
+	labels := func(list completionList) map[string]completionItem {
+		items := make(map[string]completionItem)
+		for _, item := range list.Items {
+			items[item.Label] = item
+		}
+		return items
+	}
+
+	// In the body of add after `return `
+	var list completionList
+	c.call("textDocument/completion", positionAt(uri, 3, 9), &list)
+	items := labels(list)
+	for _, label := range []string{"x", "y", "z", "add", "math", "len", "print"} {
+		assert.Contains(t, items, label)
+	}
+	assert.NotContains(t, items, "n", "n is defined after the cursor")
+	assert.Equal(t, "add(x, y)", items["add"].Detail)
+	assert.Equal(t, "len(iterable)", items["len"].Detail)
+
+	c.change(uri, program+"\nm := pr")
+	c.call("textDocument/completion", positionAt(uri, 7, 7), &list)
+	assert.Equal(t, []completionItem{{Label: "print", Kind: completionKindFunction, Detail: "print(value...)"}}, list.Items)
+
+	c.change(uri, program+"\nmath.")
+	c.call("textDocument/completion", positionAt(uri, 7, 5), &list)
+	assert.Equal(t, []completionItem{{Label: "Sq", Kind: completionKindFunction, Detail: "Sq(x)"}}, list.Items)
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	c := newClient(t)
+	uri := c.open(program)
+
+	var symbols []documentSymbol
+	c.call("textDocument/documentSymbol", documentSymbolParams{textDocumentIdentifier{uri}}, &symbols)
+
+	assert.Equal(t, []documentSymbol{
+		{Name: "math", Kind: symbolKindModule, Range: span(0, 0, 4), SelectionRange: span(0, 0, 4)},
+		{
+			Name: "add", Detail: "add(x, y)", Kind: symbolKindFunction,
+			Range:          textRange{position{1, 0}, position{4, 1}},
+			SelectionRange: span(1, 0, 3),
+			Children: []documentSymbol{
+				{Name: "z", Kind: symbolKindVariable, Range: span(2, 2, 3), SelectionRange: span(2, 2, 3)},
+			},
+		},
+		{Name: "n", Kind: symbolKindVariable, Range: span(5, 0, 1), SelectionRange: span(5, 0, 1)},
+	}, symbols)
+}
+
+func TestMethodNotFound(t *testing.T) {
+	c := newClient(t)
+	c.open(program)
+
+	require.NoError(t, framing.WriteMessage(c.in, map[string]interface{}{"jsonrpc": "2.0", "id": 99, "method": "nope"}))
+	res := c.next(func(msg received) bool { return msg.ID != nil && *msg.ID == 99 })
+	require.NotNil(t, res.Error)
+	assert.Equal(t, codeMethodNotFound, res.Error.Code)
+	assert.Nil(t, res.Result, "error responses have no result")
+}
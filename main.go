@@ -12,33 +12,67 @@ import (
 	"os"
 	"os/user"
 	"path"
+	"path/filepath"
 	"strings"
 
 	"github.com/prologic/monkey-lang/compiler"
+	"github.com/prologic/monkey-lang/dap"
 	"github.com/prologic/monkey-lang/lexer"
+	"github.com/prologic/monkey-lang/lsp"
 	"github.com/prologic/monkey-lang/object"
 	"github.com/prologic/monkey-lang/parser"
 	"github.com/prologic/monkey-lang/repl"
+	"github.com/prologic/monkey-lang/vm"
 )
 
 var (
 	engine      string
 	interactive bool
 	compile     bool
+	output      string
 	version     bool
 	debug       bool
+	debugging   bool
+	optimize    bool
+	stackSize   int
+	maxFrames   int
+	sandbox     bool
+	allow       string
 )
 
 func init() {
 	flag.Usage = func() {
-		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] [<filename>]\n", path.Base(os.Args[0]))
+		name := path.Base(os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] [<filename>]\n", name)
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s dap|lsp\n", name)
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s check [<path> ...]\n", name)
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s fmt [-l] [-w] [<path> ...]\n", name)
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s test [-run regexp] [<path> ...]\n", name)
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s vet [<path> ...]\n\n", name)
+		fmt.Fprint(flag.CommandLine.Output(), "Commands:\n")
+		fmt.Fprint(flag.CommandLine.Output(), "  check\tcheck the types of source files, or the standard input\n")
+		fmt.Fprint(flag.CommandLine.Output(), "  dap\tserve the Debug Adapter Protocol over stdin and stdout\n")
+		fmt.Fprint(flag.CommandLine.Output(), "  fmt\tformat source files, or the standard input, in the canonical style\n")
+		fmt.Fprint(flag.CommandLine.Output(), "  lsp\tserve the Language Server Protocol over stdin and stdout\n")
+		fmt.Fprint(flag.CommandLine.Output(), "  test\trun the tests in *_test.monkey files\n")
+		fmt.Fprint(flag.CommandLine.Output(), "  vet\treport suspicious constructs in source files, or the standard input\n\n")
+		fmt.Fprint(flag.CommandLine.Output(), "Options:\n")
 		flag.PrintDefaults()
 		os.Exit(0)
 	}
 
 	flag.BoolVar(&version, "v", false, "display version information")
 	flag.BoolVar(&debug, "d", false, "enable debug mode")
-	flag.BoolVar(&compile, "c", false, "compile input to bytecode")
+	flag.BoolVar(&debugging, "debug", false, "run the program in the interactive step debugger")
+	flag.BoolVar(&compile, "c", false, "compile input to a bytecode (.mbc) file")
+	flag.StringVar(&output, "o", "", "output filename of compiled bytecode (default <filename>.mbc)")
+	flag.BoolVar(&optimize, "O", false, "optimize the compiled bytecode")
+
+	flag.IntVar(&stackSize, "stack-size", vm.StackSize, "maximum number of values on the VM's stack")
+	flag.IntVar(&maxFrames, "max-frames", vm.MaxFrames, "maximum depth of calls in the VM")
+
+	flag.BoolVar(&sandbox, "sandbox", false, "run the program in a sandbox with only the builtins allowed by -allow")
+	flag.StringVar(&allow, "allow", "", "comma separated groups of builtins allowed in the sandbox (filesystem, network, ffi, process)")
 
 	flag.BoolVar(&interactive, "i", false, "enable interactive mode")
 	flag.StringVar(&engine, "e", "vm", "engine to use (eval or vm)")
@@ -68,13 +102,36 @@ func main() {
 		os.Exit(0)
 	}
 
+	args := flag.Args()
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "check":
+			os.Exit(checkFiles(args[1:]))
+		case "dap":
+			if err := dap.NewServer(os.Stdin, os.Stdout).Serve(); err != nil {
+				log.Fatal(err)
+			}
+			return
+		case "fmt":
+			os.Exit(formatFiles(args[1:]))
+		case "lsp":
+			if err := lsp.NewServer(os.Stdin, os.Stdout).Serve(); err != nil {
+				log.Fatal(err)
+			}
+			return
+		case "test":
+			os.Exit(testFiles(args[1:]))
+		case "vet":
+			os.Exit(vetFiles(args[1:]))
+		}
+	}
+
 	user, err := user.Current()
 	if err != nil {
 		log.Fatalf("could not determine current user: %s", err)
 	}
 
-	args := flag.Args()
-
 	if compile {
 		if len(args) < 1 {
 			log.Fatal("no source file given to compile")
@@ -90,7 +147,7 @@ func main() {
 			log.Fatal(err)
 		}
 
-		l := lexer.New(string(b))
+		l := lexer.NewWithFilename(string(b), args[0])
 		p := parser.New(l)
 
 		program := p.ParseProgram()
@@ -99,26 +156,60 @@ func main() {
 		}
 
 		c := compiler.New()
+		c.Optimize = optimize
 		err = c.Compile(program)
 		if err != nil {
 			log.Fatal(err)
 		}
 
 		code := c.Bytecode()
-		fmt.Printf("Main:\n%s\n", code.Instructions)
 
-		fmt.Print("Constants:\n")
-		for i, constant := range code.Constants {
-			fmt.Printf("%04d %s\n", i, constant.Inspect())
-			if fn, ok := constant.(*object.CompiledFunction); ok {
-				fmt.Printf("%s\n", Indent(fn.Instructions.String(), "     "))
+		if debug {
+			fmt.Printf("Main:\n%s\n", code.Instructions)
+
+			fmt.Print("Constants:\n")
+			for i, constant := range code.Constants {
+				fmt.Printf("%04d %s\n", i, constant.Inspect())
+				if fn, ok := constant.(*object.CompiledFunction); ok {
+					fmt.Printf("%s\n", Indent(fn.Instructions.String(), "     "))
+				}
 			}
 		}
+
+		if output == "" {
+			output = strings.TrimSuffix(args[0], filepath.Ext(args[0])) +
+				compiler.BytecodeExtension
+		}
+
+		out, err := os.Create(output)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err := code.Encode(out); err != nil {
+			out.Close()
+			log.Fatalf("error writing bytecode to %s: %s", output, err)
+		}
+		if err := out.Close(); err != nil {
+			log.Fatalf("error writing bytecode to %s: %s", output, err)
+		}
 	} else {
+		capabilities, err := object.ParseCapabilities(allow)
+		if err != nil {
+			log.Fatalf("invalid -allow: %s", err)
+		}
+
 		opts := &repl.Options{
 			Debug:       debug,
+			Debugger:    debugging,
 			Engine:      engine,
 			Interactive: interactive,
+			Optimize:    optimize,
+			StackSize:   stackSize,
+			MaxFrames:   maxFrames,
+
+			Sandbox:      sandbox,
+			Capabilities: capabilities,
 		}
 		repl := repl.New(user.Username, args, opts)
 		repl.Run()
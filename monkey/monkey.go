@@ -0,0 +1,268 @@
+// Package monkey is the API used to embed the Monkey programming language in
+// Go programs. An Interpreter compiles and runs Monkey source code on the
+// virtual machine, has its own globals, builtins and standard input and
+// output, and functions can be called across the two languages:
+//
+//	i := monkey.New()
+//	i.Stdout = &buf
+//	i.Register("greet", func(ctx *object.Context, args ...object.Object) object.Object {
+//		return &object.String{Value: "Hello " + args[0].String()}
+//	})
+//	if _, err := i.Run(`print(greet("World"))`); err != nil {
+//		...
+//	}
+package monkey
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/prologic/monkey-lang/compiler"
+	"github.com/prologic/monkey-lang/lexer"
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/parser"
+	"github.com/prologic/monkey-lang/vm"
+)
+
+// ExitError is returned when a program exits with a non-zero status
+type ExitError struct {
+	Status int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Status)
+}
+
+// Interpreter runs Monkey programs on the virtual machine. Every program run
+// by an interpreter shares its globals, so values bound by one program can
+// be used by those run after it. An Interpreter must not be used by more
+// than one goroutine at a time but separate interpreters are independent.
+type Interpreter struct {
+	// Stdin and Stdout are read by `input()` and written to by `print()`
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Args are the arguments returned by `args()`
+	Args []string
+
+	// Env is the environment as a list of "key=value" strings
+	Env []string
+
+	// Optimize enables the bytecode optimizer
+	Optimize bool
+
+	// Options are the limits of the virtual machine
+	Options vm.Options
+
+	// Sandbox restricts the builtins available to programs to the groups
+	// given by Capabilities, other builtins raise a PermissionError when
+	// called. Builtins registered by Register are always available.
+	Sandbox      bool
+	Capabilities object.Capability
+
+	state *vm.VMState
+	ctx   *object.Context
+
+	// exited is true if the program being run called `exit()` with status,
+	// they are set by the task calling `exit()` and guarded by mu
+	mu     sync.Mutex
+	exited bool
+	status int
+}
+
+// New returns a new interpreter using the process's standard input, output
+// and error and environment with no arguments
+func New() *Interpreter {
+	i := &Interpreter{
+		Options: vm.DefaultOptions,
+		state:   vm.NewVMState(),
+	}
+	i.ctx = i.newContext()
+	i.Stdin, i.Stdout, i.Stderr = i.ctx.Stdin, i.ctx.Stdout, i.ctx.Stderr
+	i.Env = i.ctx.Env
+
+	return i
+}
+
+// newContext returns a new context for the programs run by the interpreter.
+// exit() stops the program, waking the tasks it spawned, and exits the
+// goroutine of the task calling it.
+func (i *Interpreter) newContext() *object.Context {
+	ctx := object.NewContext()
+	ctx.Exit = func(status int) {
+		i.mu.Lock()
+		i.exited, i.status = true, status
+		i.mu.Unlock()
+
+		ctx.Stop()
+		runtime.Goexit()
+	}
+	return ctx
+}
+
+// Compile parses and compiles the source code src, name is the name of the
+// file used to report the position of errors (if any)
+func (i *Interpreter) Compile(name, src string) (*compiler.Bytecode, error) {
+	p := parser.New(lexer.NewWithFilename(src, name))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("parser errors:\n\t%s", strings.Join(p.Errors(), "\n\t"))
+	}
+
+	c := compiler.NewWithState(i.state.Symbols, i.state.Constants)
+	c.Optimize = i.Optimize
+	if err := c.Compile(program); err != nil {
+		return nil, err
+	}
+
+	code := c.Bytecode()
+	i.state.Constants = code.Constants
+	return code, nil
+}
+
+// Exec executes the bytecode compiled by Compile and returns the value of
+// the last expression statement. Runtime errors are returned as a *vm.Error
+// and an *ExitError is returned if the program exits with a non-zero status.
+func (i *Interpreter) Exec(code *compiler.Bytecode) (object.Object, error) {
+	return i.exec(vm.NewWithState(code, i.state))
+}
+
+// Run compiles and executes the source code src, see Exec
+func (i *Interpreter) Run(src string) (object.Object, error) {
+	code, err := i.Compile("", src)
+	if err != nil {
+		return nil, err
+	}
+	return i.Exec(code)
+}
+
+// RunFile compiles and executes the source file given by filename, see Exec
+func (i *Interpreter) RunFile(filename string) (object.Object, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := i.Compile(filename, string(b))
+	if err != nil {
+		return nil, err
+	}
+	return i.Exec(code)
+}
+
+// Get returns the value of the global binding (or builtin) given by name,
+// false is returned if there is no such binding
+func (i *Interpreter) Get(name string) (object.Object, bool) {
+	return i.state.Get(name)
+}
+
+// Set binds the Go value converted by ToObject to the global binding given by
+// name, which programs run after can refer to
+func (i *Interpreter) Set(name string, value interface{}) error {
+	switch fn := value.(type) {
+	case object.BuiltinFunction:
+		i.Register(name, fn)
+		return nil
+	case func(ctx *object.Context, args ...object.Object) object.Object:
+		i.Register(name, fn)
+		return nil
+	}
+
+	obj, err := ToObject(value)
+	if err != nil {
+		return err
+	}
+	i.state.Set(name, obj)
+	return nil
+}
+
+// Register binds the Go function fn as a builtin to the global binding given
+// by name, errors are raised by returning an *object.Error
+func (i *Interpreter) Register(name string, fn object.BuiltinFunction) {
+	i.state.Set(name, &object.Builtin{Name: name, Fn: fn})
+}
+
+// Call calls the Monkey function (or builtin) bound to the global binding
+// given by name with the Go values converted by ToObject as arguments and
+// returns the value it returns, errors are returned as for Exec
+func (i *Interpreter) Call(name string, args ...interface{}) (object.Object, error) {
+	fn, ok := i.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("undefined variable %s", name)
+	}
+
+	objs := make([]object.Object, len(args))
+	for n, arg := range args {
+		obj, err := ToObject(arg)
+		if err != nil {
+			return nil, err
+		}
+		objs[n] = obj
+	}
+
+	return i.exec(vm.NewCall(i.state, fn, objs...))
+}
+
+// exec runs the virtual machine and returns the last value popped. The
+// machine is run in its own goroutine so `exit()` can stop the program by
+// exiting the goroutine without exiting the process, whether it is called
+// by the program or by one of the tasks it spawned.
+func (i *Interpreter) exec(machine *vm.VM) (object.Object, error) {
+	// The tasks of a stopped program never run again
+	if i.ctx.Stopped() {
+		i.ctx = i.newContext()
+	}
+
+	ctx := i.ctx
+	machine.Options = i.Options
+	machine.Context = ctx
+	ctx.Stdin, ctx.Stdout, ctx.Stderr = i.Stdin, i.Stdout, i.Stderr
+	ctx.Args, ctx.Env = i.Args, i.Env
+	ctx.Sandbox, ctx.Capabilities = i.Sandbox, i.Capabilities
+
+	i.mu.Lock()
+	i.exited = false
+	i.mu.Unlock()
+
+	type outcome struct {
+		result object.Object
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		if err := machine.Run(); err != nil {
+			done <- outcome{err: err}
+			return
+		}
+		done <- outcome{result: machine.LastPopped()}
+	}()
+
+	var out outcome
+	select {
+	case out = <-done:
+	case <-ctx.Done():
+	}
+
+	i.mu.Lock()
+	exited, status := i.exited, i.status
+	i.mu.Unlock()
+
+	if exited {
+		if status != 0 {
+			return nil, &ExitError{Status: status}
+		}
+		return vm.Null, nil
+	}
+	if out.err != nil {
+		return nil, out.err
+	}
+	if out.result == nil {
+		return vm.Null, nil
+	}
+	return out.result, nil
+}
@@ -0,0 +1,354 @@
+package monkey
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/vm"
+)
+
+func TestRun(t *testing.T) {
+	i := New()
+
+	result, err := i.Run("x := 1 + 2\nx * 2")
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), ToValue(result))
+
+	// Globals are shared by the programs run
+	result, err = i.Run("x + 1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), ToValue(result))
+
+	result, err = i.Run("x := 2")
+	require.NoError(t, err)
+	assert.Equal(t, vm.Null, result)
+}
+
+func TestRunErrors(t *testing.T) {
+	i := New()
+
+	_, err := i.Run("x := (")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "parser errors")
+
+	_, err = i.Run("y")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "undefined variable y")
+
+	_, err = i.Run("1 / 0")
+	require.Error(t, err)
+	require.IsType(t, &vm.Error{}, err)
+	assert.Contains(t, err.Error(), "ZeroDivisionError")
+
+	// The interpreter can still be used after an error
+	result, err := i.Run("1 + 1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), ToValue(result))
+}
+
+func TestRunFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "monkey")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "double.monkey")
+	require.NoError(t, ioutil.WriteFile(filename, []byte("double := fn(x) { return x * 2 }\n"), 0644))
+
+	i := New()
+	_, err = i.RunFile(filename)
+	require.NoError(t, err)
+
+	result, err := i.Call("double", 21)
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), ToValue(result))
+
+	_, err = i.RunFile(filepath.Join(dir, "missing.monkey"))
+	assert.Error(t, err)
+}
+
+func TestGetSet(t *testing.T) {
+	i := New()
+
+	require.NoError(t, i.Set("name", "World"))
+	require.NoError(t, i.Set("numbers", []int{1, 2, 3}))
+	require.NoError(t, i.Set("config", map[string]interface{}{"debug": true}))
+
+	_, err := i.Run(`greeting := "Hello " + name
+total := numbers[0] + numbers[1] + numbers[2]
+debug := config["debug"]`)
+	require.NoError(t, err)
+
+	greeting, ok := i.Get("greeting")
+	require.True(t, ok)
+	assert.Equal(t, "Hello World", ToValue(greeting))
+
+	total, ok := i.Get("total")
+	require.True(t, ok)
+	assert.Equal(t, int64(6), ToValue(total))
+
+	debug, ok := i.Get("debug")
+	require.True(t, ok)
+	assert.Equal(t, true, ToValue(debug))
+
+	// Globals can be changed between programs
+	require.NoError(t, i.Set("name", "Monkey"))
+	result, err := i.Run(`"Hello " + name`)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello Monkey", ToValue(result))
+
+	builtin, ok := i.Get("len")
+	require.True(t, ok)
+	assert.IsType(t, &object.Builtin{}, builtin)
+
+	_, ok = i.Get("missing")
+	assert.False(t, ok)
+
+	assert.Error(t, i.Set("invalid", make(chan int)))
+}
+
+func TestCall(t *testing.T) {
+	i := New()
+
+	_, err := i.Run(`
+add := fn(a, b) { return a + b }
+fail := fn() { return 1 / 0 }
+`)
+	require.NoError(t, err)
+
+	result, err := i.Call("add", 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), ToValue(result))
+
+	result, err = i.Call("add", []int{1}, []int{2})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{int64(1), int64(2)}, ToValue(result))
+
+	result, err = i.Call("len", "monkey")
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), ToValue(result))
+
+	_, err = i.Call("add", 1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "wrong number of arguments")
+
+	_, err = i.Call("fail")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ZeroDivisionError")
+
+	_, err = i.Call("missing")
+	assert.Error(t, err)
+}
+
+func TestRegister(t *testing.T) {
+	i := New()
+
+	var calls []string
+	i.Register("record", func(ctx *object.Context, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return &object.Error{Message: "TypeError: record() takes 1 argument"}
+		}
+		calls = append(calls, args[0].String())
+		return &object.Integer{Value: int64(len(calls))}
+	})
+	require.NoError(t, i.Set("upper", func(ctx *object.Context, args ...object.Object) object.Object {
+		return &object.String{Value: strings.ToUpper(args[0].String())}
+	}))
+
+	result, err := i.Run(`record(upper("a"))
+f := fn(x) { return record(x) }
+f("b")`)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), ToValue(result))
+	assert.Equal(t, []string{"A", "b"}, calls)
+
+	_, err = i.Run("record()")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "TypeError: record() takes 1 argument")
+
+	result, err = i.Run(`try { record() } catch (e) { e.kind }`)
+	require.NoError(t, err)
+	assert.Equal(t, "TypeError", ToValue(result))
+}
+
+func TestStdio(t *testing.T) {
+	i := New()
+
+	var stdout bytes.Buffer
+	i.Stdin = strings.NewReader("Alice\nBob\n")
+	i.Stdout = &stdout
+	i.Args = []string{"-v"}
+	i.Env = []string{"HOME=/home/monkey"}
+
+	_, err := i.Run(`print(input("name? "))
+print(input())
+print(input())
+print(args())
+print(getenv("HOME"))`)
+	require.NoError(t, err)
+	assert.Equal(t, "name? Alice\nBob\n\n[\"-v\"]\n/home/monkey\n", stdout.String())
+}
+
+func TestInputLetsTasksRun(t *testing.T) {
+	i := New()
+
+	stdin, stdinWriter := io.Pipe()
+	stdout, stdoutWriter := io.Pipe()
+	i.Stdin, i.Stdout = stdin, stdoutWriter
+
+	// Input is only given once the task has run
+	go func() {
+		line, _ := bufio.NewReader(stdout).ReadString('\n')
+		if line == "task ran\n" {
+			io.WriteString(stdinWriter, "Alice\n")
+		}
+		stdinWriter.Close()
+		io.Copy(ioutil.Discard, stdout)
+	}()
+
+	result, err := i.Run(`spawn fn() { print("task ran") }(); input()`)
+	stdoutWriter.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", ToValue(result))
+}
+
+func TestExit(t *testing.T) {
+	i := New()
+
+	var stdout bytes.Buffer
+	i.Stdout = &stdout
+
+	_, err := i.Run(`print("before")
+exit(3)
+print("after")`)
+	require.Error(t, err)
+	assert.Equal(t, &ExitError{Status: 3}, err)
+	assert.Equal(t, "before\n", stdout.String())
+
+	result, err := i.Run("exit()")
+	require.NoError(t, err)
+	assert.Equal(t, vm.Null, result)
+
+	// The interpreter can still be used after exiting
+	result, err = i.Run("1 + 1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), ToValue(result))
+}
+
+func TestExitFromTask(t *testing.T) {
+	i := New()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := i.Run(`c := channel(); spawn fn() { exit(3) }(); x := recv(c); 42`)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		assert.Equal(t, &ExitError{Status: 3}, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("exit() from a task did not stop the program")
+	}
+
+	// The interpreter can still be used after a task exits
+	result, err := i.Run(`c := channel(); spawn fn() { send(c, 1) }(); recv(c) + 1`)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), ToValue(result))
+}
+
+func TestSandbox(t *testing.T) {
+	i := New()
+	i.Sandbox = true
+	i.Capabilities = object.ProcessCapability
+	i.Register("safe", func(ctx *object.Context, args ...object.Object) object.Object {
+		return &object.String{Value: "ok"}
+	})
+
+	result, err := i.Run(`safe()`)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", ToValue(result))
+
+	_, err = i.Run(`readfile("/etc/passwd")`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "PermissionError: readfile() requires the filesystem capability")
+
+	_, err = i.Run(`exit(2)`)
+	assert.Equal(t, &ExitError{Status: 2}, err)
+
+	i.Capabilities = object.FilesystemCapability
+	_, err = i.Run(`exit(2)`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "PermissionError: exit() requires the process capability")
+
+	i.Sandbox = false
+	_, err = i.Run(`exit(2)`)
+	assert.Equal(t, &ExitError{Status: 2}, err)
+}
+
+func TestIndependentInterpreters(t *testing.T) {
+	var wg sync.WaitGroup
+	outputs := make([]bytes.Buffer, 8)
+	for n := range outputs {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+
+			i := New()
+			i.Stdout = &outputs[n]
+			require.NoError(t, i.Set("n", n))
+			_, err := i.Run(`
+fib := fn(x) { if (x < 2) { return x } return fib(x - 1) + fib(x - 2) }
+print(n)
+print(fib(15))
+`)
+			assert.NoError(t, err)
+		}(n)
+	}
+	wg.Wait()
+
+	for n := range outputs {
+		assert.Equal(t, strings.Join([]string{string(rune('0' + n)), "610", ""}, "\n"), outputs[n].String())
+	}
+}
+
+func TestValues(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected interface{}
+	}{
+		{nil, nil},
+		{true, true},
+		{int8(-1), int64(-1)},
+		{uint(1), int64(1)},
+		{1.5, 1.5},
+		{"monkey", "monkey"},
+		{[]string{"a", "b"}, []interface{}{"a", "b"}},
+		{[2]int{1, 2}, []interface{}{int64(1), int64(2)}},
+		{map[string]int{"a": 1}, map[interface{}]interface{}{"a": int64(1)}},
+		{
+			map[[2]interface{}]int{{1, "a"}: 1},
+			map[interface{}]interface{}{[2]interface{}{int64(1), "a"}: int64(1)},
+		},
+	}
+
+	for _, tt := range tests {
+		obj, err := ToObject(tt.value)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, ToValue(obj))
+	}
+
+	_, err := ToObject(map[interface{}]int{nil: 1})
+	assert.Error(t, err)
+}
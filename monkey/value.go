@@ -0,0 +1,136 @@
+package monkey
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/vm"
+)
+
+// ToObject converts the Go value v to a Monkey object. Objects are returned
+// as is, nil converts to null, booleans, integers, floats and strings to
+// their Monkey types, slices and arrays to arrays, maps with keys that can be
+// converted to hashable objects to hashes (ordered by key) and functions of type
+// object.BuiltinFunction to builtins.
+func ToObject(v interface{}) (object.Object, error) {
+	switch v := v.(type) {
+	case nil:
+		return vm.Null, nil
+	case object.Object:
+		return v, nil
+	case bool:
+		if v {
+			return vm.True, nil
+		}
+		return vm.False, nil
+	case string:
+		return &object.String{Value: v}, nil
+	case object.BuiltinFunction:
+		return &object.Builtin{Name: "<go>", Fn: v}, nil
+	case func(ctx *object.Context, args ...object.Object) object.Object:
+		return &object.Builtin{Name: "<go>", Fn: v}, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Integer{Value: rv.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &object.Integer{Value: int64(rv.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &object.Float{Value: rv.Float()}, nil
+	case reflect.String:
+		return &object.String{Value: rv.String()}, nil
+	case reflect.Slice, reflect.Array:
+		elements := make([]object.Object, rv.Len())
+		for i := range elements {
+			obj, err := ToObject(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = obj
+		}
+		return &object.Array{Elements: elements}, nil
+	case reflect.Map:
+		// Go maps are unordered so the keys are sorted for a deterministic
+		// order
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+
+		hash := object.NewHash(rv.Len())
+		for _, k := range keys {
+			key, err := ToObject(k.Interface())
+			if err != nil {
+				return nil, err
+			}
+			hashable, ok := object.AsHashable(key)
+			if !ok {
+				return nil, fmt.Errorf("unusable as hash key: %s", key.Type())
+			}
+			value, err := ToObject(rv.MapIndex(k).Interface())
+			if err != nil {
+				return nil, err
+			}
+			hash.Set(hashable, value)
+		}
+		return hash, nil
+	default:
+		return nil, fmt.Errorf("cannot convert %T to a Monkey object", v)
+	}
+}
+
+// ToValue converts the Monkey object obj to a Go value, the inverse of
+// ToObject. Null converts to nil, booleans, integers, floats and strings to
+// bool, int64, float64 and string, arrays to []interface{} and hashes to
+// map[interface{}]interface{} with array keys converted to Go arrays of type
+// [n]interface{} (as slices cannot be map keys), other objects are returned
+// as is.
+func ToValue(obj object.Object) interface{} {
+	switch obj := obj.(type) {
+	case nil, *object.Null:
+		return nil
+	case *object.Boolean:
+		return obj.Value
+	case *object.Integer:
+		return obj.Value
+	case *object.Float:
+		return obj.Value
+	case *object.String:
+		return obj.Value
+	case *object.Array:
+		values := make([]interface{}, len(obj.Elements))
+		for i, element := range obj.Elements {
+			values[i] = ToValue(element)
+		}
+		return values
+	case *object.Hash:
+		values := make(map[interface{}]interface{}, obj.Len())
+		for _, pair := range obj.Pairs() {
+			values[toKey(pair.Key)] = ToValue(pair.Value)
+		}
+		return values
+	default:
+		return obj
+	}
+}
+
+// toKey converts the hash key obj to a Go value that can be used as a map key
+func toKey(obj object.Object) interface{} {
+	array, ok := obj.(*object.Array)
+	if !ok {
+		return ToValue(obj)
+	}
+
+	var elem interface{}
+	key := reflect.New(reflect.ArrayOf(len(array.Elements), reflect.TypeOf(&elem).Elem())).Elem()
+	for i, element := range array.Elements {
+		if v := toKey(element); v != nil {
+			key.Index(i).Set(reflect.ValueOf(v))
+		}
+	}
+	return key.Interface()
+}
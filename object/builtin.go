@@ -10,6 +10,22 @@ import (
 type Builtin struct {
 	Name string
 	Fn   BuiltinFunction
+
+	// Signature documents how the builtin is called, e.g. `len(iterable)`
+	Signature string
+
+	// Capability is the groups of builtins the builtin belongs to, any of
+	// which must be available to call it in a sandbox
+	Capability Capability
+}
+
+// Call calls the builtin with the execution context ctx and arguments args,
+// a PermissionError is returned if the builtin is not available in ctx
+func (b *Builtin) Call(ctx *Context, args ...Object) Object {
+	if err := ctx.Require(b.Name, b.Capability); err != nil {
+		return err
+	}
+	return b.Fn(ctx, args...)
 }
 
 func (b *Builtin) Bool() bool {
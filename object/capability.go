@@ -0,0 +1,73 @@
+package object
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Capability is a set of groups of builtins which can be made unavailable
+// to programs run in a sandbox
+type Capability uint
+
+const (
+	// FilesystemCapability is the group of builtins which open, read and
+	// write files and file descriptors
+	FilesystemCapability Capability = 1 << iota
+	// NetworkCapability is the group of builtins which create and use
+	// sockets
+	NetworkCapability
+	// FFICapability is the group of builtins which load native code
+	FFICapability
+	// ProcessCapability is the group of builtins which use the process's
+	// arguments and environment and exit it
+	ProcessCapability
+
+	// AllCapabilities is the set of every group of builtins
+	AllCapabilities = FilesystemCapability | NetworkCapability | FFICapability | ProcessCapability
+)
+
+var capabilityNames = []struct {
+	capability Capability
+	name       string
+}{
+	{FilesystemCapability, "filesystem"},
+	{NetworkCapability, "network"},
+	{FFICapability, "ffi"},
+	{ProcessCapability, "process"},
+}
+
+// ParseCapabilities parses a comma separated list of the names of groups of
+// builtins, e.g: "filesystem,process"
+func ParseCapabilities(s string) (Capability, error) {
+	var c Capability
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		found := false
+		for _, cn := range capabilityNames {
+			if cn.name == name {
+				c |= cn.capability
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown capability %q", name)
+		}
+	}
+	return c, nil
+}
+
+// String returns the names of the groups in the set separated by commas
+func (c Capability) String() string {
+	var names []string
+	for _, cn := range capabilityNames {
+		if c&cn.capability != 0 {
+			names = append(names, cn.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
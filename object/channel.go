@@ -0,0 +1,149 @@
+package object
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Channel is a channel of values used by tasks to communicate and
+// synchronize, values are sent with `send()` and received with `recv()` in
+// the order they were sent. Sends block until the value is received, or for
+// buffered channels until there is room in the buffer.
+type Channel struct {
+	ch chan Object
+}
+
+// NewChannel returns a new channel buffering up to size values
+func NewChannel(size int) *Channel {
+	return &Channel{ch: make(chan Object, size)}
+}
+
+// Send sends obj on the channel, the lock of the program's tasks is released
+// while blocked. An error is returned if the channel is closed.
+func (c *Channel) Send(ctx *Context, obj Object) (err error) {
+	defer func() {
+		if recover() != nil {
+			err = fmt.Errorf("send on closed channel")
+		}
+	}()
+
+	ctx.Release()
+	defer ctx.Acquire()
+
+	// The task exits when it acquires the lock if the program was stopped
+	select {
+	case c.ch <- obj:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+// Recv receives a value from the channel, the lock of the program's tasks
+// is released while blocked. False is returned once the channel is closed
+// and every value sent has been received.
+func (c *Channel) Recv(ctx *Context) (Object, bool) {
+	ctx.Release()
+	defer ctx.Acquire()
+
+	// The task exits when it acquires the lock if the program was stopped
+	select {
+	case obj, ok := <-c.ch:
+		return obj, ok
+	case <-ctx.Done():
+		return &Null{}, false
+	}
+}
+
+// Close closes the channel, an error is returned if it is already closed
+func (c *Channel) Close() (err error) {
+	defer func() {
+		if recover() != nil {
+			err = fmt.Errorf("close of closed channel")
+		}
+	}()
+
+	close(c.ch)
+	return nil
+}
+
+// Len returns the number of values buffered by the channel
+func (c *Channel) Len() int {
+	return len(c.ch)
+}
+
+func (c *Channel) Bool() bool {
+	return true
+}
+
+func (c *Channel) Compare(other Object) int {
+	if c == other {
+		return 0
+	}
+	return 1
+}
+
+func (c *Channel) String() string {
+	return c.Inspect()
+}
+
+// Type returns the type of the object
+func (c *Channel) Type() Type { return CHANNEL }
+
+// Inspect returns a stringified version of the object for debugging
+func (c *Channel) Inspect() string { return fmt.Sprintf("<channel %p>", c) }
+
+// SelectCase is a case of Select sending Value to Channel, or receiving from
+// it if Value is nil
+type SelectCase struct {
+	Channel *Channel
+	Value   Object
+}
+
+// Select waits until one of the cases can proceed and returns the index of
+// the case which did, the value received (if any) or null if the channel
+// received from is closed. If block is false and none of the cases can
+// proceed -1 is returned instead of waiting. The lock of the program's tasks
+// is released while blocked.
+func Select(ctx *Context, cases []SelectCase, block bool) (chosen int, obj Object, err error) {
+	selectCases := make([]reflect.SelectCase, len(cases), len(cases)+2)
+	for i, c := range cases {
+		if c.Value != nil {
+			selectCases[i] = reflect.SelectCase{
+				Dir:  reflect.SelectSend,
+				Chan: reflect.ValueOf(c.Channel.ch),
+				Send: reflect.ValueOf(&c.Value).Elem(),
+			}
+		} else {
+			selectCases[i] = reflect.SelectCase{
+				Dir:  reflect.SelectRecv,
+				Chan: reflect.ValueOf(c.Channel.ch),
+			}
+		}
+	}
+	// The task exits when it acquires the lock if the program was stopped
+	selectCases = append(selectCases, reflect.SelectCase{
+		Dir:  reflect.SelectRecv,
+		Chan: reflect.ValueOf(ctx.Done()),
+	})
+	if !block {
+		selectCases = append(selectCases, reflect.SelectCase{Dir: reflect.SelectDefault})
+	}
+
+	defer func() {
+		if recover() != nil {
+			err = fmt.Errorf("send on closed channel")
+		}
+	}()
+
+	ctx.Release()
+	defer ctx.Acquire()
+
+	chosen, value, ok := reflect.Select(selectCases)
+	if chosen >= len(cases) {
+		return -1, &Null{}, nil
+	}
+	if !ok || cases[chosen].Value != nil {
+		return chosen, &Null{}, nil
+	}
+	return chosen, value.Interface().(Object), nil
+}
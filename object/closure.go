@@ -7,9 +7,12 @@ import (
 )
 
 // CompiledFunction is the compiled function type that holds the function's
-// compiled body as bytecode instructions
+// name (if any), compiled body as bytecode instructions along with a map of
+// the instructions back to the source positions they were compiled from
 type CompiledFunction struct {
+	Name          string
 	Instructions  code.Instructions
+	SourceMap     code.SourceMap
 	NumLocals     int
 	NumParameters int
 }
@@ -0,0 +1,224 @@
+package object
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// yieldInterval is the number of times Yield is called by the task running
+// before it lets other tasks run
+const yieldInterval = 1000
+
+// Context is the execution context of a program which is passed to every
+// builtin it calls and holds the program's standard input, output and error,
+// its arguments and environment and how it exits. Programs run with their
+// own contexts are independent of each other and can be run concurrently.
+type Context struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Args are the program's arguments returned by `args()`
+	Args []string
+
+	// Env is the program's environment as a list of "key=value" strings
+	Env []string
+
+	// Exit is called by `exit()` with the exit status and is not expected
+	// to return
+	Exit func(int)
+
+	// Sandbox restricts the builtins available to those in the groups given
+	// by Capabilities, other builtins raise a PermissionError when called
+	Sandbox      bool
+	Capabilities Capability
+
+	// Call calls the function fn with args and returns the value it returns
+	// or the error it raised, it is set by the engine running the program so
+	// builtins can call functions (e.g: the callbacks of `run_loop()`)
+	Call func(fn Object, args ...Object) Object
+
+	// stdin buffers the input read from in by ReadLine
+	stdin *bufio.Reader
+	in    io.Reader
+
+	// lock is held by the task running once the program has spawned tasks
+	// (tasks is true), ticks counts the calls to Yield
+	lock  sync.Mutex
+	tasks bool
+	ticks int
+
+	// loop is the program's event loop, see Loop
+	loop *Loop
+
+	// done is closed when the program is stopped, see Stop
+	mu   sync.Mutex
+	done chan struct{}
+}
+
+// NewContext returns a new context using the process's standard input,
+// output and error, its environment and exiting the process with no
+// arguments
+func NewContext() *Context {
+	return &Context{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Env:    os.Environ(),
+		Exit:   os.Exit,
+	}
+}
+
+// Allows returns true if builtins in any of the groups in c can be called
+func (c *Context) Allows(capability Capability) bool {
+	return !c.Sandbox || capability == 0 || c.Capabilities&capability != 0
+}
+
+// Require returns a PermissionError if the builtin given by name, which
+// belongs to the groups in capability, cannot be called and nil otherwise
+func (c *Context) Require(name string, capability Capability) *Error {
+	if c.Allows(capability) {
+		return nil
+	}
+	return &Error{Message: fmt.Sprintf(
+		"PermissionError: %s() requires the %s capability",
+		name, strings.Replace(capability.String(), ",", " or ", -1),
+	)}
+}
+
+// Spawn runs task concurrently with the program as a new task. Tasks share
+// the program's globals and objects and run one at a time: the task running
+// holds the context's lock, which it releases while blocked (see Release)
+// and periodically (see Yield) to let other tasks run. The program (or task)
+// spawning the first task holds the lock from then on while it runs.
+func (c *Context) Spawn(task func()) {
+	if !c.tasks {
+		c.tasks = true
+		c.lock.Lock()
+	}
+
+	go func() {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		task()
+	}()
+}
+
+// Release releases the lock held by the task running so other tasks can run
+// while it blocks (e.g: on I/O), Acquire must be called once it unblocks
+// before it uses any objects. Both do nothing if no tasks were spawned.
+func (c *Context) Release() {
+	if c.tasks {
+		c.lock.Unlock()
+	}
+}
+
+// Acquire acquires the lock released by Release, waiting for the task
+// holding it to release it. If the program was stopped while the task was
+// blocked the task exits instead, see Stop.
+func (c *Context) Acquire() {
+	if c.tasks {
+		c.lock.Lock()
+	}
+	c.exitIfStopped()
+}
+
+// Done returns a channel which is closed when the program is stopped
+func (c *Context) Done() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.done == nil {
+		c.done = make(chan struct{})
+	}
+	return c.done
+}
+
+// Stop stops the program, e.g: when one of its tasks calls `exit()`. Tasks
+// blocked on channels wake up and every other task exits its goroutine,
+// without running any more of the program, once it acquires the lock of the
+// program's tasks (see Acquire). The task calling Stop must exit itself.
+func (c *Context) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.done == nil {
+		c.done = make(chan struct{})
+	}
+	select {
+	case <-c.done:
+	default:
+		close(c.done)
+	}
+}
+
+// Stopped returns true if the program was stopped by Stop
+func (c *Context) Stopped() bool {
+	select {
+	case <-c.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// exitIfStopped exits the goroutine of the task running if the program was
+// stopped, the lock of the program's tasks is released by the deferred calls
+// of the task (or program) holding it
+func (c *Context) exitIfStopped() {
+	if c.Stopped() {
+		runtime.Goexit()
+	}
+}
+
+// Yield lets other tasks run if the task running has called it many times
+// since it last did, so tasks which never block do not keep others waiting
+func (c *Context) Yield() {
+	if !c.tasks {
+		return
+	}
+
+	c.ticks++
+	if c.ticks < yieldInterval {
+		return
+	}
+	c.ticks = 0
+
+	c.lock.Unlock()
+	runtime.Gosched()
+	c.lock.Lock()
+	c.exitIfStopped()
+}
+
+// ReadLine reads a line from Stdin and returns it without the line ending.
+// Input read ahead of the line is kept for the next call unless Stdin is
+// changed.
+func (c *Context) ReadLine() (string, error) {
+	if c.stdin == nil || c.in != c.Stdin {
+		c.stdin = bufio.NewReader(c.Stdin)
+		c.in = c.Stdin
+	}
+
+	line, err := c.stdin.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// Getenv returns the value of the environment variable given by key, false
+// is returned if it is not set
+func (c *Context) Getenv(key string) (string, bool) {
+	// Later values take precedence as with os/exec
+	for i := len(c.Env) - 1; i >= 0; i-- {
+		if strings.HasPrefix(c.Env[i], key+"=") {
+			return c.Env[i][len(key)+1:], true
+		}
+	}
+	return "", false
+}
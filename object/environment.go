@@ -1,20 +1,34 @@
 package object
 
 import (
+	"sort"
 	"unicode"
 )
 
 // NewEnvironment constructs a new Environment object to hold bindings
 // of identifiers to their names
 func NewEnvironment() *Environment {
+	return NewEnvironmentWithContext(NewContext())
+}
+
+// NewEnvironmentWithContext constructs a new Environment object for a
+// program run with the execution context ctx
+func NewEnvironmentWithContext(ctx *Context) *Environment {
 	s := make(map[string]Object)
-	return &Environment{store: s}
+	return &Environment{store: s, ctx: ctx}
 }
 
 // Environment is an object that holds a mapping of names to bound objets
 type Environment struct {
 	store  map[string]Object
 	parent *Environment
+	ctx    *Context
+}
+
+// Context returns the execution context of the program the environment
+// belongs to
+func (e *Environment) Context() *Context {
+	return e.ctx
 }
 
 // ExportedHash returns a new Hash with the names and values of every publically
@@ -22,22 +36,25 @@ type Environment struct {
 // capital letter. This is used by the module import system to wrap up the
 // evaulated module into an object.
 func (e *Environment) ExportedHash() *Hash {
-	pairs := make(map[HashKey]HashPair)
-	for k, v := range e.store {
+	var names []string
+	for k := range e.store {
 		if unicode.IsUpper(rune(k[0])) {
-			s := &String{Value: k}
-			pairs[s.HashKey()] = HashPair{Key: s, Value: v}
+			names = append(names, k)
 		}
 	}
-	return &Hash{Pairs: pairs}
+	sort.Strings(names)
+
+	hash := NewHash(len(names))
+	for _, name := range names {
+		hash.Set(&String{Value: name}, e.store[name])
+	}
+	return hash
 }
 
 // Clone returns a new Environment with the parent set to the current
 // environment (enclosing environment)
 func (e *Environment) Clone() *Environment {
-	env := NewEnvironment()
-	env.parent = e
-	return env
+	return &Environment{store: make(map[string]Object), parent: e, ctx: e.ctx}
 }
 
 // Get returns the object bound by name
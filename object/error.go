@@ -1,10 +1,49 @@
 package object
 
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/prologic/monkey-lang/token"
+)
+
+// TraceEntry is a single entry of a stack trace and holds the name of the
+// function being executed by a frame and the source position of the
+// instruction it was executing
+type TraceEntry struct {
+	Function string
+	Pos      token.Position
+}
+
+// String returns a stringified version of the trace entry
+func (te TraceEntry) String() string {
+	filename := te.Pos.Filename
+	if filename == "" {
+		filename = "<unknown>"
+	}
+
+	if !te.Pos.IsValid() {
+		return fmt.Sprintf("  File %q, in %s", filename, te.Function)
+	}
+	return fmt.Sprintf(
+		"  File %q, line %d, column %d, in %s",
+		filename, te.Pos.Line, te.Pos.Column, te.Function,
+	)
+}
+
 // Error is the error type and used to hold a message denoting the details of
 // error encountered. This object is trakced through the evaluator and when
 // encountered stops evaulation of the program or body of a function.
+//
+// The message is prefixed by the kind of error, e.g: `TypeError: ...`, and
+// the error may be caught by a `try`/`catch` expression after which Caught
+// is set and the error is an ordinary value that no longer stops evaluation.
 type Error struct {
-	Message string
+	Message  string
+	Position token.Position
+	Trace    []TraceEntry
+	Caught   bool
 }
 
 func (e *Error) Bool() bool {
@@ -12,16 +51,72 @@ func (e *Error) Bool() bool {
 }
 
 func (e *Error) String() string {
+	if e.Position.IsValid() {
+		return e.Position.String() + ": " + e.Message
+	}
+	return e.Message
+}
+
+// Error implements the error interface and returns the error message
+func (e *Error) Error() string {
 	return e.Message
 }
 
+// Kind returns the kind of error given by the prefix of the message, e.g:
+// `TypeError` or `IOError`, and defaults to `Error` if there is none
+func (e *Error) Kind() string {
+	if i := strings.Index(e.Message, ": "); i > 0 {
+		kind := e.Message[:i]
+		isKind := strings.HasSuffix(kind, "Error") &&
+			strings.IndexFunc(kind, func(r rune) bool { return !unicode.IsLetter(r) }) == -1
+		if isKind {
+			return kind
+		}
+	}
+	return "Error"
+}
+
+// Text returns the error message without the kind prefix
+func (e *Error) Text() string {
+	if kind := e.Kind(); strings.HasPrefix(e.Message, kind+": ") {
+		return e.Message[len(kind)+2:]
+	}
+	return e.Message
+}
+
+// Get returns the attribute name of the error, either `kind` or `message`,
+// or nil if there is no such attribute
+func (e *Error) Get(name string) Object {
+	switch name {
+	case "kind":
+		return &String{Value: e.Kind()}
+	case "message":
+		return &String{Value: e.Text()}
+	}
+	return nil
+}
+
+// Raise returns a copy of the error that is no longer caught, for
+// re-throwing an error previously caught by a `catch` block
+func (e *Error) Raise() *Error {
+	if !e.Caught {
+		return e
+	}
+	return &Error{Message: e.Message, Position: e.Position, Trace: e.Trace}
+}
+
 // Clone creates a new copy
 func (e *Error) Clone() Object {
-	return &Error{Message: e.Message}
+	return &Error{
+		Message:  e.Message,
+		Position: e.Position,
+		Trace:    e.Trace,
+		Caught:   e.Caught,
+	}
 }
 
 // Type returns the type of the object
 func (e *Error) Type() Type { return ERROR }
 
 // Inspect returns a stringified version of the object for debugging
-func (e *Error) Inspect() string { return "ERROR: " + e.Message }
+func (e *Error) Inspect() string { return "ERROR: " + e.String() }
@@ -0,0 +1,293 @@
+package object
+
+import (
+	"container/heap"
+	"fmt"
+	"time"
+)
+
+// Events are the events a file descriptor is watched for by an event loop
+type Events int
+
+const (
+	// ReadEvent is the event of a file descriptor becoming readable
+	ReadEvent Events = 1 << iota
+	// WriteEvent is the event of a file descriptor becoming writable
+	WriteEvent
+)
+
+// ParseEvents parses events given as a string of "r" (readable) and "w"
+// (writable), e.g: "rw"
+func ParseEvents(s string) (Events, error) {
+	var events Events
+	for _, c := range s {
+		switch c {
+		case 'r':
+			events |= ReadEvent
+		case 'w':
+			events |= WriteEvent
+		default:
+			return 0, fmt.Errorf("invalid event %q", c)
+		}
+	}
+	if events == 0 {
+		return 0, fmt.Errorf("no events given")
+	}
+	return events, nil
+}
+
+func (e Events) String() string {
+	s := ""
+	if e&ReadEvent != 0 {
+		s += "r"
+	}
+	if e&WriteEvent != 0 {
+		s += "w"
+	}
+	return s
+}
+
+// handler is the callback of a file descriptor registered with a loop
+type handler struct {
+	events   Events
+	callback Object
+}
+
+// timer is a callback run by a loop once its deadline passes, and then
+// every interval if it repeats
+type timer struct {
+	id       int64
+	deadline time.Time
+	interval time.Duration
+	repeat   bool
+	callback Object
+
+	// index is the timer's index in the heap, or -1 once removed
+	index     int
+	cancelled bool
+}
+
+// timers is a heap of timers ordered by deadline, timers with the same
+// deadline are ordered by when they were added
+type timers []*timer
+
+func (t timers) Len() int { return len(t) }
+
+func (t timers) Less(i, j int) bool {
+	if t[i].deadline.Equal(t[j].deadline) {
+		return t[i].id < t[j].id
+	}
+	return t[i].deadline.Before(t[j].deadline)
+}
+
+func (t timers) Swap(i, j int) {
+	t[i], t[j] = t[j], t[i]
+	t[i].index, t[j].index = i, j
+}
+
+func (t *timers) Push(x interface{}) {
+	tm := x.(*timer)
+	tm.index = len(*t)
+	*t = append(*t, tm)
+}
+
+func (t *timers) Pop() interface{} {
+	old := *t
+	tm := old[len(old)-1]
+	*t = old[:len(old)-1]
+	tm.index = -1
+	return tm
+}
+
+// Loop is the event loop of a program run by `run_loop()` which calls the
+// callbacks of the file descriptors registered with it as they become ready
+// and of timers as they expire, so a single program can serve many
+// connections without tasks. Callbacks are called one at a time by the
+// program running the loop with ctx.Call.
+type Loop struct {
+	poller   *poller
+	handlers map[int]*handler
+	timers   timers
+	ids      map[int64]*timer
+	nextID   int64
+	running  bool
+	stopped  bool
+}
+
+// Loop returns the program's event loop
+func (c *Context) Loop() *Loop {
+	if c.loop == nil {
+		c.loop = &Loop{
+			handlers: make(map[int]*handler),
+			ids:      make(map[int64]*timer),
+		}
+	}
+	return c.loop
+}
+
+// Register watches the file descriptor fd for events, callback is called
+// with fd and the events ready when any are. Registering a file descriptor
+// again replaces its events and callback.
+func (l *Loop) Register(fd int, events Events, callback Object) error {
+	if l.poller == nil {
+		p, err := newPoller()
+		if err != nil {
+			return err
+		}
+		l.poller = p
+	}
+
+	if _, ok := l.handlers[fd]; ok {
+		if err := l.poller.modify(fd, events); err != nil {
+			return err
+		}
+	} else if err := l.poller.add(fd, events); err != nil {
+		return err
+	}
+
+	l.handlers[fd] = &handler{events: events, callback: callback}
+	return nil
+}
+
+// Unregister stops watching the file descriptor fd, false is returned if it
+// was not registered
+func (l *Loop) Unregister(fd int) bool {
+	if _, ok := l.handlers[fd]; !ok {
+		return false
+	}
+	delete(l.handlers, fd)
+	l.poller.remove(fd)
+	return true
+}
+
+// AddTimer adds a timer calling callback once after delay, or every delay
+// if repeat is true, and returns its id
+func (l *Loop) AddTimer(delay time.Duration, repeat bool, callback Object) int64 {
+	l.nextID++
+	tm := &timer{
+		id:       l.nextID,
+		deadline: time.Now().Add(delay),
+		interval: delay,
+		repeat:   repeat,
+		callback: callback,
+	}
+	heap.Push(&l.timers, tm)
+	l.ids[tm.id] = tm
+	return tm.id
+}
+
+// Cancel cancels the timer given by id, false is returned if it already
+// expired or was cancelled
+func (l *Loop) Cancel(id int64) bool {
+	tm, ok := l.ids[id]
+	if !ok {
+		return false
+	}
+	delete(l.ids, id)
+	if tm.index >= 0 {
+		heap.Remove(&l.timers, tm.index)
+	}
+	tm.cancelled = true
+	return true
+}
+
+// Stop stops the loop once the callback being called returns
+func (l *Loop) Stop() {
+	l.stopped = true
+}
+
+// Run runs the loop until no file descriptors are registered and no timers
+// are pending or Stop is called. The lock of the program's tasks is released
+// while waiting. An error raised by a callback stops the loop and is
+// returned.
+func (l *Loop) Run(ctx *Context) error {
+	if l.running {
+		return fmt.Errorf("the event loop is already running")
+	}
+	l.running, l.stopped = true, false
+	defer func() { l.running = false }()
+
+	for !l.stopped && (len(l.handlers) > 0 || len(l.timers) > 0) {
+		timeout := -1
+		if len(l.timers) > 0 {
+			timeout = 0
+			if d := time.Until(l.timers[0].deadline); d > 0 {
+				// Round up so the timer has expired once the wait ends
+				timeout = int((d + time.Millisecond - 1) / time.Millisecond)
+			}
+		}
+
+		var (
+			ready []readyEvent
+			err   error
+		)
+		if len(l.handlers) > 0 {
+			ctx.Release()
+			ready, err = l.poller.wait(timeout)
+			ctx.Acquire()
+			if err != nil {
+				return err
+			}
+		} else if timeout > 0 {
+			ctx.Release()
+			time.Sleep(time.Duration(timeout) * time.Millisecond)
+			ctx.Acquire()
+		}
+
+		for _, r := range ready {
+			// A callback called before may have unregistered it
+			h, ok := l.handlers[r.fd]
+			if !ok {
+				continue
+			}
+			events := r.events & h.events
+			if events == 0 {
+				continue
+			}
+			err := l.call(ctx, h.callback, &Integer{Value: int64(r.fd)}, &String{Value: events.String()})
+			if err != nil || l.stopped {
+				return err
+			}
+		}
+
+		// Only timers which expired before running them are run so timers
+		// added by callbacks run on the next iteration
+		now := time.Now()
+		var expired []*timer
+		for len(l.timers) > 0 && !l.timers[0].deadline.After(now) {
+			tm := l.timers[0]
+			if tm.repeat {
+				tm.deadline = now.Add(tm.interval)
+				heap.Fix(&l.timers, 0)
+			} else {
+				heap.Pop(&l.timers)
+			}
+			expired = append(expired, tm)
+			if tm.repeat && tm.interval == 0 {
+				break
+			}
+		}
+		for _, tm := range expired {
+			// A callback called before may have cancelled it
+			if tm.cancelled {
+				continue
+			}
+			if !tm.repeat {
+				delete(l.ids, tm.id)
+			}
+			if err := l.call(ctx, tm.callback); err != nil || l.stopped {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// call calls the callback with args and returns the error it raised (if any)
+func (l *Loop) call(ctx *Context, callback Object, args ...Object) error {
+	if err, ok := ctx.Call(callback, args...).(*Error); ok && !err.Caught {
+		return err
+	}
+	return nil
+}
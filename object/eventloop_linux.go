@@ -0,0 +1,82 @@
+package object
+
+import "syscall"
+
+// maxEvents is the maximum number of events returned by a single wait
+const maxEvents = 128
+
+// readyEvent is a file descriptor with the events it is ready for
+type readyEvent struct {
+	fd     int
+	events Events
+}
+
+// poller waits for file descriptors to become ready with epoll
+type poller struct {
+	fd     int
+	events []syscall.EpollEvent
+}
+
+func newPoller() (*poller, error) {
+	fd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	return &poller{fd: fd, events: make([]syscall.EpollEvent, maxEvents)}, nil
+}
+
+func epollEvents(events Events) uint32 {
+	var e uint32
+	if events&ReadEvent != 0 {
+		e |= syscall.EPOLLIN
+	}
+	if events&WriteEvent != 0 {
+		e |= syscall.EPOLLOUT
+	}
+	return e
+}
+
+func (p *poller) add(fd int, events Events) error {
+	event := syscall.EpollEvent{Events: epollEvents(events), Fd: int32(fd)}
+	return syscall.EpollCtl(p.fd, syscall.EPOLL_CTL_ADD, fd, &event)
+}
+
+func (p *poller) modify(fd int, events Events) error {
+	event := syscall.EpollEvent{Events: epollEvents(events), Fd: int32(fd)}
+	err := syscall.EpollCtl(p.fd, syscall.EPOLL_CTL_MOD, fd, &event)
+	if err == syscall.ENOENT {
+		// The file descriptor was closed and its number reused since
+		return syscall.EpollCtl(p.fd, syscall.EPOLL_CTL_ADD, fd, &event)
+	}
+	return err
+}
+
+func (p *poller) remove(fd int) {
+	// Closed file descriptors are removed by the kernel
+	syscall.EpollCtl(p.fd, syscall.EPOLL_CTL_DEL, fd, nil)
+}
+
+// wait waits up to timeout milliseconds, or forever if negative, for any of
+// the file descriptors to become ready. Errors and hang ups are reported as
+// both events so reading or writing returns them.
+func (p *poller) wait(timeout int) ([]readyEvent, error) {
+	n, err := syscall.EpollWait(p.fd, p.events, timeout)
+	if err == syscall.EINTR {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ready := make([]readyEvent, n)
+	for i, event := range p.events[:n] {
+		ready[i].fd = int(event.Fd)
+		if event.Events&(syscall.EPOLLIN|syscall.EPOLLHUP|syscall.EPOLLERR) != 0 {
+			ready[i].events |= ReadEvent
+		}
+		if event.Events&(syscall.EPOLLOUT|syscall.EPOLLHUP|syscall.EPOLLERR) != 0 {
+			ready[i].events |= WriteEvent
+		}
+	}
+	return ready, nil
+}
@@ -0,0 +1,27 @@
+//go:build !linux
+
+package object
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// readyEvent is a file descriptor with the events it is ready for
+type readyEvent struct {
+	fd     int
+	events Events
+}
+
+// poller is not supported on platforms without epoll
+type poller struct{}
+
+func newPoller() (*poller, error) {
+	return nil, fmt.Errorf("the event loop is not supported on %s", runtime.GOOS)
+}
+
+func (p *poller) add(fd int, events Events) error    { return nil }
+func (p *poller) modify(fd int, events Events) error { return nil }
+func (p *poller) remove(fd int)                      {}
+
+func (p *poller) wait(timeout int) ([]readyEvent, error) { return nil, nil }
@@ -0,0 +1,64 @@
+package object
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Float is the floating point type used to represent float literals and
+// holds an internal float64 value
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Bool() bool {
+	return f.Value != 0
+}
+
+func (f *Float) Compare(other Object) int {
+	var value float64
+	switch obj := other.(type) {
+	case *Float:
+		value = obj.Value
+	case *Integer:
+		value = float64(obj.Value)
+	default:
+		return -1
+	}
+
+	switch {
+	case f.Value < value:
+		return -1
+	case f.Value > value:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (f *Float) String() string {
+	return f.Inspect()
+}
+
+// Clone creates a new copy
+func (f *Float) Clone() Object {
+	return &Float{Value: f.Value}
+}
+
+// Type returns the type of the object
+func (f *Float) Type() Type { return FLOAT }
+
+// Inspect returns a stringified version of the object for debugging.
+// Floats with an integral value are always displayed with a trailing `.0`
+// to distinguish them from integers.
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if math.IsInf(f.Value, 0) || math.IsNaN(f.Value) {
+		return s
+	}
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
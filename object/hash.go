@@ -2,8 +2,10 @@ package object
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"hash/fnv"
+	"math"
 	"strings"
 )
 
@@ -32,6 +34,16 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+// HashKey returns a HashKey object
+func (f *Float) HashKey() HashKey {
+	// Normalize -0.0 so that it hashes the same as 0.0
+	value := f.Value
+	if value == 0 {
+		value = 0
+	}
+	return HashKey{Type: f.Type(), Value: math.Float64bits(value)}
+}
+
 // HashKey returns a HashKey object
 func (s *String) HashKey() HashKey {
 	h := fnv.New64a()
@@ -40,49 +52,201 @@ func (s *String) HashKey() HashKey {
 	return HashKey{Type: s.Type(), Value: h.Sum64()}
 }
 
+// HashKey returns a HashKey object combining the hash keys of the elements,
+// which must all be hashable (see AsHashable) for equal arrays to hash the same
+func (ao *Array) HashKey() HashKey {
+	h := fnv.New64a()
+	var buf [8]byte
+	for _, e := range ao.Elements {
+		h.Write([]byte(e.Type()))
+		if e, ok := e.(Hashable); ok {
+			binary.LittleEndian.PutUint64(buf[:], e.HashKey().Value)
+			h.Write(buf[:])
+		}
+	}
+
+	return HashKey{Type: ao.Type(), Value: h.Sum64()}
+}
+
+// AsHashable returns obj as a Hashable and true if it can be used as a hash
+// key, arrays can only be used as keys if all of their elements can be
+func AsHashable(obj Object) (Hashable, bool) {
+	if array, ok := obj.(*Array); ok {
+		for _, e := range array.Elements {
+			if _, ok := AsHashable(e); !ok {
+				return nil, false
+			}
+		}
+	}
+	hashable, ok := obj.(Hashable)
+	return hashable, ok
+}
+
+// equalKeys returns true if the keys a and b with the same hash key are
+// equal, keys whose hashes collide are distinguished by their values
+func equalKeys(a, b Object) bool {
+	if a == b {
+		return true
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *Array:
+		b := b.(*Array)
+		if len(a.Elements) != len(b.Elements) {
+			return false
+		}
+		for i, e := range a.Elements {
+			if !equalKeys(e, b.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case Comparable:
+		return a.Compare(b) == 0
+	}
+	return false
+}
+
+// freezeKey returns a copy of array keys (and the arrays they contain) so
+// modifying an array after using it as a key does not change the key
+func freezeKey(key Hashable) Hashable {
+	array, ok := key.(*Array)
+	if !ok {
+		return key
+	}
+
+	elements := make([]Object, len(array.Elements))
+	for i, e := range array.Elements {
+		if array, ok := e.(*Array); ok {
+			elements[i] = freezeKey(array)
+		} else {
+			elements[i] = e
+		}
+	}
+	return &Array{Elements: elements}
+}
+
 // HashPair is an object that holds a key and value of type Object
 type HashPair struct {
 	Key   Object
 	Value Object
 }
 
-// Hash is a hash map and holds a map of HashKey to HashPair(s)
+// Hash is a hash map of keys to values which preserves the order in which
+// keys were first inserted, used when printing and iterating over the hash,
+// with constant time lookups by key. Keys are looked up by their hash key and
+// then compared by value so keys whose hashes collide are kept apart. The zero
+// value is an empty hash.
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	pairs []HashPair
+	index map[HashKey][]int // indexes in pairs of the keys with each hash key
+}
+
+// NewHash returns a new empty hash with room for size pairs
+func NewHash(size int) *Hash {
+	return &Hash{
+		pairs: make([]HashPair, 0, size),
+		index: make(map[HashKey][]int, size),
+	}
+}
+
+// Get returns the pair of the key and its value and true, or false if the
+// key is not in the hash
+func (h *Hash) Get(key Hashable) (HashPair, bool) {
+	if i, ok := h.lookup(key, key.HashKey()); ok {
+		return h.pairs[i], true
+	}
+	return HashPair{}, false
+}
+
+// Set sets the value of the key, keys not in the hash are added after
+// those already in the hash and keys in the hash keep their position
+func (h *Hash) Set(key Hashable, value Object) {
+	hashed := key.HashKey()
+	if i, ok := h.lookup(key, hashed); ok {
+		h.pairs[i].Value = value
+		return
+	}
+
+	if h.index == nil {
+		h.index = make(map[HashKey][]int)
+	}
+	h.index[hashed] = append(h.index[hashed], len(h.pairs))
+	h.pairs = append(h.pairs, HashPair{Key: freezeKey(key), Value: value})
+}
+
+// lookup returns the index in pairs of the key with the given hash key and
+// true, or false if the key is not in the hash
+func (h *Hash) lookup(key Hashable, hashed HashKey) (int, bool) {
+	for _, i := range h.index[hashed] {
+		if equalKeys(h.pairs[i].Key, key) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Pairs returns the pairs of keys and values in the order the keys were
+// inserted, the pairs must not be modified
+func (h *Hash) Pairs() []HashPair {
+	return h.pairs
+}
+
+// Keys returns the keys in the order they were inserted
+func (h *Hash) Keys() []Object {
+	keys := make([]Object, len(h.pairs))
+	for i, pair := range h.pairs {
+		keys[i] = pair.Key
+	}
+	return keys
+}
+
+// Values returns the values in the order their keys were inserted
+func (h *Hash) Values() []Object {
+	values := make([]Object, len(h.pairs))
+	for i, pair := range h.pairs {
+		values[i] = pair.Value
+	}
+	return values
 }
 
 func (h *Hash) Len() int {
-	return len(h.Pairs)
+	return len(h.pairs)
 }
 
 func (h *Hash) Bool() bool {
-	return len(h.Pairs) > 0
+	return len(h.pairs) > 0
 }
 
+// Compare returns 0 if the other object is a hash with the same keys, in any
+// order, bound to equal values and non-zero otherwise
 func (h *Hash) Compare(other Object) int {
-	if obj, ok := other.(*Hash); ok {
-		if len(h.Pairs) != len(obj.Pairs) {
+	obj, ok := other.(*Hash)
+	if !ok || h.Len() != obj.Len() {
+		return -1
+	}
+
+	for _, pair := range h.pairs {
+		right, ok := obj.Get(pair.Key.(Hashable))
+		if !ok {
 			return -1
 		}
-		for _, pair := range h.Pairs {
-			left := pair.Value
-			hashed := left.(Hashable)
-			right, ok := obj.Pairs[hashed.HashKey()]
-			if !ok {
-				return -1
-			}
-			cmp, ok := left.(Comparable)
-			if !ok {
-				return -1
-			}
-			if cmp.Compare(right.Value) != 0 {
-				return cmp.Compare(right.Value)
-			}
+		if pair.Value == right.Value {
+			continue
+		}
+		cmp, ok := pair.Value.(Comparable)
+		if !ok {
+			return -1
+		}
+		if n := cmp.Compare(right.Value); n != 0 {
+			return n
 		}
-
-		return 0
 	}
-	return -1
+
+	return 0
 }
 
 func (h *Hash) String() string {
@@ -92,12 +256,13 @@ func (h *Hash) String() string {
 // Type returns the type of the object
 func (h *Hash) Type() Type { return HASH }
 
-// Inspect returns a stringified version of the object for debugging
+// Inspect returns a stringified version of the object for debugging with
+// the pairs in the order the keys were inserted
 func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for _, pair := range h.Pairs {
+	for _, pair := range h.pairs {
 		pairs = append(pairs, fmt.Sprintf("%s: %s",
 			pair.Key.Inspect(), pair.Value.Inspect()))
 	}
@@ -25,6 +25,9 @@ func (i *Integer) Compare(other Object) int {
 			return 0
 		}
 	}
+	if obj, ok := other.(*Float); ok {
+		return -obj.Compare(i)
+	}
 	return -1
 }
 
@@ -0,0 +1,109 @@
+package object
+
+import (
+	"unicode/utf8"
+)
+
+// Iterable is the interface for all objects that can be iterated over with
+// a `for ... in` loop and must implement the Iter() method which returns a
+// new Iterator positioned at the first element.
+type Iterable interface {
+	Iter() Iterator
+}
+
+// Iterator is the interface for iterating over the elements of an Iterable.
+// Next() returns the key (index) and value of the next element and `false`
+// once the iterator is exhausted.
+type Iterator interface {
+	Object
+	Next() (key, value Object, ok bool)
+}
+
+// iterator implements the Object interface for all iterators
+type iterator struct{}
+
+func (it *iterator) Bool() bool {
+	return true
+}
+
+func (it *iterator) String() string {
+	return it.Inspect()
+}
+
+// Type returns the type of the object
+func (it *iterator) Type() Type { return ITERATOR }
+
+// Inspect returns a stringified version of the object for debugging
+func (it *iterator) Inspect() string { return "<iterator>" }
+
+// arrayIterator iterates over the index and elements of an array
+type arrayIterator struct {
+	iterator
+	array *Array
+	i     int
+}
+
+func (it *arrayIterator) Next() (Object, Object, bool) {
+	if it.i >= len(it.array.Elements) {
+		return nil, nil, false
+	}
+	key := &Integer{Value: int64(it.i)}
+	value := it.array.Elements[it.i]
+	it.i++
+	return key, value, true
+}
+
+// Iter returns a new iterator over the indexes and elements of the array
+func (a *Array) Iter() Iterator {
+	return &arrayIterator{array: a}
+}
+
+// stringIterator iterates over the index and characters of a string
+type stringIterator struct {
+	iterator
+	value  string
+	offset int
+	i      int
+}
+
+func (it *stringIterator) Next() (Object, Object, bool) {
+	if it.offset >= len(it.value) {
+		return nil, nil, false
+	}
+	r, size := utf8.DecodeRuneInString(it.value[it.offset:])
+	key := &Integer{Value: int64(it.i)}
+	value := &String{Value: string(r)}
+	it.offset += size
+	it.i++
+	return key, value, true
+}
+
+// Iter returns a new iterator over the indexes and characters of the string
+func (s *String) Iter() Iterator {
+	return &stringIterator{value: s.Value}
+}
+
+// hashIterator iterates over the keys and values of a hash
+type hashIterator struct {
+	iterator
+	pairs []HashPair
+	i     int
+}
+
+func (it *hashIterator) Next() (Object, Object, bool) {
+	if it.i >= len(it.pairs) {
+		return nil, nil, false
+	}
+	pair := it.pairs[it.i]
+	it.i++
+	return pair.Key, pair.Value, true
+}
+
+// Iter returns a new iterator over the keys and values of the hash in the
+// order the keys were inserted. Changes to the hash during iteration are not
+// observed.
+func (h *Hash) Iter() Iterator {
+	pairs := make([]HashPair, len(h.pairs))
+	copy(pairs, h.pairs)
+	return &hashIterator{pairs: pairs}
+}
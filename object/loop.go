@@ -0,0 +1,39 @@
+package object
+
+// Break is the break type and used to signal a `break` statement. It is
+// tracked through the evaluator and when encountered stops evaluation of
+// the body of the innermost loop and exits the loop.
+type Break struct{}
+
+func (b *Break) Bool() bool {
+	return true
+}
+
+func (b *Break) String() string {
+	return b.Inspect()
+}
+
+// Type returns the type of the object
+func (b *Break) Type() Type { return BREAK }
+
+// Inspect returns a stringified version of the object for debugging
+func (b *Break) Inspect() string { return "break" }
+
+// Continue is the continue type and used to signal a `continue` statement.
+// It is tracked through the evaluator and when encountered stops evaluation
+// of the body of the innermost loop and continues with the next iteration.
+type Continue struct{}
+
+func (c *Continue) Bool() bool {
+	return true
+}
+
+func (c *Continue) String() string {
+	return c.Inspect()
+}
+
+// Type returns the type of the object
+func (c *Continue) Type() Type { return CONTINUE }
+
+// Inspect returns a stringified version of the object for debugging
+func (c *Continue) Inspect() string { return "continue" }
@@ -12,6 +12,9 @@ const (
 	// INTEGER is the Integer object type
 	INTEGER = "int"
 
+	// FLOAT is the Float object type
+	FLOAT = "float"
+
 	// STRING is the String object type
 	STRING = "str"
 
@@ -24,6 +27,12 @@ const (
 	// RETURN is the Return object type
 	RETURN = "return"
 
+	// BREAK is the Break object type
+	BREAK = "break"
+
+	// CONTINUE is the Continue object type
+	CONTINUE = "continue"
+
 	// ERROR is the Error object type
 	ERROR = "error"
 
@@ -45,8 +54,17 @@ const (
 	// HASH is the Hash object type
 	HASH = "hash"
 
+	// ITERATOR is the Iterator object type
+	ITERATOR = "iterator"
+
+	// RANGE is the Range object type
+	RANGE = "range"
+
 	// MODULE is the Module object type
 	MODULE = "module"
+
+	// CHANNEL is the Channel object type
+	CHANNEL = "channel"
 )
 
 // Comparable is the interface for comparing two Object and their underlying
@@ -72,11 +90,13 @@ type Immutable interface {
 // Hashable is the interface for all hashable objects which must implement
 // the HashKey() method which reutrns a HashKey result.
 type Hashable interface {
+	Object
 	HashKey() HashKey
 }
 
-// BuiltinFunction represents the builtin function type
-type BuiltinFunction func(args ...Object) Object
+// BuiltinFunction represents the builtin function type which is called with
+// the execution context of the program calling it
+type BuiltinFunction func(ctx *Context, args ...Object) Object
 
 // Type represents the type of an object
 type Type string
@@ -1,7 +1,11 @@
 package object
 
 import (
+	"io"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestStringHashKey(t *testing.T) {
@@ -22,3 +26,331 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+// collider is a hashable object whose hash keys all collide
+type collider struct {
+	name string
+}
+
+func (c *collider) Type() Type       { return "collider" }
+func (c *collider) Bool() bool       { return true }
+func (c *collider) Inspect() string  { return c.name }
+func (c *collider) String() string   { return c.name }
+func (c *collider) HashKey() HashKey { return HashKey{Type: c.Type(), Value: 1} }
+
+func TestHashCollisions(t *testing.T) {
+	a, b := &collider{"a"}, &collider{"b"}
+
+	hash := NewHash(0)
+	hash.Set(a, &Integer{Value: 1})
+	hash.Set(b, &Integer{Value: 2})
+	hash.Set(a, &Integer{Value: 3})
+
+	if hash.Len() != 2 {
+		t.Fatalf("Len: want=2, got=%d", hash.Len())
+	}
+	for key, expected := range map[Hashable]int64{a: 3, b: 2} {
+		pair, ok := hash.Get(key)
+		if !ok || pair.Value.(*Integer).Value != expected {
+			t.Errorf("Get(%s): want=%d, got=%v", key, expected, pair.Value)
+		}
+	}
+	if _, ok := hash.Get(&collider{"c"}); ok {
+		t.Errorf("Get: want no pair for missing key")
+	}
+}
+
+func TestArrayHashKey(t *testing.T) {
+	array := func(elements ...Object) *Array {
+		return &Array{Elements: elements}
+	}
+	one, a := &Integer{Value: 1}, &String{Value: "a"}
+
+	if array(one, a).HashKey() != array(&Integer{Value: 1}, &String{Value: "a"}).HashKey() {
+		t.Errorf("arrays with same elements have different hash keys")
+	}
+	if array(one, a).HashKey() == array(a, one).HashKey() {
+		t.Errorf("arrays with different elements have same hash keys")
+	}
+	if array(one).HashKey() == array(&Float{Value: 1}).HashKey() {
+		t.Errorf("arrays with elements of different types have same hash keys")
+	}
+
+	if _, ok := AsHashable(array(one, array(a))); !ok {
+		t.Errorf("AsHashable: want array of hashable elements to be hashable")
+	}
+	if _, ok := AsHashable(array(one, array(&Null{}))); ok {
+		t.Errorf("AsHashable: want array of unhashable elements to be unhashable")
+	}
+
+	key := array(one, array(a))
+	hash := NewHash(1)
+	hash.Set(key, one)
+	key.Elements[1].(*Array).Elements[0] = one
+	if _, ok := hash.Get(array(one, array(a))); !ok {
+		t.Errorf("Get: want key unchanged by modifying the array used as key")
+	}
+}
+
+func TestContextReadLine(t *testing.T) {
+	ctx := &Context{Stdin: strings.NewReader("one\r\ntwo\nthree")}
+
+	for _, expected := range []string{"one", "two", "three"} {
+		line, err := ctx.ReadLine()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if line != expected {
+			t.Errorf("line: want=%q, got=%q", expected, line)
+		}
+	}
+
+	if _, err := ctx.ReadLine(); err != io.EOF {
+		t.Errorf("err: want=%v, got=%v", io.EOF, err)
+	}
+
+	// Input is read from Stdin when changed
+	ctx.Stdin = strings.NewReader("four\n")
+	if line, _ := ctx.ReadLine(); line != "four" {
+		t.Errorf("line: want=%q, got=%q", "four", line)
+	}
+}
+
+func TestContextGetenv(t *testing.T) {
+	ctx := &Context{Env: []string{"HOME=/root", "PATH=/bin", "HOME=/home/monkey", "EMPTY="}}
+
+	tests := []struct {
+		key      string
+		expected string
+		ok       bool
+	}{
+		{"HOME", "/home/monkey", true},
+		{"PATH", "/bin", true},
+		{"EMPTY", "", true},
+		{"PATHS", "", false},
+		{"USER", "", false},
+	}
+
+	for _, tt := range tests {
+		value, ok := ctx.Getenv(tt.key)
+		if value != tt.expected || ok != tt.ok {
+			t.Errorf("%s: want=%q, %t got=%q, %t", tt.key, tt.expected, tt.ok, value, ok)
+		}
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Capability
+	}{
+		{"", 0},
+		{"filesystem", FilesystemCapability},
+		{"network, ffi", NetworkCapability | FFICapability},
+		{"process,filesystem,", FilesystemCapability | ProcessCapability},
+		{"filesystem,network,ffi,process", AllCapabilities},
+	}
+
+	for _, tt := range tests {
+		c, err := ParseCapabilities(tt.input)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %s", tt.input, err)
+		}
+		if c != tt.expected {
+			t.Errorf("%q: want=%s, got=%s", tt.input, tt.expected, c)
+		}
+	}
+
+	if _, err := ParseCapabilities("filesystem,disk"); err == nil {
+		t.Errorf("expected error parsing unknown capability")
+	}
+
+	if s := (FilesystemCapability | ProcessCapability).String(); s != "filesystem,process" {
+		t.Errorf("String: want=%q, got=%q", "filesystem,process", s)
+	}
+}
+
+func TestBuiltinCall(t *testing.T) {
+	builtin := &Builtin{
+		Name:       "net",
+		Fn:         func(ctx *Context, args ...Object) Object { return &Integer{Value: 1} },
+		Capability: FilesystemCapability | NetworkCapability,
+	}
+
+	tests := []struct {
+		ctx      *Context
+		expected string
+	}{
+		{&Context{}, "1"},
+		{&Context{Sandbox: true, Capabilities: NetworkCapability}, "1"},
+		{&Context{Sandbox: true, Capabilities: FilesystemCapability | ProcessCapability}, "1"},
+		{
+			&Context{Sandbox: true, Capabilities: ProcessCapability},
+			"PermissionError: net() requires the filesystem or network capability",
+		},
+	}
+
+	for _, tt := range tests {
+		if actual := builtin.Call(tt.ctx).String(); actual != tt.expected {
+			t.Errorf("want=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestHashOrder(t *testing.T) {
+	hash := NewHash(0)
+	hash.Set(&String{Value: "b"}, &Integer{Value: 1})
+	hash.Set(&Integer{Value: 2}, &Integer{Value: 2})
+	hash.Set(&String{Value: "a"}, &Integer{Value: 3})
+	hash.Set(&String{Value: "b"}, &Integer{Value: 4})
+
+	expected := `{"b": 4, 2: 2, "a": 3}`
+	if hash.Inspect() != expected {
+		t.Errorf("Inspect: want=%q, got=%q", expected, hash.Inspect())
+	}
+
+	if hash.Len() != 3 {
+		t.Errorf("Len: want=3, got=%d", hash.Len())
+	}
+
+	pair, ok := hash.Get(&String{Value: "a"})
+	if !ok || pair.Value.(*Integer).Value != 3 {
+		t.Errorf("Get: want=3, got=%v", pair.Value)
+	}
+	if _, ok := hash.Get(&String{Value: "c"}); ok {
+		t.Errorf("Get: want no pair for missing key")
+	}
+
+	var zero Hash
+	zero.Set(&Boolean{Value: true}, &String{Value: "yes"})
+	if zero.Inspect() != `{true: "yes"}` {
+		t.Errorf("Inspect: want=%q, got=%q", `{true: "yes"}`, zero.Inspect())
+	}
+}
+
+func TestHashCompare(t *testing.T) {
+	hash := func(pairs ...Object) *Hash {
+		h := NewHash(len(pairs) / 2)
+		for i := 0; i < len(pairs); i += 2 {
+			h.Set(pairs[i].(Hashable), pairs[i+1])
+		}
+		return h
+	}
+	a, b, one, two := &String{Value: "a"}, &String{Value: "b"}, &Integer{Value: 1}, &Integer{Value: 2}
+
+	tests := []struct {
+		left, right *Hash
+		equal       bool
+	}{
+		{hash(), hash(), true},
+		{hash(a, one, b, two), hash(b, two, a, one), true},
+		{hash(a, one), hash(a, two), false},
+		{hash(a, one), hash(b, one), false},
+		{hash(a, one), hash(one, a), false},
+		{hash(a, one), hash(a, one, b, two), false},
+		{hash(a, hash(b, one)), hash(a, hash(b, one)), true},
+	}
+
+	for _, tt := range tests {
+		if equal := tt.left.Compare(tt.right) == 0; equal != tt.equal {
+			t.Errorf("%s == %s: want=%t, got=%t", tt.left, tt.right, tt.equal, equal)
+		}
+	}
+}
+
+func TestChannel(t *testing.T) {
+	ctx := &Context{}
+	c := NewChannel(1)
+
+	if err := c.Send(ctx, &Integer{Value: 1}); err != nil {
+		t.Fatalf("unexpected error sending: %s", err)
+	}
+	if c.Len() != 1 {
+		t.Errorf("Len: want=1, got=%d", c.Len())
+	}
+
+	cases := []SelectCase{{Channel: c, Value: &Integer{Value: 2}}}
+	if chosen, _, _ := Select(ctx, cases, false); chosen != -1 {
+		t.Errorf("Select on full channel: want=-1, got=%d", chosen)
+	}
+
+	cases = append(cases, SelectCase{Channel: c})
+	chosen, obj, err := Select(ctx, cases, false)
+	if err != nil {
+		t.Fatalf("unexpected error selecting: %s", err)
+	}
+	if chosen != 1 || obj.String() != "1" {
+		t.Errorf("Select: want=(1, 1), got=(%d, %s)", chosen, obj)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %s", err)
+	}
+	if _, ok := c.Recv(ctx); ok {
+		t.Errorf("expected Recv on closed channel to fail")
+	}
+	if err := c.Send(ctx, &Integer{Value: 3}); err == nil {
+		t.Errorf("expected error sending on closed channel")
+	}
+	if err := c.Close(); err == nil {
+		t.Errorf("expected error closing closed channel")
+	}
+}
+
+func TestEventLoop(t *testing.T) {
+	var calls []string
+	ctx := &Context{}
+	ctx.Call = func(fn Object, args ...Object) Object {
+		return fn.(*Builtin).Fn(ctx, args...)
+	}
+	callback := func(name string) *Builtin {
+		return &Builtin{Name: name, Fn: func(ctx *Context, args ...Object) Object {
+			s := name
+			if len(args) > 0 {
+				// Read what is ready so the loop stops calling back
+				syscall.Read(int(args[0].(*Integer).Value), make([]byte, 1))
+				s += " " + args[1].String()
+			}
+			calls = append(calls, s)
+			return nil
+		}}
+	}
+
+	var fds [2]int
+	if err := syscall.Pipe(fds[:]); err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(fds[0])
+	defer syscall.Close(fds[1])
+
+	loop := ctx.Loop()
+	if err := loop.Register(fds[0], ReadEvent, callback("read")); err != nil {
+		t.Fatalf("unexpected error registering: %s", err)
+	}
+	loop.AddTimer(10*time.Millisecond, false, &Builtin{Fn: func(ctx *Context, args ...Object) Object {
+		calls = append(calls, "timer")
+		syscall.Write(fds[1], []byte("x"))
+		return nil
+	}})
+	loop.AddTimer(20*time.Millisecond, false, &Builtin{Fn: func(ctx *Context, args ...Object) Object {
+		ctx.Loop().Unregister(fds[0])
+		return nil
+	}})
+	cancelled := loop.AddTimer(0, false, callback("cancelled"))
+	if !loop.Cancel(cancelled) || loop.Cancel(cancelled) {
+		t.Errorf("expected timer to be cancelled once")
+	}
+
+	if err := loop.Run(ctx); err != nil {
+		t.Fatalf("unexpected error running: %s", err)
+	}
+
+	expected := []string{"timer", "read r"}
+	if strings.Join(calls, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("calls: want=%q, got=%q", expected, calls)
+	}
+
+	if e, err := ParseEvents("wr"); err != nil || e != ReadEvent|WriteEvent || e.String() != "rw" {
+		t.Errorf("ParseEvents: want=rw, got=%s (%v)", e, err)
+	}
+}
@@ -0,0 +1,73 @@
+package object
+
+import (
+	"fmt"
+)
+
+// Range is a lazy sequence of integers from Start up to (but excluding)
+// Stop in increments of Step. The integers are produced on demand while
+// iterating and never stored.
+type Range struct {
+	Start int64
+	Stop  int64
+	Step  int64
+}
+
+func (r *Range) Len() int {
+	switch {
+	case r.Step > 0 && r.Start < r.Stop:
+		return int((r.Stop - r.Start + r.Step - 1) / r.Step)
+	case r.Step < 0 && r.Start > r.Stop:
+		return int((r.Start - r.Stop - r.Step - 1) / -r.Step)
+	default:
+		return 0
+	}
+}
+
+func (r *Range) Bool() bool {
+	return r.Len() > 0
+}
+
+func (r *Range) Compare(other Object) int {
+	if obj, ok := other.(*Range); ok {
+		if r.Start == obj.Start && r.Stop == obj.Stop && r.Step == obj.Step {
+			return 0
+		}
+	}
+	return -1
+}
+
+func (r *Range) String() string {
+	return r.Inspect()
+}
+
+// Type returns the type of the object
+func (r *Range) Type() Type { return RANGE }
+
+// Inspect returns a stringified version of the object for debugging
+func (r *Range) Inspect() string {
+	return fmt.Sprintf("range(%d, %d, %d)", r.Start, r.Stop, r.Step)
+}
+
+// rangeIterator iterates over the integers of a range
+type rangeIterator struct {
+	iterator
+	r *Range
+	i int64
+}
+
+func (it *rangeIterator) Next() (Object, Object, bool) {
+	value := it.r.Start + it.i*it.r.Step
+	if (it.r.Step > 0 && value >= it.r.Stop) ||
+		(it.r.Step < 0 && value <= it.r.Stop) {
+		return nil, nil, false
+	}
+	key := &Integer{Value: it.i}
+	it.i++
+	return key, &Integer{Value: value}, true
+}
+
+// Iter returns a new iterator over the indexes and integers of the range
+func (r *Range) Iter() Iterator {
+	return &rangeIterator{r: r}
+}
@@ -122,6 +122,38 @@ func TestReturnStatements(t *testing.T) {
 	}
 }
 
+func TestBreakContinueStatements(t *testing.T) {
+	input := `while (true) { break; continue }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+			len(program.Statements))
+	}
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.WhileExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.WhileExpression. got=%T",
+			stmt.Expression)
+	}
+
+	body := exp.Consequence.Statements
+	if len(body) != 2 {
+		t.Fatalf("consequence is not 2 statements. got=%d\n", len(body))
+	}
+	if _, ok := body[0].(*ast.BreakStatement); !ok {
+		t.Errorf("body[0] is not ast.BreakStatement. got=%T", body[0])
+	}
+	if _, ok := body[1].(*ast.ContinueStatement); !ok {
+		t.Errorf("body[1] is not ast.ContinueStatement. got=%T", body[1])
+	}
+}
+
 func TestIdentifierExpression(t *testing.T) {
 	input := "foobar;"
 
@@ -184,6 +216,37 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestFloatLiteralExpression(t *testing.T) {
+	input := "3.14;"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program has not enough statements. got=%d",
+			len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	literal, ok := stmt.Expression.(*ast.FloatLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+	}
+	if literal.Value != 3.14 {
+		t.Errorf("literal.Value not %g. got=%g", 3.14, literal.Value)
+	}
+	if literal.TokenLiteral() != "3.14" {
+		t.Errorf("literal.TokenLiteral not %s. got=%s", "3.14",
+			literal.TokenLiteral())
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -576,6 +639,166 @@ func TestWhileExpression(t *testing.T) {
 	}
 }
 
+func TestForExpression(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedKey   string
+		expectedValue string
+	}{
+		{`for x in xs { x }`, "", "x"},
+		{`for k, v in xs { x }`, "k", "v"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+				1, len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+				program.Statements[0])
+		}
+
+		exp, ok := stmt.Expression.(*ast.ForExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.ForExpression. got=%T",
+				stmt.Expression)
+		}
+
+		if tt.expectedKey == "" {
+			if exp.Key != nil {
+				t.Errorf("exp.Key is not nil. got=%s", exp.Key)
+			}
+		} else if !testIdentifier(t, exp.Key, tt.expectedKey) {
+			return
+		}
+
+		if !testIdentifier(t, exp.Value, tt.expectedValue) {
+			return
+		}
+
+		if !testIdentifier(t, exp.Iterable, "xs") {
+			return
+		}
+
+		if len(exp.Body.Statements) != 1 {
+			t.Errorf("body is not 1 statements. got=%d\n",
+				len(exp.Body.Statements))
+		}
+	}
+}
+
+func TestTryExpression(t *testing.T) {
+	tests := []struct {
+		input      string
+		catchParam string
+		hasCatch   bool
+		hasFinally bool
+	}{
+		{`try { x } catch (e) { e }`, "e", true, false},
+		{`try { x } catch { y }`, "", true, false},
+		{`try { x } finally { y }`, "", false, true},
+		{`try { x } catch (err) { y } finally { z }`, "err", true, true},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+				1, len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+				program.Statements[0])
+		}
+
+		exp, ok := stmt.Expression.(*ast.TryExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.TryExpression. got=%T",
+				stmt.Expression)
+		}
+
+		if len(exp.Block.Statements) != 1 {
+			t.Errorf("block is not 1 statements. got=%d\n",
+				len(exp.Block.Statements))
+		}
+
+		if tt.catchParam == "" {
+			if exp.CatchParam != nil {
+				t.Errorf("exp.CatchParam is not nil. got=%s", exp.CatchParam)
+			}
+		} else if !testIdentifier(t, exp.CatchParam, tt.catchParam) {
+			return
+		}
+
+		if (exp.Catch != nil) != tt.hasCatch {
+			t.Errorf("exp.Catch wrong. expected present=%t got=%s",
+				tt.hasCatch, exp.Catch)
+		}
+
+		if (exp.Finally != nil) != tt.hasFinally {
+			t.Errorf("exp.Finally wrong. expected present=%t got=%s",
+				tt.hasFinally, exp.Finally)
+		}
+	}
+}
+
+func TestTryExpressionWithoutHandler(t *testing.T) {
+	l := lexer.New(`try { x }`)
+	p := New(l)
+	p.ParseProgram()
+
+	if len(p.Errors()) == 0 {
+		t.Fatalf("expected parser errors for try without catch or finally")
+	}
+}
+
+func TestThrowExpression(t *testing.T) {
+	l := lexer.New(`throw "oops"`)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	exp, ok := stmt.Expression.(*ast.ThrowExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.ThrowExpression. got=%T",
+			stmt.Expression)
+	}
+
+	literal, ok := exp.Value.(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf("exp.Value is not ast.StringLiteral. got=%T", exp.Value)
+	}
+
+	if literal.Value != "oops" {
+		t.Errorf("literal.Value not %q. got=%q", "oops", literal.Value)
+	}
+}
+
 func TestIfElseExpression(t *testing.T) {
 	input := `if (x < y) { x } else { y }`
 
@@ -1322,3 +1545,132 @@ func TestParsingImportExpressions(t *testing.T) {
 		assert.Equal(tt.expected, program.String())
 	}
 }
+
+func TestParsingSpawnAndSelectExpressions(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"spawn f(1, 2)", "spawn f(1, 2)"},
+		{"spawn fn() { x }", "spawn fn () x"},
+		{"select { case recv(c) { 1 } }", "select { case recv(c) 1 }"},
+		{"select { case v := recv(c) { v } }", "select { case v := recv(c) v }"},
+		{"select { case send(c, 1) { 2 } default { 3 } }", "select { case send(c, 1) 2 default 3 }"},
+		{"select {\n  # comment\n  default {}\n}", "select { default  }"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		assert.Equal(tt.expected, program.String(), tt.input)
+	}
+
+	program := New(lexer.New("select { case v := recv(c) {} case send(d, 1) {} }")).ParseProgram()
+	exp := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.SelectExpression)
+	if assert.Len(exp.Cases, 2) {
+		assert.Equal("v", exp.Cases[0].Name.Value)
+		assert.Equal("c", exp.Cases[0].Channel.String())
+		assert.Nil(exp.Cases[0].Value)
+		assert.Nil(exp.Cases[1].Name)
+		assert.Equal("d", exp.Cases[1].Channel.String())
+		assert.Equal("1", exp.Cases[1].Value.String())
+	}
+	assert.Nil(exp.Default)
+}
+
+func TestParsingTypeAnnotations(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x: int := 1", "x: int :=1"},
+		{"xs: [str] := []", "xs: [str] :=[]"},
+		{"h: {str: [int]} := {}", "h: {str: [int]} :={}"},
+		{"f: fn(int, str) -> bool := g", "f: fn(int, str) -> bool :=g"},
+		{"n: null := null", "n: null :=null"},
+		{"fn(x: int, y) -> str { x }", "fn (x: int, y) -> str x"},
+		{"fn(x, y) { x }", "fn (x, y) x"},
+		{"f := fn() -> fn() -> int { g }", "f:=fn f() -> fn() -> int g"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		assert.Equal(tt.expected, program.String(), tt.input)
+	}
+
+	program := New(lexer.New("fn(x, y: int) {}")).ParseProgram()
+	fn := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if assert.Len(fn.ParameterTypes, 2) {
+		assert.Nil(fn.ParameterTypes[0])
+		assert.Equal("int", fn.ParameterTypes[1].String())
+	}
+
+	program = New(lexer.New("fn(x, y) {}")).ParseProgram()
+	fn = program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	assert.Nil(fn.ParameterTypes)
+}
+
+func TestParserErrorPositions(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x := (1 + 2", "test.monkey:1:12: expected next token to be ), got EOF instead"},
+		{"x := 1\ny := )", "test.monkey:2:6: no prefix parse function for ) found"},
+		{"if (x) {\n  [1, 2\n}", "test.monkey:3:1: expected next token to be ], got } instead"},
+		{"x: 1 := 2", "test.monkey:1:4: expected type, got INT instead"},
+		{"f := fn(x: [int) {}", "test.monkey:1:16: expected next token to be ], got ) instead"},
+		{"select { x }", "test.monkey:1:10: expected case or default in select, got IDENT instead"},
+		{"select { case f(c) {} }", "test.monkey:1:15: expected recv(channel) or send(channel, value) in select case"},
+		{"select { default {} default {} }", "test.monkey:1:21: multiple defaults in select"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewWithFilename(tt.input, "test.monkey")
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if assert.NotEmpty(errors) {
+			assert.Equal(tt.expected, errors[0])
+		}
+	}
+}
+
+func TestNodePositions(t *testing.T) {
+	assert := assert.New(t)
+
+	input := `add := fn(x, y) {
+  x + y
+}`
+
+	l := lexer.NewWithFilename(input, "test.monkey")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	bind := stmt.Expression.(*ast.BindExpression)
+	assert.Equal("test.monkey:1:5", bind.Pos().String())
+
+	function := bind.Value.(*ast.FunctionLiteral)
+	assert.Equal("test.monkey:1:8", function.Pos().String())
+	assert.Equal("test.monkey:1:11", function.Parameters[0].Pos().String())
+
+	body := function.Body.Statements[0].(*ast.ExpressionStatement)
+	assert.Equal("test.monkey:2:3", body.Pos().String())
+	assert.Equal("test.monkey:2:5", body.Expression.Pos().String())
+}
@@ -5,6 +5,6 @@ import (
 )
 
 // Hello ...
-func Hello(args ...Object) Object {
+func Hello(ctx *Context, args ...Object) Object {
 	return &String{Value: "Hello World!"}
 }
@@ -10,8 +10,10 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 
 	"github.com/prologic/monkey-lang/compiler"
+	"github.com/prologic/monkey-lang/debugger"
 	"github.com/prologic/monkey-lang/eval"
 	"github.com/prologic/monkey-lang/lexer"
 	"github.com/prologic/monkey-lang/object"
@@ -39,28 +41,46 @@ const MonkeyFace = `            __,__
 
 type Options struct {
 	Debug       bool
+	Debugger    bool
 	Engine      string
 	Interactive bool
+	Optimize    bool
+
+	// StackSize and MaxFrames are the limits of the VM's stack and frames,
+	// the defaults are used if zero
+	StackSize int
+	MaxFrames int
+
+	// Sandbox restricts the builtins available to programs to the groups
+	// given by Capabilities
+	Sandbox      bool
+	Capabilities object.Capability
 }
 
 type REPL struct {
 	user string
 	args []string
 	opts *Options
+
+	// ctx is the execution context of the programs run
+	ctx *object.Context
+
+	// failed is true if the program failed to run
+	failed bool
 }
 
 func New(user string, args []string, opts *Options) *REPL {
-	object.StandardInput = os.Stdin
-	object.StandardOutput = os.Stdout
-	object.ExitFunction = os.Exit
+	ctx := object.NewContext()
+	ctx.Sandbox = opts.Sandbox
+	ctx.Capabilities = opts.Capabilities
 
-	return &REPL{user, args, opts}
+	return &REPL{user: user, args: args, opts: opts, ctx: ctx}
 }
 
 // Eval parses and evalulates the program given by f and returns the resulting
 // environment, any errors are printed to stderr
 func (r *REPL) Eval(f io.Reader) (env *object.Environment) {
-	env = object.NewEnvironment()
+	env = object.NewEnvironmentWithContext(r.ctx)
 
 	b, err := ioutil.ReadAll(f)
 	if err != nil {
@@ -68,16 +88,21 @@ func (r *REPL) Eval(f io.Reader) (env *object.Environment) {
 		return
 	}
 
-	l := lexer.New(string(b))
+	l := lexer.NewWithFilename(string(b), sourceName(f))
 	p := parser.New(l)
 
 	program := p.ParseProgram()
 	if len(p.Errors()) != 0 {
 		printParserErrors(os.Stderr, p.Errors())
+		r.failed = true
 		return
 	}
 
-	eval.Eval(program, env)
+	obj := eval.Eval(program, env)
+	if err, ok := obj.(*object.Error); ok {
+		printEvalError(os.Stderr, err)
+		r.failed = true
+	}
 	return
 }
 
@@ -92,20 +117,23 @@ func (r *REPL) Exec(f io.Reader) (state *vm.VMState) {
 
 	state = vm.NewVMState()
 
-	l := lexer.New(string(b))
+	l := lexer.NewWithFilename(string(b), sourceName(f))
 	p := parser.New(l)
 
 	program := p.ParseProgram()
 	if len(p.Errors()) != 0 {
 		printParserErrors(os.Stderr, p.Errors())
+		r.failed = true
 		return
 	}
 
 	c := compiler.NewWithState(state.Symbols, state.Constants)
 	c.Debug = r.opts.Debug
+	c.Optimize = r.opts.Optimize
 	err = c.Compile(program)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Woops! Compilation failed:\n %s\n", err)
+		r.failed = true
 		return
 	}
 
@@ -114,21 +142,73 @@ func (r *REPL) Exec(f io.Reader) (state *vm.VMState) {
 
 	machine := vm.NewWithState(code, state)
 	machine.Debug = r.opts.Debug
+	machine.Options = r.vmOptions()
+	machine.Context = r.ctx
 	err = machine.Run()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Woops! Executing bytecode failed:\n %s\n", err)
+		printRuntimeError(os.Stderr, err)
+		r.failed = true
 		return
 	}
 
 	return
 }
 
+// ExecDebugger parses and compiles the program given by f and executes it
+// in the interactive step debugger reading commands from stdin, any errors
+// are printed to stderr
+func (r *REPL) ExecDebugger(f io.Reader) {
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading source file: %s", err)
+		return
+	}
+
+	console := debugger.NewConsole(os.Stdin, os.Stdout)
+	d, err := debugger.New(sourceName(f), string(b), console.Handle)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Woops! Compilation failed:\n %s\n", err)
+		r.failed = true
+		return
+	}
+	d.StopOnEntry = true
+	d.Context = r.ctx
+
+	if err := d.Run(); err != nil {
+		printRuntimeError(os.Stderr, err)
+		r.failed = true
+		return
+	}
+	fmt.Println("Program exited")
+}
+
+// ExecBytecode loads and executes the compiled bytecode file given by f
+// without lexing, parsing or compiling, any errors are printed to stderr
+func (r *REPL) ExecBytecode(f io.Reader) {
+	code, err := compiler.DecodeBytecode(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Woops! Loading bytecode failed:\n %s\n", err)
+		r.failed = true
+		return
+	}
+
+	machine := vm.New(code)
+	machine.Debug = r.opts.Debug
+	machine.Options = r.vmOptions()
+	machine.Context = r.ctx
+	err = machine.Run()
+	if err != nil {
+		printRuntimeError(os.Stderr, err)
+		r.failed = true
+	}
+}
+
 // StartEvalLoop starts the REPL in a continious eval loop
 func (r *REPL) StartEvalLoop(in io.Reader, out io.Writer, env *object.Environment) {
 	scanner := bufio.NewScanner(in)
 
 	if env == nil {
-		env = object.NewEnvironment()
+		env = object.NewEnvironmentWithContext(r.ctx)
 	}
 
 	for {
@@ -140,7 +220,7 @@ func (r *REPL) StartEvalLoop(in io.Reader, out io.Writer, env *object.Environmen
 
 		line := scanner.Text()
 
-		l := lexer.New(line)
+		l := lexer.NewWithFilename(line, "<stdin>")
 		p := parser.New(l)
 
 		program := p.ParseProgram()
@@ -176,7 +256,7 @@ func (r *REPL) StartExecLoop(in io.Reader, out io.Writer, state *vm.VMState) {
 
 		line := scanner.Text()
 
-		l := lexer.New(line)
+		l := lexer.NewWithFilename(line, "<stdin>")
 		p := parser.New(l)
 
 		program := p.ParseProgram()
@@ -187,6 +267,7 @@ func (r *REPL) StartExecLoop(in io.Reader, out io.Writer, state *vm.VMState) {
 
 		c := compiler.NewWithState(state.Symbols, state.Constants)
 		c.Debug = r.opts.Debug
+		c.Optimize = r.opts.Optimize
 		err := c.Compile(program)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Woops! Compilation failed:\n %s\n", err)
@@ -198,9 +279,11 @@ func (r *REPL) StartExecLoop(in io.Reader, out io.Writer, state *vm.VMState) {
 
 		machine := vm.NewWithState(code, state)
 		machine.Debug = r.opts.Debug
+		machine.Options = r.vmOptions()
+		machine.Context = r.ctx
 		err = machine.Run()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Woops! Executing bytecode failed:\n %s\n", err)
+			printRuntimeError(os.Stderr, err)
 			return
 		}
 
@@ -213,8 +296,8 @@ func (r *REPL) StartExecLoop(in io.Reader, out io.Writer, state *vm.VMState) {
 }
 
 func (r *REPL) Run() {
-	object.Arguments = make([]string, len(r.args))
-	copy(object.Arguments, r.args)
+	r.ctx.Args = make([]string, len(r.args))
+	copy(r.ctx.Args, r.args)
 
 	if len(r.args) == 0 {
 		fmt.Printf("Hello %s! This is the Monkey programming language!\n", r.user)
@@ -235,9 +318,15 @@ func (r *REPL) Run() {
 
 		// Remove program argument (zero)
 		r.args = r.args[1:]
-		object.Arguments = object.Arguments[1:]
-
-		if r.opts.Engine == "eval" {
+		r.ctx.Args = r.ctx.Args[1:]
+
+		if r.opts.Debugger {
+			r.ExecDebugger(f)
+		} else if filepath.Ext(f.Name()) == compiler.BytecodeExtension {
+			// Compiled bytecode can only be executed by the vm and carries
+			// no symbols so there is nothing to continue interactively with
+			r.ExecBytecode(f)
+		} else if r.opts.Engine == "eval" {
 			env := r.Eval(f)
 			if r.opts.Interactive {
 				r.StartEvalLoop(os.Stdin, os.Stdout, env)
@@ -248,9 +337,52 @@ func (r *REPL) Run() {
 				r.StartExecLoop(os.Stdin, os.Stdout, state)
 			}
 		}
+
+		// Programs that fail exit with a non-zero status so failures (e.g. a
+		// failed assert) can be detected by the caller
+		if r.failed && !r.opts.Interactive {
+			os.Exit(1)
+		}
 	}
 }
 
+// vmOptions returns the limits of the VM given by the options
+func (r *REPL) vmOptions() vm.Options {
+	opts := vm.DefaultOptions
+	if r.opts.StackSize > 0 {
+		opts.StackSize = r.opts.StackSize
+	}
+	if r.opts.MaxFrames > 0 {
+		opts.MaxFrames = r.opts.MaxFrames
+	}
+	return opts
+}
+
+// sourceName returns the name of the source file f (if any) used to report
+// the position of errors
+func sourceName(f io.Reader) string {
+	if named, ok := f.(interface{ Name() string }); ok {
+		return named.Name()
+	}
+	return ""
+}
+
+// printRuntimeError prints the stack trace of a runtime error returned by
+// the virtual machine
+func printRuntimeError(out io.Writer, err error) {
+	if e, ok := err.(*vm.Error); ok {
+		io.WriteString(out, e.StackTrace())
+	} else {
+		fmt.Fprintf(out, "Woops! Executing bytecode failed:\n %s\n", err)
+	}
+}
+
+// printEvalError prints an error raised by the evaluator with its source
+// position (if any)
+func printEvalError(out io.Writer, err *object.Error) {
+	fmt.Fprintf(out, "Woops! Evaluation failed:\n %s\n", err.String())
+}
+
 func printParserErrors(out io.Writer, errors []string) {
 	io.WriteString(out, MonkeyFace)
 	io.WriteString(out, "Woops! We ran into some monkey business here!\n")
@@ -0,0 +1,57 @@
+package repl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureStderr returns what f writes to the process's standard error
+func captureStderr(t *testing.T, f func()) string {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = stderr }()
+
+	f()
+	w.Close()
+
+	b, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestEvalErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "monkey-repl")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x := 1\nx + \"a\"", "2:3: TypeError: unknown operator: int + str"},
+		{"x := 1\nfoo", "2:1: NameError: identifier not found: foo"},
+	}
+
+	for _, tt := range tests {
+		filename := filepath.Join(dir, "ee.monkey")
+		require.NoError(t, ioutil.WriteFile(filename, []byte(tt.input), 0644))
+
+		f, err := os.Open(filename)
+		require.NoError(t, err)
+
+		r := New("", nil, &Options{Engine: "eval"})
+		stderr := captureStderr(t, func() { r.Eval(f) })
+		f.Close()
+
+		assert.True(t, r.failed, tt.input)
+		assert.Equal(t, "Woops! Evaluation failed:\n "+filename+":"+tt.expected+"\n", stderr)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// sourceExtension is the file extension of Monkey source files
+const sourceExtension = ".monkey"
+
+// walkSources calls fn for each file given in paths and each source file in
+// the directories given, errors are printed and the exit status returned is
+// 2 if any occurred
+func walkSources(paths []string, fn func(filename string) error) int {
+	status := 0
+	for _, arg := range paths {
+		err := filepath.Walk(arg, func(filename string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			// Files given explicitly are used whatever their extension
+			if info.IsDir() || (filename != arg && filepath.Ext(filename) != sourceExtension) {
+				return nil
+			}
+			if err := fn(filename); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				status = 2
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 2
+		}
+	}
+	return status
+}
+
+// reportSources implements commands which report problems found in the
+// files or the source files in directories given as arguments, or the
+// standard input if none are given, by calling report with the source of
+// each. The problems are printed and the exit status returned is 1 if any
+// were found or 2 if an error occurred
+func reportSources(command string, args []string, report func(filename string, src []byte) ([]string, error)) int {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	flags.Usage = func() {
+		name := path.Base(os.Args[0])
+		fmt.Fprintf(flags.Output(), "Usage: %s %s [<path> ...]\n", name, command)
+	}
+	flags.Parse(args)
+
+	found := false
+	check := func(filename string, src []byte) error {
+		problems, err := report(filename, src)
+		if err != nil {
+			return fmt.Errorf("%s: %s", filename, err)
+		}
+		for _, problem := range problems {
+			fmt.Fprintln(os.Stderr, problem)
+			found = true
+		}
+		return nil
+	}
+
+	status := 0
+	if flags.NArg() == 0 {
+		src, err := ioutil.ReadAll(os.Stdin)
+		if err == nil {
+			err = check("<stdin>", src)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 2
+		}
+	} else {
+		status = walkSources(flags.Args(), func(filename string) error {
+			src, err := ioutil.ReadFile(filename)
+			if err != nil {
+				return err
+			}
+			return check(filename, src)
+		})
+	}
+
+	if status == 0 && found {
+		status = 1
+	}
+	return status
+}
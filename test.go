@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+
+	"github.com/prologic/monkey-lang/testrunner"
+)
+
+// testFiles implements the test command which runs the tests in the test
+// files found in the paths given as arguments, or the current directory if
+// none are given, and returns the exit status
+func testFiles(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	run := flags.String("run", "", "run only the tests whose names match the regular expression")
+	flags.Usage = func() {
+		name := path.Base(os.Args[0])
+		fmt.Fprintf(flags.Output(), "Usage: %s test [options] [<path> ...]\n\n", name)
+		fmt.Fprint(flags.Output(), "Options:\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	runner := testrunner.New(os.Stdout)
+	if *run != "" {
+		re, err := regexp.Compile(*run)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: invalid -run regular expression: %s\n", err)
+			return 2
+		}
+		runner.Run = re
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	filenames, err := testrunner.Discover(paths)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if len(filenames) == 0 {
+		fmt.Fprintln(os.Stderr, "no test files found")
+		return 1
+	}
+
+	status := 0
+	for _, filename := range filenames {
+		results, err := runner.RunFile(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", filename, err)
+			status = 1
+		}
+		for _, result := range results {
+			if !result.Passed() {
+				status = 1
+			}
+		}
+	}
+	return status
+}
@@ -0,0 +1,218 @@
+// Package testrunner implements a runner for tests written in Monkey. Tests
+// are functions named Test* bound at the top level of files whose names end
+// in _test.monkey, each is run in isolation on a new virtual machine and
+// fails if it raises an error (e.g. a failed assert) or exits with a non-zero
+// status.
+package testrunner
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/prologic/monkey-lang/ast"
+	"github.com/prologic/monkey-lang/compiler"
+	"github.com/prologic/monkey-lang/lexer"
+	"github.com/prologic/monkey-lang/parser"
+	"github.com/prologic/monkey-lang/token"
+	"github.com/prologic/monkey-lang/utils"
+	"github.com/prologic/monkey-lang/vm"
+)
+
+// Suffix is the suffix of the names of files containing tests
+const Suffix = "_test.monkey"
+
+// Result is the result of running a test
+type Result struct {
+	Name    string
+	Elapsed time.Duration
+
+	// Err is the error that caused the test to fail or nil if it passed
+	Err error
+}
+
+// Passed returns true if the test passed
+func (r Result) Passed() bool {
+	return r.Err == nil
+}
+
+// Runner runs tests and reports their results to Output
+type Runner struct {
+	Output io.Writer
+
+	// Run (if not nil) selects the tests to run by name
+	Run *regexp.Regexp
+}
+
+// New returns a runner reporting results to out
+func New(out io.Writer) *Runner {
+	return &Runner{Output: out}
+}
+
+// Discover returns the test files given in paths and found in the
+// directories given in paths
+func Discover(paths []string) ([]string, error) {
+	var filenames []string
+	for _, arg := range paths {
+		err := filepath.Walk(arg, func(filename string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			// Files given explicitly are used whatever their name
+			if info.IsDir() || (filename != arg && !strings.HasSuffix(filename, Suffix)) {
+				return nil
+			}
+			filenames = append(filenames, filename)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return filenames, nil
+}
+
+// Tests returns the names of the tests defined in the program in the order
+// they are defined
+func Tests(program *ast.Program) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, stmt := range program.Statements {
+		es, ok := stmt.(*ast.ExpressionStatement)
+		if !ok {
+			continue
+		}
+		bind, ok := es.Expression.(*ast.BindExpression)
+		if !ok {
+			continue
+		}
+		ident, ok := bind.Left.(*ast.Identifier)
+		if !ok || !strings.HasPrefix(ident.Value, "Test") || seen[ident.Value] {
+			continue
+		}
+		if _, ok := bind.Value.(*ast.FunctionLiteral); ok {
+			names = append(names, ident.Value)
+			seen[ident.Value] = true
+		}
+	}
+	return names
+}
+
+// RunFile runs the tests in the file given by filename selected by the
+// runner, reporting the result of each test and a summary of the file. The
+// results are returned, an error is returned if the file could not be read,
+// parsed or compiled
+func (r *Runner) RunFile(filename string) ([]Result, error) {
+	start := time.Now()
+
+	results, err := r.runFile(filename)
+	elapsed := time.Since(start)
+
+	if err != nil {
+		fmt.Fprintf(r.Output, "FAIL\t%s\t%.3fs\n", filename, elapsed.Seconds())
+		return nil, err
+	}
+
+	status := "ok  "
+	for _, result := range results {
+		if !result.Passed() {
+			status = "FAIL"
+		}
+	}
+	fmt.Fprintf(r.Output, "%s\t%s\t%.3fs\n", status, filename, elapsed.Seconds())
+	return results, nil
+}
+
+func (r *Runner) runFile(filename string) ([]Result, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser.New(lexer.NewWithFilename(string(b), filename))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("parser errors:\n\t%s", strings.Join(p.Errors(), "\n\t"))
+	}
+
+	// Modules next to the test file can be imported by it
+	dir, err := filepath.Abs(filepath.Dir(filename))
+	if err != nil {
+		return nil, err
+	}
+	paths := utils.SearchPaths
+	utils.SearchPaths = append([]string{dir}, paths...)
+	defer func() { utils.SearchPaths = paths }()
+
+	var results []Result
+	for _, name := range Tests(program) {
+		if r.Run != nil && !r.Run.MatchString(name) {
+			continue
+		}
+
+		start := time.Now()
+		err := run(program, name)
+		result := Result{Name: name, Elapsed: time.Since(start), Err: err}
+		results = append(results, result)
+
+		if result.Passed() {
+			fmt.Fprintf(r.Output, "--- PASS: %s (%.2fs)\n", name, result.Elapsed.Seconds())
+		} else {
+			fmt.Fprintf(r.Output, "--- FAIL: %s (%.2fs)\n", name, result.Elapsed.Seconds())
+			fmt.Fprintf(r.Output, "    %s\n", result.Err)
+		}
+	}
+	return results, nil
+}
+
+// run runs the program followed by a call to the test given by name on a
+// new virtual machine and returns the error that caused the test to fail
+func run(program *ast.Program, name string) error {
+	call := &ast.ExpressionStatement{
+		Token: token.Token{Type: token.IDENT, Literal: name},
+		Expression: &ast.CallExpression{
+			Token:    token.Token{Type: token.LPAREN, Literal: "("},
+			Function: &ast.Identifier{Token: token.Token{Type: token.IDENT, Literal: name}, Value: name},
+		},
+	}
+	test := &ast.Program{Statements: append(program.Statements[:len(program.Statements):len(program.Statements)], call)}
+
+	c := compiler.New()
+	if err := c.Compile(test); err != nil {
+		return err
+	}
+	machine := vm.New(c.Bytecode())
+
+	// exit() stops the test, waking the tasks it spawned, and exits the
+	// goroutine of the task calling it. A test that exits with a non-zero
+	// status fails.
+	ctx := machine.Context
+	exited := make(chan error, 1)
+	ctx.Exit = func(status int) {
+		if status != 0 {
+			exited <- fmt.Errorf("exit status %d", status)
+		} else {
+			exited <- nil
+		}
+		ctx.Stop()
+		runtime.Goexit()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- machine.Run()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return <-exited
+	}
+}
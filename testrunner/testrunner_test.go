@@ -0,0 +1,134 @@
+package testrunner
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// write writes the files given by name to a temporary directory and returns
+// its path
+func write(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "testrunner")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	for name, src := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644))
+	}
+	return dir
+}
+
+func TestRunFile(t *testing.T) {
+	dir := write(t, map[string]string{
+		"math.monkey": "Add := fn(a, b) { return a + b }\n",
+		"math_test.monkey": `math := import("math")
+calls := 0
+
+TestAdd := fn() {
+  calls = calls + 1
+  assert(math.Add(1, 2) == 3, "1 + 2 should be 3")
+  assert(calls == 1, "tests should be isolated")
+}
+
+TestAddFails := fn() {
+  calls = calls + 1
+  assert(math.Add(1, 1) == 3, "1 + 1 should be 3")
+}
+
+TestExit := fn() { exit(0) }
+TestExitFails := fn() { exit(2) }
+TestExitTask := fn() { c := channel(); spawn fn() { exit(4) }(); recv(c) }
+helper := fn() { assert(false, "not a test") }
+`,
+	})
+
+	var out bytes.Buffer
+	filename := filepath.Join(dir, "math_test.monkey")
+	results, err := New(&out).RunFile(filename)
+	require.NoError(t, err)
+	require.Len(t, results, 5)
+
+	assert.Equal(t, "TestAdd", results[0].Name)
+	assert.True(t, results[0].Passed(), "%s", results[0].Err)
+
+	assert.Equal(t, "TestAddFails", results[1].Name)
+	require.False(t, results[1].Passed())
+	assert.Equal(t, filename+":12:9: AssertionError: 1 + 1 should be 3", results[1].Err.Error())
+
+	assert.Equal(t, "TestExit", results[2].Name)
+	assert.True(t, results[2].Passed())
+
+	assert.Equal(t, "TestExitFails", results[3].Name)
+	require.False(t, results[3].Passed())
+	assert.Equal(t, "exit status 2", results[3].Err.Error())
+
+	assert.Equal(t, "TestExitTask", results[4].Name)
+	require.False(t, results[4].Passed())
+	assert.Equal(t, "exit status 4", results[4].Err.Error())
+
+	assert.Contains(t, out.String(), "--- PASS: TestAdd (")
+	assert.Contains(t, out.String(), "--- FAIL: TestAddFails (")
+	assert.Contains(t, out.String(), "AssertionError: 1 + 1 should be 3\n")
+	assert.Contains(t, out.String(), "FAIL\t"+filename+"\t")
+}
+
+func TestRunFileFilter(t *testing.T) {
+	dir := write(t, map[string]string{
+		"filter_test.monkey": `TestA := fn() { assert(true, "a") }
+TestB := fn() { assert(false, "b") }
+TestAB := fn() { assert(true, "ab") }
+`,
+	})
+
+	var out bytes.Buffer
+	runner := New(&out)
+	runner.Run = regexp.MustCompile("A")
+	results, err := runner.RunFile(filepath.Join(dir, "filter_test.monkey"))
+	require.NoError(t, err)
+
+	var names []string
+	for _, result := range results {
+		names = append(names, result.Name)
+		assert.True(t, result.Passed())
+	}
+	assert.Equal(t, []string{"TestA", "TestAB"}, names)
+	assert.Contains(t, out.String(), "ok  \t")
+}
+
+func TestRunFileErrors(t *testing.T) {
+	dir := write(t, map[string]string{
+		"syntax_test.monkey": "TestA := fn() { assert(true, \n",
+	})
+
+	var out bytes.Buffer
+	_, err := New(&out).RunFile(filepath.Join(dir, "syntax_test.monkey"))
+	assert.Error(t, err)
+	assert.Contains(t, out.String(), "FAIL\t")
+
+	_, err = New(&out).RunFile(filepath.Join(dir, "missing_test.monkey"))
+	assert.Error(t, err)
+}
+
+func TestDiscover(t *testing.T) {
+	dir := write(t, map[string]string{
+		"a_test.monkey": "",
+		"b_test.monkey": "",
+		"a.monkey":      "",
+		"test.monkey":   "",
+	})
+
+	filenames, err := Discover([]string{dir, filepath.Join(dir, "a.monkey")})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "a_test.monkey"),
+		filepath.Join(dir, "b_test.monkey"),
+		filepath.Join(dir, "a.monkey"),
+	}, filenames)
+}
@@ -3,6 +3,10 @@ package token
 // Package token implements types and constants to support tokenizing
 // the input source before passing the stream of tokens on to the parser.
 
+import (
+	"fmt"
+)
+
 const (
 	// ILLEGAL represents an illegal token
 	ILLEGAL = "ILLEGAL"
@@ -21,6 +25,8 @@ const (
 	IDENT = "IDENT"
 	// INT an integer, e.g: 1234
 	INT = "INT"
+	// FLOAT a floating point number, e.g: 3.14 or 1e-9
+	FLOAT = "FLOAT"
 	// STRING a string, e.g: "1234"
 	STRING = "STRING"
 
@@ -102,6 +108,8 @@ const (
 	COLON = ":"
 	// DOT a dot
 	DOT = "."
+	// ARROW an arrow preceding the return type of a function
+	ARROW = "->"
 
 	// LPAREN a left paranthesis
 	LPAREN = "("
@@ -136,29 +144,96 @@ const (
 	RETURN = "RETURN"
 	// WHILE the `while` keyword (while)
 	WHILE = "WHILE"
+	// FOR the `for` keyword (for)
+	FOR = "FOR"
+	// IN the `in` keyword (in)
+	IN = "IN"
 	// IMPORT the `import` keyword (import)
 	IMPORT = "IMPORT"
+	// BREAK the `break` keyword (break)
+	BREAK = "BREAK"
+	// CONTINUE the `continue` keyword (continue)
+	CONTINUE = "CONTINUE"
+	// TRY the `try` keyword (try)
+	TRY = "TRY"
+	// CATCH the `catch` keyword (catch)
+	CATCH = "CATCH"
+	// FINALLY the `finally` keyword (finally)
+	FINALLY = "FINALLY"
+	// THROW the `throw` keyword (throw)
+	THROW = "THROW"
+	// SPAWN the `spawn` keyword (spawn)
+	SPAWN = "SPAWN"
+	// SELECT the `select` keyword (select)
+	SELECT = "SELECT"
+	// CASE the `case` keyword (case)
+	CASE = "CASE"
+	// DEFAULT the `default` keyword (default)
+	DEFAULT = "DEFAULT"
 )
 
 var keywords = map[string]Type{
-	"fn":     FUNCTION,
-	"true":   TRUE,
-	"false":  FALSE,
-	"null":   NULL,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
-	"while":  WHILE,
-	"import": IMPORT,
+	"fn":       FUNCTION,
+	"true":     TRUE,
+	"false":    FALSE,
+	"null":     NULL,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"while":    WHILE,
+	"for":      FOR,
+	"in":       IN,
+	"import":   IMPORT,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"try":      TRY,
+	"catch":    CATCH,
+	"finally":  FINALLY,
+	"throw":    THROW,
+	"spawn":    SPAWN,
+	"select":   SELECT,
+	"case":     CASE,
+	"default":  DEFAULT,
 }
 
 // Type represents the type of a token
 type Type string
 
-// Token holds a single token type and its literal value
+// Position represents a location in the source input, the file name (if
+// any), the line and the column. Lines and columns both start at 1.
+type Position struct {
+	Filename string
+	Line     int
+	Column   int
+}
+
+// IsValid reports whether the position is valid
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns the position in the form file:line:column, line:column,
+// file or - depending on which parts of the position are valid
+func (p Position) String() string {
+	s := p.Filename
+	if p.IsValid() {
+		if s != "" {
+			s += ":"
+		}
+		s += fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	if s == "" {
+		s = "-"
+	}
+	return s
+}
+
+// Token holds a single token type, its literal value and the position in
+// the source input where the token starts
 type Token struct {
 	Type    Type
 	Literal string
+	Pos     Position
 }
 
 // LookupIdent looks up the identifier in ident and returns the appropriate
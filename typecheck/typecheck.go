@@ -0,0 +1,637 @@
+// Package typecheck implements a static type checker for Monkey programs.
+// Types are inferred from literals, operators, builtins and functions and
+// verified against the optional annotations of bindings, function parameters
+// and return values (e.g. `fn(x: int, y: str) -> bool`) before a program is
+// run. Values whose type cannot be inferred, such as unannotated parameters,
+// have the type any which is compatible with every type.
+package typecheck
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/prologic/monkey-lang/ast"
+	"github.com/prologic/monkey-lang/lexer"
+	"github.com/prologic/monkey-lang/parser"
+	"github.com/prologic/monkey-lang/token"
+)
+
+// Error is a type error at a position in the source
+type Error struct {
+	Pos     token.Position
+	Message string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+}
+
+// variable is a name bound in a scope
+type variable struct {
+	typ       Type
+	annotated bool
+}
+
+// scope is a lexical scope, the program or a function body
+type scope struct {
+	parent *scope
+	vars   map[string]*variable
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{parent: parent, vars: make(map[string]*variable)}
+}
+
+// resolve returns the variable visible in the scope or nil if the name is
+// not bound
+func (s *scope) resolve(name string) *variable {
+	for ; s != nil; s = s.parent {
+		if v, ok := s.vars[name]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// function is the function whose body is being checked
+type function struct {
+	result  Type // the annotated return type or nil
+	returns Type // the type of the values returned or nil if none are
+}
+
+// checker checks a program
+type checker struct {
+	errors []Error
+	fn     *function
+}
+
+// Source parses the source code read from filename and checks it, an error
+// is returned if the source does not parse
+func Source(filename string, src []byte) ([]Error, error) {
+	p := parser.New(lexer.NewWithFilename(string(src), filename))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("parser errors:\n\t%s", strings.Join(p.Errors(), "\n\t"))
+	}
+	return Check(program), nil
+}
+
+// Check returns the type errors in the program ordered by position
+func Check(program *ast.Program) []Error {
+	c := &checker{}
+
+	s := newScope(nil)
+	for _, stmt := range program.Statements {
+		c.statement(stmt, s)
+	}
+
+	sort.SliceStable(c.errors, func(i, j int) bool {
+		a, b := c.errors[i].Pos, c.errors[j].Pos
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+	return c.errors
+}
+
+func (c *checker) errorf(pos token.Position, format string, a ...interface{}) {
+	c.errors = append(c.errors, Error{pos, fmt.Sprintf(format, a...)})
+}
+
+// statement checks the statement in scope s
+func (c *checker) statement(stmt ast.Statement, s *scope) {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		c.expr(stmt.Expression, s)
+	case *ast.ReturnStatement:
+		if c.fn == nil {
+			c.expr(stmt.ReturnValue, s)
+			break
+		}
+		typ := c.typed(stmt.ReturnValue, c.fn.result, s)
+		if c.fn.result != nil && !assignable(typ, c.fn.result) {
+			c.errorf(start(stmt.ReturnValue), "cannot return %s from a function returning %s", typ, c.fn.result)
+		}
+		if c.fn.returns == nil {
+			c.fn.returns = typ
+		} else {
+			c.fn.returns = join(c.fn.returns, typ)
+		}
+	case *ast.BlockStatement:
+		c.block(stmt, s)
+	}
+}
+
+// block checks the statements of the block in scope s
+func (c *checker) block(block *ast.BlockStatement, s *scope) {
+	if block == nil {
+		return
+	}
+	for _, stmt := range block.Statements {
+		c.statement(stmt, s)
+	}
+}
+
+// annotation returns the type named by a type annotation
+func (c *checker) annotation(t ast.Type) Type {
+	switch t := t.(type) {
+	case *ast.NamedType:
+		if b, ok := basics[t.Name]; ok {
+			return b
+		}
+		c.errorf(t.Pos(), "unknown type %s", t.Name)
+		return Any
+	case *ast.ArrayType:
+		return &Array{c.annotation(t.Element)}
+	case *ast.HashType:
+		return &Hash{c.annotation(t.Key), c.annotation(t.Value)}
+	case *ast.FunctionType:
+		fn := &Function{Return: Any}
+		for _, param := range t.Parameters {
+			fn.Parameters = append(fn.Parameters, c.annotation(param))
+		}
+		if t.Return != nil {
+			fn.Return = c.annotation(t.Return)
+		}
+		return fn
+	}
+	return Any
+}
+
+// expr returns the type of the expression checked in scope s
+func (c *checker) expr(e ast.Expression, s *scope) Type {
+	// Optional nodes may be typed nil pointers
+	if e == nil || reflect.ValueOf(e).IsNil() {
+		return Null
+	}
+
+	switch e := e.(type) {
+	case *ast.IntegerLiteral:
+		return Int
+	case *ast.FloatLiteral:
+		return Float
+	case *ast.StringLiteral:
+		return Str
+	case *ast.Boolean:
+		return Bool
+	case *ast.Null:
+		return Null
+
+	case *ast.Identifier:
+		if v := s.resolve(e.Value); v != nil {
+			return v.typ
+		}
+		if typ, ok := builtins[e.Value]; ok {
+			return &Function{Return: typ, Variadic: true}
+		}
+		return Any
+
+	case *ast.PrefixExpression:
+		return c.prefix(e, c.expr(e.Right, s))
+	case *ast.InfixExpression:
+		return c.infix(e, c.expr(e.Left, s), c.expr(e.Right, s))
+
+	case *ast.BindExpression:
+		return c.bind(e, s)
+	case *ast.AssignmentExpression:
+		return c.assign(e, s)
+
+	case *ast.FunctionLiteral:
+		return c.function(e, s, nil)
+	case *ast.CallExpression:
+		return c.call(e, s)
+	case *ast.IndexExpression:
+		return c.index(e, s)
+
+	case *ast.ArrayLiteral:
+		var elem Type
+		for _, element := range e.Elements {
+			typ := c.expr(element, s)
+			if elem == nil {
+				elem = typ
+			} else {
+				elem = join(elem, typ)
+			}
+		}
+		if elem == nil {
+			elem = Any
+		}
+		return &Array{elem}
+	case *ast.HashLiteral:
+		var key, value Type
+		for _, k := range e.Keys {
+			v := e.Pairs[k]
+			kt, vt := c.expr(k, s), c.expr(v, s)
+			if key == nil {
+				key, value = kt, vt
+			} else {
+				key, value = join(key, kt), join(value, vt)
+			}
+		}
+		if key == nil {
+			key, value = Any, Any
+		}
+		return &Hash{key, value}
+
+	case *ast.IfExpression:
+		c.expr(e.Condition, s)
+		c.block(e.Consequence, s)
+		c.block(e.Alternative, s)
+		return Any
+	case *ast.WhileExpression:
+		c.expr(e.Condition, s)
+		c.block(e.Consequence, s)
+		return Any
+	case *ast.ForExpression:
+		key, value := Type(Any), Type(Any)
+		switch typ := c.expr(e.Iterable, s).(type) {
+		case *Array:
+			key, value = Int, typ.Element
+		case *Hash:
+			key, value = typ.Key, typ.Value
+		case basic:
+			if typ == Str {
+				key, value = Int, Str
+			}
+		}
+		if e.Key != nil {
+			s.vars[e.Key.Value] = &variable{typ: key}
+		}
+		if e.Value != nil {
+			s.vars[e.Value.Value] = &variable{typ: value}
+		}
+		c.block(e.Body, s)
+		return Any
+	case *ast.TryExpression:
+		c.block(e.Block, s)
+		if e.CatchParam != nil {
+			s.vars[e.CatchParam.Value] = &variable{typ: Err}
+		}
+		c.block(e.Catch, s)
+		c.block(e.Finally, s)
+		return Any
+	case *ast.ThrowExpression:
+		c.expr(e.Value, s)
+		return Any
+	case *ast.SpawnExpression:
+		c.expr(e.Value, s)
+		return Null
+	case *ast.SelectExpression:
+		for _, sc := range e.Cases {
+			c.expr(sc.Channel, s)
+			if sc.Value != nil {
+				c.expr(sc.Value, s)
+			}
+			if sc.Name != nil {
+				s.vars[sc.Name.Value] = &variable{typ: Any}
+			}
+			c.block(sc.Body, s)
+		}
+		c.block(e.Default, s)
+		return Any
+	case *ast.ImportExpression:
+		c.expr(e.Name, s)
+		return Module
+	}
+
+	return Any
+}
+
+// typed returns the type of the expression e checked in scope s where a
+// value of the expected type (if not nil) is expected. The elements of array
+// and hash literals are checked individually, so the type returned is the
+// expected type for literals whose elements are all of the expected types.
+func (c *checker) typed(e ast.Expression, expected Type, s *scope) Type {
+	switch lit := e.(type) {
+	case *ast.ArrayLiteral:
+		arr, ok := expected.(*Array)
+		if !ok {
+			break
+		}
+		for _, element := range lit.Elements {
+			typ := c.typed(element, arr.Element, s)
+			if !assignable(typ, arr.Element) {
+				c.errorf(start(element), "cannot use %s as %s in an element of %s", typ, arr.Element, arr)
+			}
+		}
+		return arr
+
+	case *ast.HashLiteral:
+		hash, ok := expected.(*Hash)
+		if !ok {
+			break
+		}
+		for _, key := range lit.Keys {
+			value := lit.Pairs[key]
+			if typ := c.typed(key, hash.Key, s); !assignable(typ, hash.Key) {
+				c.errorf(start(key), "cannot use %s as %s in a key of %s", typ, hash.Key, hash)
+			}
+			if typ := c.typed(value, hash.Value, s); !assignable(typ, hash.Value) {
+				c.errorf(start(value), "cannot use %s as %s in a value of %s", typ, hash.Value, hash)
+			}
+		}
+		return hash
+	}
+
+	return c.expr(e, s)
+}
+
+// prefix returns the type of the prefix expression
+func (c *checker) prefix(e *ast.PrefixExpression, right Type) Type {
+	switch {
+	case e.Operator == "!":
+		return Bool
+	case right == Any:
+		return Any
+	case e.Operator == "-" && (right == Int || right == Float):
+		return right
+	case e.Operator == "~" && right == Int:
+		return Int
+	}
+	c.errorf(e.Pos(), "invalid operation: %s%s", e.Operator, right)
+	return Any
+}
+
+// infix returns the type of the infix expression following the rules of
+// the evaluation of operators
+func (c *checker) infix(e *ast.InfixExpression, left, right Type) Type {
+	op := e.Operator
+
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		return Bool
+	}
+
+	if left == Any || right == Any {
+		switch op {
+		case "&&", "||":
+			return Bool
+		}
+		return Any
+	}
+
+	numeric := func(t Type) bool { return t == Int || t == Float }
+
+	switch {
+	case op == "&&" || op == "||":
+		if left == Bool && right == Bool {
+			return Bool
+		}
+	case left == Int && right == Int:
+		switch op {
+		case "+", "-", "*", "/", "%", "|", "^", "&", "<<", ">>":
+			return Int
+		}
+	case numeric(left) && numeric(right):
+		switch op {
+		case "+", "-", "*", "/", "%":
+			return Float
+		}
+	case op == "+" && left == Str && right == Str:
+		return Str
+	case op == "*" && ((left == Str && right == Int) || (left == Int && right == Str)):
+		return Str
+	case op == "+":
+		if _, ok := left.(*Array); ok {
+			if _, ok := right.(*Array); ok {
+				return join(left, right)
+			}
+		}
+		if _, ok := left.(*Hash); ok {
+			if _, ok := right.(*Hash); ok {
+				return join(left, right)
+			}
+		}
+	case op == "*":
+		if _, ok := left.(*Array); ok && right == Int {
+			return left
+		}
+		if _, ok := right.(*Array); ok && left == Int {
+			return right
+		}
+	}
+
+	c.errorf(e.Pos(), "invalid operation: %s %s %s", left, op, right)
+	return Any
+}
+
+// bind checks the binding and binds its name in scope s
+func (c *checker) bind(e *ast.BindExpression, s *scope) Type {
+	ident, ok := e.Left.(*ast.Identifier)
+	if !ok {
+		return c.expr(e.Value, s)
+	}
+
+	v := &variable{typ: Any}
+	if e.Type != nil {
+		v.typ, v.annotated = c.annotation(e.Type), true
+	}
+	// The name is bound before the value is checked so functions can refer
+	// to themselves
+	s.vars[ident.Value] = v
+
+	var typ Type
+	if fn, ok := e.Value.(*ast.FunctionLiteral); ok {
+		typ = c.function(fn, s, v)
+	} else if v.annotated {
+		typ = c.typed(e.Value, v.typ, s)
+	} else {
+		typ = c.expr(e.Value, s)
+	}
+
+	if !v.annotated {
+		v.typ = typ
+	} else if !assignable(typ, v.typ) {
+		c.errorf(ident.Pos(), "cannot bind %s to %s of type %s", typ, ident.Value, v.typ)
+	}
+	return typ
+}
+
+// assign checks the assignment in scope s
+func (c *checker) assign(e *ast.AssignmentExpression, s *scope) Type {
+	var expected Type
+	if ident, ok := e.Left.(*ast.Identifier); ok {
+		if v := s.resolve(ident.Value); v != nil && v.annotated {
+			expected = v.typ
+		}
+	}
+	typ := c.typed(e.Value, expected, s)
+
+	switch left := e.Left.(type) {
+	case *ast.Identifier:
+		v := s.resolve(left.Value)
+		if v == nil {
+			break
+		}
+		if v.annotated {
+			if !assignable(typ, v.typ) {
+				c.errorf(left.Pos(), "cannot assign %s to %s of type %s", typ, left.Value, v.typ)
+			}
+		} else if !assignable(typ, v.typ) {
+			// The type of an unannotated variable is that of all the values
+			// assigned to it
+			v.typ = join(v.typ, typ)
+		}
+
+	case *ast.IndexExpression:
+		container := c.expr(left.Left, s)
+		index := c.expr(left.Index, s)
+
+		// Only the elements of annotated variables are checked as others
+		// may hold values of different types
+		ident, ok := left.Left.(*ast.Identifier)
+		if !ok {
+			break
+		}
+		if v := s.resolve(ident.Value); v == nil || !v.annotated {
+			break
+		}
+		switch container := container.(type) {
+		case *Array:
+			if !assignable(typ, container.Element) {
+				c.errorf(start(e.Value), "cannot assign %s to an element of %s", typ, container)
+			}
+		case *Hash:
+			if !assignable(index, container.Key) {
+				c.errorf(start(left.Index), "cannot use %s as a key of %s", index, container)
+			}
+			if !assignable(typ, container.Value) {
+				c.errorf(start(e.Value), "cannot assign %s to a value of %s", typ, container)
+			}
+		}
+	}
+
+	return typ
+}
+
+// function checks the function literal in a scope enclosed by s and returns
+// its type, v is the variable bound to the function if any
+func (c *checker) function(fl *ast.FunctionLiteral, s *scope, v *variable) Type {
+	typ := &Function{Return: Any}
+	inner := newScope(s)
+	for i, param := range fl.Parameters {
+		t := Type(Any)
+		annotated := i < len(fl.ParameterTypes) && fl.ParameterTypes[i] != nil
+		if annotated {
+			t = c.annotation(fl.ParameterTypes[i])
+		}
+		typ.Parameters = append(typ.Parameters, t)
+		inner.vars[param.Value] = &variable{typ: t, annotated: annotated}
+	}
+
+	fn := &function{}
+	if fl.ReturnType != nil {
+		fn.result = c.annotation(fl.ReturnType)
+		typ.Return = fn.result
+	}
+
+	// Recursive calls have the type of the function before the type of the
+	// values it returns is inferred
+	if v != nil && !v.annotated {
+		v.typ = typ
+	}
+
+	outer := c.fn
+	c.fn = fn
+	c.block(fl.Body, inner)
+	c.fn = outer
+
+	if fn.result == nil && fn.returns != nil {
+		typ.Return = fn.returns
+	}
+	return typ
+}
+
+// call checks the arguments of the call in scope s and returns the type of
+// the value returned
+func (c *checker) call(e *ast.CallExpression, s *scope) Type {
+	callee := c.expr(e.Function, s)
+
+	// Arguments are checked against the types of the parameters if known
+	var params []Type
+	if fn, ok := callee.(*Function); ok && !fn.Variadic && len(fn.Parameters) == len(e.Arguments) {
+		params = fn.Parameters
+	}
+
+	var args []Type
+	for i, arg := range e.Arguments {
+		var expected Type
+		if params != nil {
+			expected = params[i]
+		}
+		args = append(args, c.typed(arg, expected, s))
+	}
+
+	switch fn := callee.(type) {
+	case *Function:
+		if fn.Variadic {
+			return fn.Return
+		}
+		if len(args) != len(fn.Parameters) {
+			c.errorf(start(e), "%s takes %d arguments (%d given)", e.Function, len(fn.Parameters), len(args))
+			return fn.Return
+		}
+		for i, arg := range args {
+			if !assignable(arg, fn.Parameters[i]) {
+				c.errorf(start(e.Arguments[i]), "cannot use %s as %s in argument %d of %s", arg, fn.Parameters[i], i+1, e.Function)
+			}
+		}
+		return fn.Return
+	case basic:
+		if fn != Any {
+			c.errorf(start(e), "cannot call %s of type %s", e.Function, fn)
+		}
+	default:
+		c.errorf(start(e), "cannot call %s of type %s", e.Function, fn)
+	}
+	return Any
+}
+
+// index returns the type of the element indexed by the index expression
+func (c *checker) index(e *ast.IndexExpression, s *scope) Type {
+	left := c.expr(e.Left, s)
+	index := c.expr(e.Index, s)
+
+	switch left := left.(type) {
+	case *Array:
+		if !assignable(index, Int) {
+			c.errorf(start(e.Index), "invalid index %s of %s", index, left)
+		}
+		return left.Element
+	case *Hash:
+		if !assignable(index, left.Key) {
+			c.errorf(start(e.Index), "invalid key %s of %s", index, left)
+		}
+		return left.Value
+	case basic:
+		switch left {
+		case Str:
+			if !assignable(index, Int) {
+				c.errorf(start(e.Index), "invalid index %s of str", index)
+			}
+			return Str
+		case Any, Module, Err:
+			return Any
+		}
+	}
+
+	c.errorf(start(e), "cannot index %s", left)
+	return Any
+}
+
+// start returns the position of the first token of the expression
+func start(e ast.Expression) token.Position {
+	switch e := e.(type) {
+	case *ast.InfixExpression:
+		return start(e.Left)
+	case *ast.BindExpression:
+		return start(e.Left)
+	case *ast.AssignmentExpression:
+		return start(e.Left)
+	case *ast.CallExpression:
+		return start(e.Function)
+	case *ast.IndexExpression:
+		return start(e.Left)
+	}
+	return e.Pos()
+}
@@ -0,0 +1,97 @@
+package typecheck
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		// Unannotated programs are only checked for invalid operations
+		{"x := 1\nx = \"a\"\ny: int := x", nil},
+		{"f := fn(x) { return x + 1 }\nf(\"a\")", nil},
+		{"1 + \"a\"", []string{"1:3: invalid operation: int + str"}},
+		{"-\"a\"", []string{"1:1: invalid operation: -str"}},
+		{"true && 1", []string{"1:6: invalid operation: bool && int"}},
+		{"\"a\" * 3 + [1] * 2", []string{"1:9: invalid operation: str + [int]"}},
+		{"x := 1.5 + 2\ny: float := x\nz: int := x", []string{"3:1: cannot bind float to z of type int"}},
+		{"({\"a\": 1} + {\"b\": 2})[\"c\"] + 1", nil},
+		{"len(\"a\") + \"b\"", []string{"1:10: invalid operation: int + str"}},
+		{"1[0]", []string{"1:1: cannot index int"}},
+		{"[1][\"a\"]", []string{"1:5: invalid index str of [int]"}},
+		{"x := 1\nx()", []string{"2:1: cannot call x of type int"}},
+
+		// Bindings
+		{"x: int := 1", nil},
+		{"x: float := 1", nil},
+		{"x: int := \"a\"", []string{"1:1: cannot bind str to x of type int"}},
+		{"x: any := 1\nx = \"a\"", nil},
+		{"x: int := 1\nx = \"a\"", []string{"2:1: cannot assign str to x of type int"}},
+		{"x: foo := 1", []string{"1:4: unknown type foo"}},
+		{"xs: [int] := [1, \"a\"]", []string{"1:18: cannot use str as int in an element of [int]"}},
+		{"xs: [int] := []\nys: [str] := xs", []string{"2:1: cannot bind [int] to ys of type [str]"}},
+		{"h: {str: int} := {\"a\": 1, 2: 3}", []string{"1:27: cannot use int as str in a key of {str: int}"}},
+		{"h: {str: int} := {}\nh[\"a\"] = \"b\"", []string{"2:10: cannot assign str to a value of {str: int}"}},
+		{"xs: [int] := []\nxs[0] = 1.5", []string{"2:9: cannot assign float to an element of [int]"}},
+		{"for k, v in {\"a\": [1]} { x: int := v }", []string{"1:26: cannot bind [int] to x of type int"}},
+		{"for i, c in \"abc\" { x: str := c\ny: int := i }", nil},
+		{"try { throw \"x\" } catch (e) { x: error := e }", nil},
+		{"c: channel := channel()\nselect { case v := recv(c) { x: int := v } }", nil},
+		{"c: channel := 1", []string{"1:1: cannot bind int to c of type channel"}},
+
+		// Functions
+		{"add := fn(x: int, y: int) -> int { return x + y }\nz: int := add(1, 2)", nil},
+		{"add := fn(x: int, y: int) { return x + y }\nz: str := add(1, 2)", []string{"2:1: cannot bind int to z of type str"}},
+		{"f := fn(x: int) -> str { return x }", []string{"1:33: cannot return int from a function returning str"}},
+		{"f := fn(x: int) { return x }\nf(\"a\")", []string{"2:3: cannot use str as int in argument 1 of f"}},
+		{"f := fn(x: int) { return x }\nf(1, 2)", []string{"2:1: f takes 1 arguments (2 given)"}},
+		{"f := fn(xs: [int]) { return xs }\nf([1, \"a\"])", []string{"2:7: cannot use str as int in an element of [int]"}},
+		{"f := fn(x: str) -> int { return len(x) }\ny: int := f(\"a\")", nil},
+		{"fact := fn(n: int) -> int { if (n < 2) { return 1 }\nreturn n * fact(n - 1) }", nil},
+		{
+			"apply := fn(f: fn(int) -> int, x: int) -> int { return f(x) }\n" +
+				"apply(fn(x: int) -> int { return x * 2 }, 1)\n" +
+				"apply(fn(s: str) -> int { return 1 }, 1)",
+			[]string{"3:7: cannot use fn(str) -> int as fn(int) -> int in argument 1 of apply"},
+		},
+		{"f := fn(x: int) -> int { return x }\ng: fn(int) -> int := f\nh: fn() -> int := f", []string{"3:1: cannot bind fn(int) -> int to h of type fn() -> int"}},
+	}
+
+	for _, tt := range tests {
+		errors, err := Source("", []byte(tt.input))
+		require.NoError(t, err, tt.input)
+
+		var actual []string
+		for _, e := range errors {
+			actual = append(actual, e.Error())
+		}
+		assert.Equal(t, tt.expected, actual, tt.input)
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	_, err := Source("test.monkey", []byte("x: := 1"))
+	assert.Error(t, err)
+}
+
+func TestExamples(t *testing.T) {
+	matches, err := filepath.Glob("../examples/*.monkey")
+	require.NoError(t, err)
+	require.NotEmpty(t, matches)
+
+	for _, filename := range matches {
+		src, err := ioutil.ReadFile(filename)
+		require.NoError(t, err)
+
+		errors, err := Source(filename, src)
+		require.NoError(t, err, filename)
+		assert.Empty(t, errors, filename)
+	}
+}
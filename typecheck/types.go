@@ -0,0 +1,167 @@
+package typecheck
+
+import (
+	"strings"
+)
+
+// Type is the static type of a value
+type Type interface {
+	String() string
+}
+
+// basic is a type named by an identifier
+type basic string
+
+func (b basic) String() string { return string(b) }
+
+// Basic types, any is the type of values whose type is unknown and is
+// compatible with every type
+const (
+	Any    basic = "any"
+	Int    basic = "int"
+	Float  basic = "float"
+	Str    basic = "str"
+	Bool   basic = "bool"
+	Null   basic = "null"
+	Err    basic = "error"
+	Module basic = "module"
+	Chan   basic = "channel"
+)
+
+// basics are the basic types by name
+var basics = map[string]basic{
+	"any":     Any,
+	"int":     Int,
+	"float":   Float,
+	"str":     Str,
+	"bool":    Bool,
+	"null":    Null,
+	"error":   Err,
+	"module":  Module,
+	"channel": Chan,
+}
+
+// Array is the type of arrays of elements of a type
+type Array struct {
+	Element Type
+}
+
+func (a *Array) String() string { return "[" + a.Element.String() + "]" }
+
+// Hash is the type of hashes with keys and values of a type
+type Hash struct {
+	Key, Value Type
+}
+
+func (h *Hash) String() string { return "{" + h.Key.String() + ": " + h.Value.String() + "}" }
+
+// Function is the type of functions, the parameters of variadic functions
+// (builtins) are not checked
+type Function struct {
+	Parameters []Type
+	Return     Type
+	Variadic   bool
+}
+
+func (f *Function) String() string {
+	if f.Variadic {
+		return "fn(...) -> " + f.Return.String()
+	}
+	params := []string{}
+	for _, param := range f.Parameters {
+		params = append(params, param.String())
+	}
+	return "fn(" + strings.Join(params, ", ") + ") -> " + f.Return.String()
+}
+
+// builtins are the types of the builtins whose return type is known, other
+// builtins return any
+var builtins = map[string]Type{
+	"len":        Int,
+	"find":       Int,
+	"ord":        Int,
+	"timer":      Int,
+	"cancel":     Bool,
+	"unregister": Bool,
+	"int":        Int,
+	"float":      Float,
+	"bool":       Bool,
+	"str":        Str,
+	"type":       Str,
+	"bin":        Str,
+	"hex":        Str,
+	"oct":        Str,
+	"chr":        Str,
+	"input":      Str,
+	"join":       Str,
+	"lower":      Str,
+	"upper":      Str,
+	"readfile":   Str,
+	"split":      &Array{Str},
+	"args":       &Array{Str},
+	"channel":    Chan,
+}
+
+// equal returns true if the types are identical
+func equal(a, b Type) bool {
+	return a.String() == b.String()
+}
+
+// assignable returns true if a value of type from can be used where a value
+// of type to is expected
+func assignable(from, to Type) bool {
+	if from == Any || to == Any {
+		return true
+	}
+
+	switch to := to.(type) {
+	case basic:
+		return from == to || (to == Float && from == Int)
+	case *Array:
+		from, ok := from.(*Array)
+		return ok && assignable(from.Element, to.Element)
+	case *Hash:
+		from, ok := from.(*Hash)
+		return ok && assignable(from.Key, to.Key) && assignable(from.Value, to.Value)
+	case *Function:
+		from, ok := from.(*Function)
+		if !ok {
+			return false
+		}
+		if !from.Variadic && !to.Variadic {
+			if len(from.Parameters) != len(to.Parameters) {
+				return false
+			}
+			for i := range to.Parameters {
+				// Parameters are contravariant
+				if !assignable(to.Parameters[i], from.Parameters[i]) {
+					return false
+				}
+			}
+		}
+		return assignable(from.Return, to.Return)
+	}
+	return false
+}
+
+// join returns the most specific type of both values of type a and b
+func join(a, b Type) Type {
+	if equal(a, b) {
+		return a
+	}
+	if (a == Int && b == Float) || (a == Float && b == Int) {
+		return Float
+	}
+
+	switch a := a.(type) {
+	case *Array:
+		if b, ok := b.(*Array); ok {
+			return &Array{join(a.Element, b.Element)}
+		}
+	case *Hash:
+		if b, ok := b.(*Hash); ok {
+			return &Hash{join(a.Key, b.Key), join(a.Value, b.Value)}
+		}
+	}
+	return Any
+}
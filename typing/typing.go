@@ -17,40 +17,58 @@ func Check(name string, args []object.Object, checks ...CheckFunc) error {
 	return nil
 }
 
-func ExactArgs(n int) CheckFunc {
-	return func(name string, args []object.Object) error {
-		if len(args) != n {
-			return fmt.Errorf(
-				"TypeError: %s() takes exactly %d argument (%d given)",
-				name, n, len(args),
-			)
-		}
-		return nil
-	}
+// Arity is the number of arguments a function takes, at least Min and at
+// most Max or any number of arguments if Max is Variadic
+type Arity struct {
+	Min, Max int
 }
 
-func MinimumArgs(n int) CheckFunc {
-	return func(name string, args []object.Object) error {
-		if len(args) < n {
+// Variadic is the Max of the Arity of a function taking any number of
+// arguments above its Min
+const Variadic = -1
+
+// Check returns an error if the function name does not take n arguments
+func (a Arity) Check(name string, n int) error {
+	switch {
+	case a.Max == Variadic:
+		if n < a.Min {
 			return fmt.Errorf(
 				"TypeError: %s() takes a minimum %d arguments (%d given)",
-				name, n, len(args),
+				name, a.Min, n,
 			)
 		}
-		return nil
-	}
-}
-
-func RangeOfArgs(n, m int) CheckFunc {
-	return func(name string, args []object.Object) error {
-		if len(args) < n || len(args) > m {
+	case a.Min == a.Max:
+		if n != a.Min {
+			return fmt.Errorf(
+				"TypeError: %s() takes exactly %d argument (%d given)",
+				name, a.Min, n,
+			)
+		}
+	default:
+		if n < a.Min || n > a.Max {
 			return fmt.Errorf(
 				"TypeError: %s() takes at least %d arguments at most %d (%d given)",
-				name, n, m, len(args),
+				name, a.Min, a.Max, n,
 			)
 		}
-		return nil
 	}
+	return nil
+}
+
+func (a Arity) check(name string, args []object.Object) error {
+	return a.Check(name, len(args))
+}
+
+func ExactArgs(n int) CheckFunc {
+	return Arity{n, n}.check
+}
+
+func MinimumArgs(n int) CheckFunc {
+	return Arity{n, Variadic}.check
+}
+
+func RangeOfArgs(n, m int) CheckFunc {
+	return Arity{n, m}.check
 }
 
 func WithTypes(types ...object.Type) CheckFunc {
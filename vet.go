@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/prologic/monkey-lang/vet"
+)
+
+// vetFiles implements the vet command which reports suspicious constructs
+// and returns the exit status
+func vetFiles(args []string) int {
+	return reportSources("vet", args, func(filename string, src []byte) ([]string, error) {
+		problems, err := vet.Source(filename, src)
+		if err != nil {
+			return nil, err
+		}
+		var result []string
+		for _, problem := range problems {
+			result = append(result, problem.String())
+		}
+		return result, nil
+	})
+}
@@ -0,0 +1,337 @@
+// Package vet implements a static checker which reports suspicious
+// constructs in Monkey programs without running them: unused bindings,
+// assignments to names never bound, shadowed bindings, unreachable code,
+// calls to builtins with the wrong number of arguments and imported modules
+// whose exported names are never used.
+package vet
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/prologic/monkey-lang/ast"
+	"github.com/prologic/monkey-lang/builtins"
+	"github.com/prologic/monkey-lang/lexer"
+	"github.com/prologic/monkey-lang/parser"
+	"github.com/prologic/monkey-lang/token"
+)
+
+// Problem is a problem reported at a position in the source
+type Problem struct {
+	Pos     token.Position
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s", p.Pos, p.Message)
+}
+
+// kind is the kind of a name
+type kind int
+
+const (
+	kindBinding   kind = iota // bound by `:=`
+	kindParameter             // a function parameter
+	kindVariable              // a for loop variable or the parameter of a catch block
+)
+
+// name is a name defined in a scope
+type name struct {
+	ident  *ast.Identifier
+	kind   kind
+	module string // the name of the module bound to an import expression
+
+	// used is true if the name is referred to other than by assigning to
+	// it and exported is true if a module's exported names are used
+	used, exported bool
+}
+
+// scope is a lexical scope, the program or a function body
+type scope struct {
+	parent *scope
+	names  map[string]*name
+	order  []*name
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{parent: parent, names: make(map[string]*name)}
+}
+
+// resolve returns the name visible in the scope or nil if it is not defined
+func (s *scope) resolve(ident string) *name {
+	for ; s != nil; s = s.parent {
+		if n, ok := s.names[ident]; ok {
+			return n
+		}
+	}
+	return nil
+}
+
+// checker checks a program
+type checker struct {
+	problems []Problem
+	scopes   []*scope
+}
+
+// Source parses the source code read from filename and checks it, an error
+// is returned if the source does not parse
+func Source(filename string, src []byte) ([]Problem, error) {
+	p := parser.New(lexer.NewWithFilename(string(src), filename))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("parser errors:\n\t%s", strings.Join(p.Errors(), "\n\t"))
+	}
+	return Check(program), nil
+}
+
+// Check returns the problems found in the program ordered by position
+func Check(program *ast.Program) []Problem {
+	c := &checker{}
+
+	root := newScope(nil)
+	c.scopes = append(c.scopes, root)
+	c.statements(program.Statements, root)
+
+	for _, s := range c.scopes {
+		for _, n := range s.order {
+			c.unused(n, s == root)
+		}
+	}
+
+	sort.SliceStable(c.problems, func(i, j int) bool {
+		a, b := c.problems[i].Pos, c.problems[j].Pos
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+	return c.problems
+}
+
+func (c *checker) report(pos token.Position, format string, a ...interface{}) {
+	c.problems = append(c.problems, Problem{pos, fmt.Sprintf(format, a...)})
+}
+
+// unused reports the name if it is a binding that is never used
+func (c *checker) unused(n *name, global bool) {
+	ident := n.ident.Value
+	if n.kind != kindBinding || ident == "_" {
+		return
+	}
+
+	if n.module != "" {
+		if !n.exported {
+			c.report(n.ident.Token.Pos, "module %q is imported as %s but none of its exported names are used", n.module, ident)
+		}
+		return
+	}
+
+	// Names exported by a module are used by the programs importing it
+	if global && unicode.IsUpper([]rune(ident)[0]) {
+		return
+	}
+
+	if !n.used {
+		c.report(n.ident.Token.Pos, "%s is bound but never used", ident)
+	}
+}
+
+// statements checks the statements of a block or the program in scope s
+func (c *checker) statements(stmts []ast.Statement, s *scope) {
+	terminated := false
+	for _, stmt := range stmts {
+		if _, ok := stmt.(*ast.Comment); ok {
+			continue
+		}
+		if terminated {
+			c.report(stmt.Pos(), "unreachable code")
+			terminated = false
+		}
+		c.walk(stmt, s)
+		terminated = terminates(stmt)
+	}
+}
+
+// terminates returns true if control never continues past the statement
+func terminates(stmt ast.Statement) bool {
+	switch stmt := stmt.(type) {
+	case *ast.ReturnStatement, *ast.BreakStatement, *ast.ContinueStatement:
+		return true
+	case *ast.ExpressionStatement:
+		_, ok := stmt.Expression.(*ast.ThrowExpression)
+		return ok
+	}
+	return false
+}
+
+// walk checks the AST node in scope s
+func (c *checker) walk(node ast.Node, s *scope) {
+	// Optional nodes may be typed nil pointers
+	if node == nil || reflect.ValueOf(node).IsNil() {
+		return
+	}
+
+	switch node := node.(type) {
+	case *ast.ExpressionStatement:
+		c.walk(node.Expression, s)
+	case *ast.ReturnStatement:
+		c.walk(node.ReturnValue, s)
+	case *ast.BlockStatement:
+		c.statements(node.Statements, s)
+
+	case *ast.Identifier:
+		if n := s.resolve(node.Value); n != nil {
+			n.used = true
+			n.exported = true
+		}
+
+	case *ast.BindExpression:
+		ident, ok := node.Left.(*ast.Identifier)
+		if !ok {
+			c.walk(node.Left, s)
+			c.walk(node.Value, s)
+			break
+		}
+
+		// The name is bound before the value is evaluated so functions
+		// can refer to themselves
+		n := c.define(ident, kindBinding, s)
+		if ie, ok := node.Value.(*ast.ImportExpression); ok {
+			if module, ok := ie.Name.(*ast.StringLiteral); ok {
+				n.module = module.Value
+			}
+		}
+		c.walk(node.Value, s)
+
+	case *ast.AssignmentExpression:
+		if ident, ok := node.Left.(*ast.Identifier); ok {
+			if s.resolve(ident.Value) == nil {
+				c.report(ident.Token.Pos, "assignment to %s which is never bound", ident.Value)
+			}
+		} else {
+			c.walk(node.Left, s)
+		}
+		c.walk(node.Value, s)
+
+	case *ast.FunctionLiteral:
+		inner := newScope(s)
+		c.scopes = append(c.scopes, inner)
+		for _, param := range node.Parameters {
+			c.define(param, kindParameter, inner)
+		}
+		c.walk(node.Body, inner)
+
+	case *ast.CallExpression:
+		c.walk(node.Function, s)
+		for _, arg := range node.Arguments {
+			c.walk(arg, s)
+		}
+		c.call(node, s)
+	case *ast.IndexExpression:
+		// A selector expression, e.g. foo.bar, indexes by a string literal
+		// parsed from an identifier
+		if sel, ok := node.Index.(*ast.StringLiteral); ok && sel.Token.Type == token.IDENT {
+			if operand, ok := node.Left.(*ast.Identifier); ok {
+				if n := s.resolve(operand.Value); n != nil {
+					n.used = true
+					// All the names of modules implemented in Go are exported
+					_, native := builtins.Modules[n.module]
+					n.exported = n.exported || native || unicode.IsUpper([]rune(sel.Value)[0])
+				}
+				break
+			}
+			c.walk(node.Left, s)
+			break
+		}
+		c.walk(node.Left, s)
+		c.walk(node.Index, s)
+
+	case *ast.PrefixExpression:
+		c.walk(node.Right, s)
+	case *ast.InfixExpression:
+		c.walk(node.Left, s)
+		c.walk(node.Right, s)
+	case *ast.ArrayLiteral:
+		for _, element := range node.Elements {
+			c.walk(element, s)
+		}
+	case *ast.HashLiteral:
+		for _, key := range node.Keys {
+			value := node.Pairs[key]
+			c.walk(key, s)
+			c.walk(value, s)
+		}
+
+	case *ast.IfExpression:
+		c.walk(node.Condition, s)
+		c.walk(node.Consequence, s)
+		c.walk(node.Alternative, s)
+	case *ast.WhileExpression:
+		c.walk(node.Condition, s)
+		c.walk(node.Consequence, s)
+	case *ast.ForExpression:
+		c.walk(node.Iterable, s)
+		if node.Key != nil {
+			c.define(node.Key, kindVariable, s)
+		}
+		if node.Value != nil {
+			c.define(node.Value, kindVariable, s)
+		}
+		c.walk(node.Body, s)
+	case *ast.TryExpression:
+		c.walk(node.Block, s)
+		if node.CatchParam != nil {
+			c.define(node.CatchParam, kindVariable, s)
+		}
+		c.walk(node.Catch, s)
+		c.walk(node.Finally, s)
+	case *ast.ThrowExpression:
+		c.walk(node.Value, s)
+	case *ast.SpawnExpression:
+		c.walk(node.Value, s)
+	case *ast.SelectExpression:
+		for _, sc := range node.Cases {
+			c.walk(sc.Channel, s)
+			c.walk(sc.Value, s)
+			if sc.Name != nil {
+				c.define(sc.Name, kindVariable, s)
+			}
+			c.walk(sc.Body, s)
+		}
+		c.walk(node.Default, s)
+	case *ast.ImportExpression:
+		c.walk(node.Name, s)
+	}
+}
+
+// define defines the identifier in scope s, binding a name already defined
+// in the same scope binds the same name again
+func (c *checker) define(ident *ast.Identifier, k kind, s *scope) *name {
+	if n, ok := s.names[ident.Value]; ok {
+		return n
+	}
+
+	if k == kindBinding && s.parent != nil {
+		if outer := s.parent.resolve(ident.Value); outer != nil {
+			c.report(ident.Token.Pos, "%s shadows the binding at %s", ident.Value, outer.ident.Token.Pos)
+		}
+	}
+
+	n := &name{ident: ident, kind: k}
+	s.names[ident.Value] = n
+	s.order = append(s.order, n)
+	return n
+}
+
+// call checks the number of arguments of a call to a builtin
+func (c *checker) call(call *ast.CallExpression, s *scope) {
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok || s.resolve(ident.Value) != nil {
+		return
+	}
+	if arity, ok := builtins.Arities[ident.Value]; ok {
+		if err := arity.Check(ident.Value, len(call.Arguments)); err != nil {
+			c.report(ident.Token.Pos, "%s", err)
+		}
+	}
+}
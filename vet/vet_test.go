@@ -0,0 +1,111 @@
+package vet
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prologic/monkey-lang/builtins"
+	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/typing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"x := 1\nprint(x)", nil},
+		{"x := 1", []string{"1:1: x is bound but never used"}},
+		{"x := 1\nx = 2", []string{"1:1: x is bound but never used"}},
+		{"X := 1\n_ := 2", nil},
+		{"f := fn() { X := 1 }\nf()", []string{"1:13: X is bound but never used"}},
+		{"f := fn(x) { return 1 }\nf(1)", nil},
+		{"fib := fn(n) { return fib(n - 1) }\nfib(1)", nil},
+
+		{"y = 1", []string{"1:1: assignment to y which is never bound"}},
+		{"f := fn() { y = 1 }\nf()\ny := 2\nprint(y)", []string{"1:13: assignment to y which is never bound"}},
+		{"for x in [1] { x = 2 }", nil},
+		{"a := [1]\na[0] = 2", nil},
+
+		{
+			"x := 1\nf := fn() { x := 2; return x }\nprint(x, f())",
+			[]string{"2:13: x shadows the binding at 1:1"},
+		},
+		{"x := 1\nx := 2\nprint(x)", nil},
+		{"x := 1\nf := fn(x) { return x }\nprint(x, f(1))", nil},
+
+		{
+			"f := fn() { return 1\nprint(2)\nprint(3) }\nf()",
+			[]string{"2:1: unreachable code"},
+		},
+		{"while (true) { break\n# done\nprint(1) }", []string{"3:1: unreachable code"}},
+		{"try { throw \"x\"\nprint(1) } catch (e) { print(e) }", []string{"2:1: unreachable code"}},
+		{"f := fn(x) { if (x) { return 1 }\nreturn 2 }\nf(1)", nil},
+
+		{"c := channel()\nspawn fn() { send(c, 1) }()\nselect { case v := recv(c) { print(v) } }", nil},
+
+		{"len(1, 2)", []string{"1:1: TypeError: len() takes exactly 1 argument (2 given)"}},
+		{"print()", []string{"1:1: TypeError: print() takes a minimum 1 arguments (0 given)"}},
+		{"range(1, 2, 3, 4)", []string{"1:1: TypeError: range() takes at least 1 arguments at most 3 (4 given)"}},
+		{"len := fn(a, b) { return a + b }\nlen(1, 2)", nil},
+		{"f := fn(len) { return len(1, 2) }\nf(1)", nil},
+
+		{"m := import(\"m\")\nm.Foo(1)", nil},
+		{"m := import(\"m\")\nprint(m)", nil},
+		{"m := import(\"m\")", []string{`1:1: module "m" is imported as m but none of its exported names are used`}},
+		{"m := import(\"m\")\nm.foo", []string{`1:1: module "m" is imported as m but none of its exported names are used`}},
+		{"http := import(\"http\")\nhttp.get(\"http://localhost/\")", nil},
+		{"http := import(\"http\")", []string{`1:1: module "http" is imported as http but none of its exported names are used`}},
+	}
+
+	for _, tt := range tests {
+		problems, err := Source("", []byte(tt.input))
+		require.NoError(t, err, tt.input)
+
+		var actual []string
+		for _, problem := range problems {
+			actual = append(actual, problem.String())
+		}
+		assert.Equal(t, tt.expected, actual, tt.input)
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	_, err := Source("test.monkey", []byte("x := (1 + "))
+	assert.Error(t, err)
+}
+
+// TestArities checks the arities used to check calls agree with the checks
+// made when builtins are called by calling them with one argument too few
+// and one too many
+func TestArities(t *testing.T) {
+	for name, builtin := range builtins.Builtins {
+		arity, ok := builtins.Arities[name]
+		require.True(t, ok, "no arity for %s()", name)
+
+		counts := []int{arity.Min - 1}
+		if arity.Max != typing.Variadic {
+			counts = append(counts, arity.Max+1)
+		}
+
+		for _, n := range counts {
+			if n < 0 {
+				continue
+			}
+			args := make([]object.Object, n)
+			for i := range args {
+				args[i] = &object.Null{}
+			}
+
+			expected := arity.Check(name, n)
+			require.Error(t, expected)
+
+			result, ok := builtin.Fn(object.NewContext(), args...).(*object.Error)
+			require.True(t, ok, "%s() with %d arguments", name, n)
+			assert.True(t, strings.HasSuffix(result.Message, expected.Error()), "%s() with %d arguments: %s", name, n, result.Message)
+		}
+	}
+}
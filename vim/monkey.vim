@@ -11,13 +11,13 @@ endif
 
 syntax case match
 
-syntax keyword xType true false null int str bool array hash
+syntax keyword xType true false null int float str bool array hash
 
-syntax keyword xKeyword fn if else return while
+syntax keyword xKeyword fn if else return while for in break continue try catch finally throw spawn select case default
 
 syntax keyword xFunction len input print first last rest push pop exit assert
-syntax keyword xFunction bool int str typeof args lower upper join split find
-syntax keyword xFunction read write
+syntax keyword xFunction bool int float str typeof args lower upper join split find
+syntax keyword xFunction read write range
 
 syntax match xOperator "\v\=\="
 syntax match xOperator "\v!\="
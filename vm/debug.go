@@ -0,0 +1,37 @@
+package vm
+
+import (
+	"errors"
+
+	"github.com/prologic/monkey-lang/object"
+)
+
+// ErrHalted is returned by Run when execution was halted by a Hook
+var ErrHalted = errors.New("execution halted")
+
+// Hook is called before each instruction is executed, with the current
+// frame's instruction pointer pointing at it, and is used by debuggers to
+// pause execution and inspect the state of the VM. Execution is halted with
+// ErrHalted if the hook returns false.
+type Hook func(vm *VM) bool
+
+// CallStack returns the frames being executed, outer most first
+func (vm *VM) CallStack() []*Frame {
+	return vm.frames[:vm.framesIndex]
+}
+
+// Stack returns the values on the value stack, bottom most first
+func (vm *VM) Stack() []object.Object {
+	return vm.stack[:vm.sp]
+}
+
+// Global returns the value of the global binding at index or nil if unset
+func (vm *VM) Global(index int) object.Object {
+	return vm.state.global(index)
+}
+
+// Local returns the value of the local binding at index of the frame f or
+// nil if unset
+func (vm *VM) Local(f *Frame, index int) object.Object {
+	return vm.stack[f.basePointer+index]
+}
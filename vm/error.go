@@ -0,0 +1,97 @@
+package vm
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/prologic/monkey-lang/object"
+)
+
+// TraceEntry is a single entry of a stack trace
+type TraceEntry = object.TraceEntry
+
+// Error is a runtime error returned by the VM and holds the underlying error
+// along with a stack trace of the frames being executed (outer most first)
+// at the time the error occurred
+type Error struct {
+	Err   error
+	Trace []TraceEntry
+}
+
+// Error returns the error message prefixed with the source position of the
+// instruction that caused the error (if known)
+func (e *Error) Error() string {
+	if len(e.Trace) > 0 {
+		if pos := e.Trace[len(e.Trace)-1].Pos; pos.IsValid() {
+			return fmt.Sprintf("%s: %s", pos, e.Err)
+		}
+	}
+	return e.Err.Error()
+}
+
+// maxRepeatedEntries is the number of identical consecutive entries of a
+// stack trace shown before the rest are summarised, e.g: by deep recursion
+const maxRepeatedEntries = 3
+
+// StackTrace returns a formatted stack trace, most recent call last,
+// followed by the error message
+func (e *Error) StackTrace() string {
+	var out bytes.Buffer
+
+	out.WriteString("Traceback (most recent call last):\n")
+	repeated := 0
+	for i, entry := range e.Trace {
+		if i > 0 && entry == e.Trace[i-1] {
+			repeated++
+		} else {
+			writeRepeated(&out, repeated)
+			repeated = 0
+		}
+		if repeated < maxRepeatedEntries {
+			out.WriteString(entry.String())
+			out.WriteString("\n")
+		}
+	}
+	writeRepeated(&out, repeated)
+	out.WriteString(e.Err.Error())
+	out.WriteString("\n")
+
+	return out.String()
+}
+
+// writeRepeated writes the number of entries of a stack trace repeating the
+// previous entry which were not shown
+func writeRepeated(out *bytes.Buffer, repeated int) {
+	if repeated >= maxRepeatedEntries {
+		fmt.Fprintf(out, "  [Previous line repeated %d more times]\n", repeated-maxRepeatedEntries+1)
+	}
+}
+
+// trace returns a stack trace of the frames currently being executed
+func (vm *VM) trace() []TraceEntry {
+	trace := make([]TraceEntry, vm.framesIndex)
+	for i, frame := range vm.frames[:vm.framesIndex] {
+		trace[i] = TraceEntry{
+			Function: frame.Name(),
+			Pos:      frame.SourcePos(),
+		}
+	}
+	return trace
+}
+
+// errorObject converts an error raised while executing an instruction into
+// an error object recording the position and stack trace where it was
+// raised, unless already recorded when first raised and now re-thrown
+func (vm *VM) errorObject(err error) *object.Error {
+	obj, ok := err.(*object.Error)
+	if !ok {
+		obj = &object.Error{Message: err.Error()}
+	}
+
+	if obj.Trace == nil {
+		obj.Trace = vm.trace()
+		obj.Position = vm.currentFrame().SourcePos()
+	}
+
+	return obj
+}
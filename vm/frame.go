@@ -3,6 +3,7 @@ package vm
 import (
 	"github.com/prologic/monkey-lang/code"
 	"github.com/prologic/monkey-lang/object"
+	"github.com/prologic/monkey-lang/token"
 )
 
 type Frame struct {
@@ -29,3 +30,27 @@ func (f *Frame) NextOp() code.Opcode {
 func (f *Frame) Instructions() code.Instructions {
 	return f.cl.Fn.Instructions
 }
+
+// SourcePos returns the source position of the instruction currently being
+// executed in the frame
+func (f *Frame) SourcePos() token.Position {
+	return f.cl.Fn.SourceMap.Lookup(f.ip)
+}
+
+// Name returns the name of the function executing in the frame
+func (f *Frame) Name() string {
+	if f.cl.Fn.Name == "" {
+		return "<anonymous>"
+	}
+	return f.cl.Fn.Name
+}
+
+// Function returns the compiled function executing in the frame
+func (f *Frame) Function() *object.CompiledFunction {
+	return f.cl.Fn
+}
+
+// Free returns the free variables of the closure executing in the frame
+func (f *Frame) Free() []object.Object {
+	return f.cl.Free
+}
@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math"
+	"sort"
 	"strings"
 	"unicode"
 
@@ -21,11 +23,36 @@ import (
 )
 
 const (
-	StackSize  = 2048
-	MaxFrames  = 1024
+	// StackSize is the default maximum number of values on the stack
+	StackSize = 1 << 20
+	// MaxFrames is the default maximum number of frames, i.e: the depth of
+	// calls
+	MaxFrames = 1 << 16
+	// MaxGlobals is the maximum number of global bindings which can be
+	// referred to by instructions
 	MaxGlobals = 65536
 )
 
+// The stack and frames start small and grow on demand up to their limits
+const (
+	initialStackSize = 256
+	initialFrames    = 64
+)
+
+// Options are the limits of the resources used by a virtual machine
+type Options struct {
+	// StackSize is the maximum number of values on the stack
+	StackSize int
+	// MaxFrames is the maximum number of frames, i.e: the depth of calls
+	MaxFrames int
+}
+
+// DefaultOptions are the limits used by virtual machines unless changed
+var DefaultOptions = Options{StackSize: StackSize, MaxFrames: MaxFrames}
+
+// MainFunction is the name given to the main function of a program or module
+const MainFunction = "<main>"
+
 var (
 	True  = &object.Boolean{Value: true}
 	False = &object.Boolean{Value: false}
@@ -55,8 +82,18 @@ func isTruthy(obj object.Object) bool {
 
 // ExecModule compiles the named module and returns a *object.Module object
 func ExecModule(name string, state *VMState) (object.Object, error) {
+	return execModule(name, state, DefaultOptions, object.NewContext())
+}
+
+// execModule compiles the named module and executes it with the limits
+// given by opts and the execution context ctx. Modules implemented in Go
+// are used if no Monkey module of the same name is found.
+func execModule(name string, state *VMState, opts Options, ctx *object.Context) (object.Object, error) {
 	filename := utils.FindModule(name)
 	if filename == "" {
+		if attrs, ok := builtins.LookupModule(name); ok {
+			return attrs, nil
+		}
 		return nil, fmt.Errorf("ImportError: no module named '%s'", name)
 	}
 
@@ -65,7 +102,7 @@ func ExecModule(name string, state *VMState) (object.Object, error) {
 		return nil, fmt.Errorf("IOError: error reading module '%s': %s", name, err)
 	}
 
-	l := lexer.New(string(b))
+	l := lexer.NewWithFilename(string(b), filename)
 	p := parser.New(l)
 
 	module := p.ParseProgram()
@@ -83,7 +120,11 @@ func ExecModule(name string, state *VMState) (object.Object, error) {
 	state.Constants = code.Constants
 
 	machine := NewWithState(code, state)
-	err = machine.Run()
+	machine.Options = opts
+	machine.Context = ctx
+	// The module is run by the task importing it which holds the lock of
+	// the program's tasks (if any)
+	err = machine.execute()
 	if err != nil {
 		return nil, fmt.Errorf("RuntimeError: error loading module '%s'", err)
 	}
@@ -105,31 +146,91 @@ func NewVMState() *VMState {
 
 	return &VMState{
 		Constants: []object.Object{},
-		Globals:   make([]object.Object, MaxGlobals),
 		Symbols:   symbolTable,
 	}
 }
 
+// global returns the value of the global binding at index or nil if unset
+func (s *VMState) global(index int) object.Object {
+	if index >= len(s.Globals) {
+		return nil
+	}
+	return s.Globals[index]
+}
+
+// setGlobal sets the value of the global binding at index, the globals grow
+// on demand
+func (s *VMState) setGlobal(index int, obj object.Object) {
+	if index >= len(s.Globals) {
+		globals := make([]object.Object, index+1, 2*(index+1))
+		copy(globals, s.Globals)
+		s.Globals = globals
+	}
+	s.Globals[index] = obj
+}
+
+// Get returns the value of the global or builtin binding given by name, false
+// is returned if there is no such binding
+func (s *VMState) Get(name string) (object.Object, bool) {
+	symbol, ok := s.Symbols.Resolve(name)
+	if !ok {
+		return nil, false
+	}
+
+	switch symbol.Scope {
+	case compiler.GlobalScope:
+		obj := s.global(symbol.Index)
+		if obj == nil {
+			return Null, true
+		}
+		return obj, true
+	case compiler.BuiltinScope:
+		return builtins.BuiltinsIndex[symbol.Index], true
+	default:
+		return nil, false
+	}
+}
+
+// Set binds obj to the global binding given by name, the binding is defined
+// if it does not exist and shadows a builtin of the same name
+func (s *VMState) Set(name string, obj object.Object) {
+	symbol, ok := s.Symbols.Resolve(name)
+	if !ok || symbol.Scope != compiler.GlobalScope {
+		symbol = s.Symbols.Define(name)
+	}
+	s.setGlobal(symbol.Index, obj)
+}
+
 // ExportedHash returns a new Hash with the names and values of every publically
 // exported binding in the vm state. That is every binding that starts with a
 // capital letter. This is used by the module import system to wrap up the
 // compiled and evaulated module into an object.
 func (s *VMState) ExportedHash() *object.Hash {
-	pairs := make(map[object.HashKey]object.HashPair)
+	var names []string
 	for name, symbol := range s.Symbols.Store {
-		if unicode.IsUpper(rune(name[0])) {
-			if symbol.Scope == compiler.GlobalScope {
-				obj := s.Globals[symbol.Index]
-				s := &object.String{Value: name}
-				pairs[s.HashKey()] = object.HashPair{Key: s, Value: obj}
-			}
+		if unicode.IsUpper(rune(name[0])) && symbol.Scope == compiler.GlobalScope {
+			names = append(names, name)
 		}
 	}
-	return &object.Hash{Pairs: pairs}
+	sort.Strings(names)
+
+	hash := object.NewHash(len(names))
+	for _, name := range names {
+		obj := s.global(s.Symbols.Store[name].Index)
+		hash.Set(&object.String{Value: name}, obj)
+	}
+	return hash
 }
 
 type VM struct {
 	Debug bool
+	Hook  Hook
+
+	// Options are the limits of the resources used by the VM
+	Options Options
+
+	// Context is the execution context passed to the builtins called
+	Context *object.Context
 
 	state *VMState
 
@@ -138,15 +239,41 @@ type VM struct {
 
 	stack []object.Object
 	sp    int // Always points to the next value. Top of stack is stack[sp-1]
+
+	handlers []handler // try blocks being executed, innermost last
+}
+
+// handler is an entry of the VM's handler table pushed by `SetupTry` and
+// holds the frame and stack pointer to unwind to and the position of the
+// instructions to resume at when an error is raised within a try block
+type handler struct {
+	framesIndex int
+	sp          int
+	pos         int
 }
 
 func (vm *VM) currentFrame() *Frame {
 	return vm.frames[vm.framesIndex-1]
 }
 
-func (vm *VM) pushFrame(f *Frame) {
-	vm.frames[vm.framesIndex] = f
+// pushFrame pushes the frame f onto the frames, the frames grow on demand and
+// a RecursionError is raised if the maximum number of frames is exceeded
+func (vm *VM) pushFrame(f *Frame) error {
+	if vm.framesIndex >= vm.Options.MaxFrames {
+		return fmt.Errorf(
+			"RecursionError: maximum recursion depth exceeded (%d frames)",
+			vm.Options.MaxFrames,
+		)
+	}
+
+	if vm.framesIndex == len(vm.frames) {
+		vm.frames = append(vm.frames, f)
+	} else {
+		vm.frames[vm.framesIndex] = f
+	}
 	vm.framesIndex++
+
+	return nil
 }
 
 func (vm *VM) popFrame() *Frame {
@@ -155,49 +282,91 @@ func (vm *VM) popFrame() *Frame {
 }
 
 func New(bytecode *compiler.Bytecode) *VM {
-	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions}
+	state := NewVMState()
+	state.Constants = bytecode.Constants
+
+	return NewWithState(bytecode, state)
+}
+
+func NewWithState(bytecode *compiler.Bytecode, state *VMState) *VM {
+	mainFn := &object.CompiledFunction{
+		Name:         MainFunction,
+		Instructions: bytecode.Instructions,
+		SourceMap:    bytecode.SourceMap,
+	}
 	mainClosure := &object.Closure{Fn: mainFn}
 	mainFrame := NewFrame(mainClosure, 0)
 
-	frames := make([]*Frame, MaxFrames)
+	frames := make([]*Frame, initialFrames)
 	frames[0] = mainFrame
 
-	state := NewVMState()
-	state.Constants = bytecode.Constants
-
 	return &VM{
+		Options: DefaultOptions,
+		Context: object.NewContext(),
+
 		state: state,
 
 		frames:      frames,
 		framesIndex: 1,
 
-		stack: make([]object.Object, StackSize),
+		stack: make([]object.Object, initialStackSize),
 		sp:    0,
 	}
 }
 
-func NewWithState(bytecode *compiler.Bytecode, state *VMState) *VM {
-	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions}
-	mainClosure := &object.Closure{Fn: mainFn}
-	mainFrame := NewFrame(mainClosure, 0)
+// NewCall returns a new virtual machine sharing state which calls the closure
+// or builtin fn with args when run, the value returned by fn is then given by
+// LastPopped
+func NewCall(state *VMState, fn object.Object, args ...object.Object) *VM {
+	bytecode := &compiler.Bytecode{
+		Instructions: append(code.Make(code.Call, len(args)), code.Make(code.Pop)...),
+		Constants:    state.Constants,
+	}
 
-	frames := make([]*Frame, MaxFrames)
-	frames[0] = mainFrame
+	vm := NewWithState(bytecode, state)
+	if len(vm.stack) < len(args)+1 {
+		vm.stack = make([]object.Object, len(args)+1)
+	}
+	vm.stack[0] = fn
+	copy(vm.stack[1:], args)
+	vm.sp = len(args) + 1
 
-	return &VM{
-		state: state,
+	return vm
+}
 
-		frames:      frames,
-		framesIndex: 1,
+// grow grows the stack to hold at least size values, a StackOverflowError is
+// raised if the maximum stack size is exceeded
+func (vm *VM) grow(size int) error {
+	// The limit is checked first as it may be less than the initial size
+	if size > vm.Options.StackSize {
+		return fmt.Errorf(
+			"StackOverflowError: maximum stack size exceeded (%d values)",
+			vm.Options.StackSize,
+		)
+	}
 
-		stack: make([]object.Object, StackSize),
-		sp:    0,
+	if size <= len(vm.stack) {
+		return nil
 	}
+
+	n := 2 * len(vm.stack)
+	if n < size {
+		n = size
+	}
+	if n > vm.Options.StackSize {
+		n = vm.Options.StackSize
+	}
+
+	stack := make([]object.Object, n)
+	copy(stack, vm.stack)
+	vm.stack = stack
+
+	return nil
 }
 
 func (vm *VM) push(o object.Object) error {
-	if vm.sp >= StackSize {
-		return fmt.Errorf("stack overflow")
+	if err := vm.grow(vm.sp + 1); err != nil {
+		return err
 	}
 
 	vm.stack[vm.sp] = o
@@ -223,16 +392,16 @@ func (vm *VM) executeBinaryOperation(op code.Opcode) error {
 
 	// {"a": 1} + {"b": 2}
 	case op == code.Add && left.Type() == object.HASH && right.Type() == object.HASH:
-		leftVal := left.(*object.Hash).Pairs
-		rightVal := right.(*object.Hash).Pairs
-		pairs := make(map[object.HashKey]object.HashPair)
-		for k, v := range leftVal {
-			pairs[k] = v
+		leftVal := left.(*object.Hash)
+		rightVal := right.(*object.Hash)
+		hash := object.NewHash(leftVal.Len() + rightVal.Len())
+		for _, pair := range leftVal.Pairs() {
+			hash.Set(pair.Key.(object.Hashable), pair.Value)
 		}
-		for k, v := range rightVal {
-			pairs[k] = v
+		for _, pair := range rightVal.Pairs() {
+			hash.Set(pair.Key.(object.Hashable), pair.Value)
 		}
-		return vm.push(&object.Hash{Pairs: pairs})
+		return vm.push(hash)
 
 	// [1] + [2]
 	case op == code.Add && left.Type() == object.ARRAY && right.Type() == object.ARRAY:
@@ -276,20 +445,52 @@ func (vm *VM) executeBinaryOperation(op code.Opcode) error {
 		return vm.executeBinaryBooleanOperation(op, left, right)
 	case leftType == object.INTEGER && rightType == object.INTEGER:
 		return vm.executeBinaryIntegerOperation(op, left, right)
+	case leftType == object.FLOAT && rightType == object.FLOAT:
+		return vm.executeBinaryFloatOperation(op, left, right)
+	// 1 + 2.5 and 2.5 + 1 (int is promoted to float)
+	case leftType == object.INTEGER && rightType == object.FLOAT:
+		left = &object.Float{Value: float64(left.(*object.Integer).Value)}
+		return vm.executeBinaryFloatOperation(op, left, right)
+	case leftType == object.FLOAT && rightType == object.INTEGER:
+		right = &object.Float{Value: float64(right.(*object.Integer).Value)}
+		return vm.executeBinaryFloatOperation(op, left, right)
 	case leftType == object.STRING && rightType == object.STRING:
 		return vm.executeBinaryStringOperation(op, left, right)
 	default:
-		return fmt.Errorf("unsupported types for binary operation: %s %s",
-			leftType, rightType)
+		return unknownOperator(op, left, right)
 	}
 }
 
+// operators are the operators of the binary operation opcodes as written in
+// the source
+var operators = map[code.Opcode]string{
+	code.Add:        "+",
+	code.Sub:        "-",
+	code.Mul:        "*",
+	code.Div:        "/",
+	code.Mod:        "%",
+	code.BitwiseOR:  "|",
+	code.BitwiseXOR: "^",
+	code.BitwiseAND: "&",
+	code.LeftShift:  "<<",
+	code.RightShift: ">>",
+	code.Or:         "||",
+	code.And:        "&&",
+}
+
+// unknownOperator returns the error for the binary operation op that is not
+// supported by the types of its operands
+func unknownOperator(op code.Opcode, left, right object.Object) error {
+	return fmt.Errorf("TypeError: unknown operator: %s %s %s",
+		left.Type(), operators[op], right.Type())
+}
+
 func (vm *VM) executeBinaryStringOperation(
 	op code.Opcode,
 	left, right object.Object,
 ) error {
 	if op != code.Add {
-		return fmt.Errorf("unknown string operator: %d", op)
+		return unknownOperator(op, left, right)
 	}
 
 	leftValue := left.(*object.String).Value
@@ -313,7 +514,7 @@ func (vm *VM) executeBinaryBooleanOperation(
 	case code.And:
 		result = leftValue && rightValue
 	default:
-		return fmt.Errorf("unknown boolean operator: %d", op)
+		return unknownOperator(op, left, right)
 	}
 
 	return vm.push(&object.Boolean{Value: result})
@@ -326,6 +527,10 @@ func (vm *VM) executeBinaryIntegerOperation(
 	leftValue := left.(*object.Integer).Value
 	rightValue := right.(*object.Integer).Value
 
+	if (op == code.Div || op == code.Mod) && rightValue == 0 {
+		return fmt.Errorf("ZeroDivisionError: integer division or modulo by zero")
+	}
+
 	var result int64
 
 	switch op {
@@ -350,12 +555,39 @@ func (vm *VM) executeBinaryIntegerOperation(
 	case code.RightShift:
 		result = leftValue >> uint64(rightValue)
 	default:
-		return fmt.Errorf("unknown integer operator: %d", op)
+		return unknownOperator(op, left, right)
 	}
 
 	return vm.push(&object.Integer{Value: result})
 }
 
+func (vm *VM) executeBinaryFloatOperation(
+	op code.Opcode,
+	left, right object.Object,
+) error {
+	leftValue := left.(*object.Float).Value
+	rightValue := right.(*object.Float).Value
+
+	var result float64
+
+	switch op {
+	case code.Add:
+		result = leftValue + rightValue
+	case code.Sub:
+		result = leftValue - rightValue
+	case code.Mul:
+		result = leftValue * rightValue
+	case code.Div:
+		result = leftValue / rightValue
+	case code.Mod:
+		result = math.Mod(leftValue, rightValue)
+	default:
+		return unknownOperator(op, left, right)
+	}
+
+	return vm.push(&object.Float{Value: result})
+}
+
 func (vm *VM) executeComparison(op code.Opcode) error {
 	right := vm.pop()
 	left := vm.pop()
@@ -380,18 +612,16 @@ func (vm *VM) executeBitwiseNotOperator() error {
 	if i, ok := operand.(*object.Integer); ok {
 		return vm.push(&object.Integer{Value: ^i.Value})
 	}
-	return fmt.Errorf("expected int got=%T", operand)
+	return fmt.Errorf("TypeError: unknown operator: ~%s", operand.Type())
 }
 
 func (vm *VM) executeNotOperator() error {
 	operand := vm.pop()
 
-	switch operand {
-	case True:
-		return vm.push(False)
-	case False:
-		return vm.push(True)
-	case Null:
+	switch operand := operand.(type) {
+	case *object.Boolean:
+		return vm.push(nativeBoolToBooleanObject(!operand.Value))
+	case *object.Null:
 		return vm.push(True)
 	default:
 		return vm.push(False)
@@ -400,10 +630,21 @@ func (vm *VM) executeNotOperator() error {
 
 func (vm *VM) executeMinusOperator() error {
 	operand := vm.pop()
-	if i, ok := operand.(*object.Integer); ok {
-		return vm.push(&object.Integer{Value: -i.Value})
+	switch obj := operand.(type) {
+	case *object.Integer:
+		return vm.push(&object.Integer{Value: -obj.Value})
+	case *object.Float:
+		return vm.push(&object.Float{Value: -obj.Value})
+	}
+	return fmt.Errorf("TypeError: unknown operator: -%s", operand.Type())
+}
+
+func (vm *VM) executeGetIter(obj object.Object) error {
+	iterable, ok := obj.(object.Iterable)
+	if !ok {
+		return fmt.Errorf("TypeError: object of type '%s' is not iterable", obj.Type())
 	}
-	return fmt.Errorf("expected int got=%T", operand)
+	return vm.push(iterable.Iter())
 }
 
 func (vm *VM) executeSetItem(left, index, value object.Object) error {
@@ -414,7 +655,7 @@ func (vm *VM) executeSetItem(left, index, value object.Object) error {
 		return vm.executeHashSetItem(left, index, value)
 	default:
 		return fmt.Errorf(
-			"set item operation not supported: left=%s index=%s",
+			"TypeError: set item operation not supported: left=%s index=%s",
 			left.Type(), index.Type(),
 		)
 	}
@@ -432,14 +673,25 @@ func (vm *VM) executeGetItem(left, index object.Object) error {
 		return vm.executeHashGetItem(left, index)
 	case left.Type() == object.MODULE:
 		return vm.executeHashGetItem(left.(*object.Module).Attrs, index)
+	case left.Type() == object.ERROR && index.Type() == object.STRING:
+		return vm.executeErrorGetItem(left, index)
 	default:
 		return fmt.Errorf(
-			"index operator not supported: left=%s index=%s",
+			"TypeError: index operator not supported: left=%s index=%s",
 			left.Type(), index.Type(),
 		)
 	}
 }
 
+func (vm *VM) executeErrorGetItem(err, index object.Object) error {
+	attr := err.(*object.Error).Get(index.(*object.String).Value)
+	if attr == nil {
+		return vm.push(Null)
+	}
+
+	return vm.push(attr)
+}
+
 func (vm *VM) executeStringGetItem(str, index object.Object) error {
 	stringObject := str.(*object.String)
 	i := index.(*object.Integer).Value
@@ -481,7 +733,7 @@ func (vm *VM) executeArraySetItem(array, index, value object.Object) error {
 	max := int64(len(arrayObject.Elements) - 1)
 
 	if i < 0 || i > max {
-		return fmt.Errorf("index out of bounds: %d", i)
+		return fmt.Errorf("IndexError: index out of bounds: %d", i)
 	}
 
 	arrayObject.Elements[i] = value
@@ -491,12 +743,12 @@ func (vm *VM) executeArraySetItem(array, index, value object.Object) error {
 func (vm *VM) executeHashGetItem(hash, index object.Object) error {
 	hashObject := hash.(*object.Hash)
 
-	key, ok := index.(object.Hashable)
+	key, ok := object.AsHashable(index)
 	if !ok {
-		return fmt.Errorf("unusable as hash key: %s", index.Type())
+		return fmt.Errorf("TypeError: unusable as hash key: %s", index.Type())
 	}
 
-	pair, ok := hashObject.Pairs[key.HashKey()]
+	pair, ok := hashObject.Get(key)
 	if !ok {
 		return vm.push(Null)
 	}
@@ -507,13 +759,12 @@ func (vm *VM) executeHashGetItem(hash, index object.Object) error {
 func (vm *VM) executeHashSetItem(hash, index, value object.Object) error {
 	hashObject := hash.(*object.Hash)
 
-	key, ok := index.(object.Hashable)
+	key, ok := object.AsHashable(index)
 	if !ok {
-		return fmt.Errorf("unusable as hash key: %s", index.Type())
+		return fmt.Errorf("TypeError: unusable as hash key: %s", index.Type())
 	}
 
-	hashed := key.HashKey()
-	hashObject.Pairs[hashed] = object.HashPair{Key: index, Value: value}
+	hashObject.Set(key, value)
 
 	return vm.push(Null)
 }
@@ -529,23 +780,21 @@ func (vm *VM) buildArray(startIndex, endIndex int) object.Object {
 }
 
 func (vm *VM) buildHash(startIndex, endIndex int) (object.Object, error) {
-	hashedPairs := make(map[object.HashKey]object.HashPair)
+	hash := object.NewHash((endIndex - startIndex) / 2)
 
 	for i := startIndex; i < endIndex; i += 2 {
 		key := vm.stack[i]
 		value := vm.stack[i+1]
 
-		pair := object.HashPair{Key: key, Value: value}
-
-		hashKey, ok := key.(object.Hashable)
+		hashKey, ok := object.AsHashable(key)
 		if !ok {
-			return nil, fmt.Errorf("unusable as hash key: %s", key.Type())
+			return nil, fmt.Errorf("TypeError: unusable as hash key: %s", key.Type())
 		}
 
-		hashedPairs[hashKey.HashKey()] = pair
+		hash.Set(hashKey, value)
 	}
 
-	return &object.Hash{Pairs: hashedPairs}, nil
+	return hash, nil
 }
 
 func (vm *VM) executeCall(numArgs int) error {
@@ -556,35 +805,57 @@ func (vm *VM) executeCall(numArgs int) error {
 	case *object.Builtin:
 		return vm.callBuiltin(callee, numArgs)
 	default:
-		return fmt.Errorf(
-			"calling non-closure and non-builtin: %T %v",
-			callee, callee,
-		)
+		return fmt.Errorf("TypeError: not a function: %s", callee.Type())
 	}
 }
 
 func (vm *VM) callClosure(cl *object.Closure, numArgs int) error {
 	if numArgs != cl.Fn.NumParameters {
-		return fmt.Errorf("wrong number of arguments: want=%d, got=%d",
+		return fmt.Errorf("TypeError: wrong number of arguments: want=%d, got=%d",
 			cl.Fn.NumParameters, numArgs)
 	}
 
-	// Optimize tail calls and avoid creating a new frame
-	if cl.Fn == vm.currentFrame().cl.Fn {
-		nextOp := vm.currentFrame().NextOp()
-		if nextOp == code.Return {
-			for p := 0; p < numArgs; p++ {
-				vm.stack[vm.currentFrame().basePointer+p] = vm.stack[vm.sp-numArgs+p]
-			}
-			vm.sp -= numArgs + 1
-			vm.currentFrame().ip = -1 // reset IP to beginning of the frame
-			return nil
-		}
+	frame := NewFrame(cl, vm.sp-numArgs)
+	if err := vm.grow(frame.basePointer + cl.Fn.NumLocals); err != nil {
+		return err
+	}
+	if err := vm.pushFrame(frame); err != nil {
+		return err
 	}
 
-	frame := NewFrame(cl, vm.sp-numArgs)
-	vm.pushFrame(frame)
+	vm.sp = frame.basePointer + cl.Fn.NumLocals
+
+	return nil
+}
+
+// executeTailCall calls the function in tail position, the frame of the
+// function making the call is replaced by the frame of the closure called so
+// tail calls run in constant frame space. Builtins are called as usual.
+func (vm *VM) executeTailCall(numArgs int) error {
+	cl, ok := vm.stack[vm.sp-1-numArgs].(*object.Closure)
+	if !ok || vm.framesIndex == 1 {
+		return vm.executeCall(numArgs)
+	}
 
+	if numArgs != cl.Fn.NumParameters {
+		return fmt.Errorf("TypeError: wrong number of arguments: want=%d, got=%d",
+			cl.Fn.NumParameters, numArgs)
+	}
+
+	// Move the closure and its arguments down over those of the frame
+	frame := vm.currentFrame()
+	if err := vm.grow(frame.basePointer + cl.Fn.NumLocals); err != nil {
+		return err
+	}
+	copy(vm.stack[frame.basePointer-1:], vm.stack[vm.sp-1-numArgs:vm.sp])
+
+	// Drop the handlers of try blocks of the replaced frame
+	for len(vm.handlers) > 0 &&
+		vm.handlers[len(vm.handlers)-1].framesIndex >= vm.framesIndex {
+		vm.handlers = vm.handlers[:len(vm.handlers)-1]
+	}
+
+	vm.frames[vm.framesIndex-1] = NewFrame(cl, frame.basePointer)
 	vm.sp = frame.basePointer + cl.Fn.NumLocals
 
 	return nil
@@ -593,9 +864,18 @@ func (vm *VM) callClosure(cl *object.Closure, numArgs int) error {
 func (vm *VM) callBuiltin(builtin *object.Builtin, numArgs int) error {
 	args := vm.stack[vm.sp-numArgs : vm.sp]
 
-	result := builtin.Fn(args...)
+	result := builtin.Call(vm.Context, args...)
 	vm.sp = vm.sp - numArgs - 1
 
+	// Errors returned by builtins are raised unless previously caught
+	if err, ok := result.(*object.Error); ok && !err.Caught {
+		// Errors raised by functions the builtin called are traced from here
+		if err.Trace != nil {
+			err.Trace = append(vm.trace(), err.Trace...)
+		}
+		return err
+	}
+
 	if result != nil {
 		vm.push(result)
 	} else {
@@ -605,6 +885,95 @@ func (vm *VM) callBuiltin(builtin *object.Builtin, numArgs int) error {
 	return nil
 }
 
+func (vm *VM) executeThrow(obj object.Object) error {
+	switch obj := obj.(type) {
+	case *object.Error:
+		return obj.Raise()
+	case *object.String:
+		return &object.Error{Message: obj.Value}
+	default:
+		return fmt.Errorf(
+			"TypeError: exceptions must be str or error, not %s",
+			obj.Type(),
+		)
+	}
+}
+
+// executeSpawn runs the closure or builtin below its arguments on the stack
+// as a new task with its own virtual machine sharing the state (globals and
+// constants) of the VM. Errors not caught by the task are printed to the
+// standard error of the execution context as it has no caller to raise them.
+func (vm *VM) executeSpawn(numArgs int) error {
+	fn := vm.stack[vm.sp-1-numArgs]
+	switch fn.(type) {
+	case *object.Closure, *object.Builtin:
+	default:
+		return fmt.Errorf("TypeError: spawn expected a function, got `%s`", fn.Type())
+	}
+
+	args := make([]object.Object, numArgs)
+	copy(args, vm.stack[vm.sp-numArgs:vm.sp])
+	vm.sp = vm.sp - numArgs - 1
+
+	task := NewCall(vm.state, fn, args...)
+	task.Options = vm.Options
+	task.Context = vm.Context
+
+	ctx := vm.Context
+	ctx.Spawn(func() {
+		if err := task.execute(); err != nil {
+			if e, ok := err.(*Error); ok {
+				// Drop the frame of the call made by the task's machine
+				if len(e.Trace) > 1 {
+					e.Trace = e.Trace[1:]
+				}
+				fmt.Fprint(ctx.Stderr, e.StackTrace())
+			} else {
+				fmt.Fprintln(ctx.Stderr, err)
+			}
+		}
+	})
+
+	return vm.push(Null)
+}
+
+// executeSelect waits for one of the cases of a select expression, given by
+// the channel, value to send (or null) and whether it is sent on the stack,
+// to proceed and pushes the value received (or null) and the index of the
+// case selected. The index is numCases if none of the cases can proceed and
+// there is a default.
+func (vm *VM) executeSelect(numCases int, hasDefault bool) error {
+	cases := make([]object.SelectCase, numCases)
+	base := vm.sp - 3*numCases
+	for i := range cases {
+		ch, ok := vm.stack[base+3*i].(*object.Channel)
+		if !ok {
+			return fmt.Errorf(
+				"TypeError: select case #%d expected a `channel` got `%s`",
+				i+1, vm.stack[base+3*i].Type(),
+			)
+		}
+		cases[i].Channel = ch
+		if isTruthy(vm.stack[base+3*i+2]) {
+			cases[i].Value = vm.stack[base+3*i+1]
+		}
+	}
+	vm.sp = base
+
+	chosen, obj, err := object.Select(vm.Context, cases, !hasDefault)
+	if err != nil {
+		return fmt.Errorf("ChannelError: %s", err)
+	}
+	if chosen == -1 {
+		chosen = numCases
+	}
+
+	if err := vm.push(obj); err != nil {
+		return err
+	}
+	return vm.push(&object.Integer{Value: int64(chosen)})
+}
+
 func (vm *VM) pushClosure(constIndex, numFree int) error {
 	constant := vm.state.Constants[constIndex]
 	function, ok := constant.(*object.CompiledFunction)
@@ -631,7 +1000,7 @@ func (vm *VM) loadModule(name object.Object) error {
 		)
 	}
 
-	attrs, err := ExecModule(s.Value, vm.state)
+	attrs, err := execModule(s.Value, vm.state, vm.Options, vm.Context)
 	if err != nil {
 		return err
 	}
@@ -644,7 +1013,88 @@ func (vm *VM) LastPopped() object.Object {
 	return vm.stack[vm.sp]
 }
 
+// Run executes the bytecode instructions until the main function returns or
+// an error occurs. Errors are returned as an *Error which holds a stack trace
+// of the frames being executed at the time. Tasks spawned by the program
+// keep running after Run returns, while the program is not running.
 func (vm *VM) Run() error {
+	// The program holds the lock of its tasks while it runs
+	vm.Context.Acquire()
+	defer vm.Context.Release()
+
+	vm.Context.Call = vm.call
+
+	return vm.execute()
+}
+
+// call calls fn with args on a new machine sharing the program's state and
+// returns the value it returns or the error it raised, see Context.Call
+func (vm *VM) call(fn object.Object, args ...object.Object) object.Object {
+	machine := NewCall(vm.state, fn, args...)
+	machine.Options = vm.Options
+	machine.Context = vm.Context
+
+	if err := machine.execute(); err != nil {
+		if e, ok := err.(*Error); ok {
+			obj := e.Err.(*object.Error)
+			// Drop the frame of the call made by the machine
+			if len(obj.Trace) > 1 {
+				obj.Trace = obj.Trace[1:]
+			}
+			return obj
+		}
+		return &object.Error{Message: err.Error()}
+	}
+
+	if result := machine.LastPopped(); result != nil {
+		return result
+	}
+	return Null
+}
+
+// execute executes the bytecode instructions, see Run, and handles errors
+// raised in try blocks
+func (vm *VM) execute() error {
+	for {
+		err := vm.run()
+		if err == nil || err == ErrHalted {
+			return err
+		}
+
+		obj := vm.errorObject(err)
+		if !vm.handle(obj) {
+			return &Error{Err: obj, Trace: obj.Trace}
+		}
+	}
+}
+
+// handle unwinds the frames and the stack to the innermost handler (if any)
+// and resumes execution at the handler's position with the caught error
+// pushed onto the stack. Returns false if there is no handler.
+func (vm *VM) handle(err *object.Error) bool {
+	if len(vm.handlers) == 0 {
+		return false
+	}
+
+	h := vm.handlers[len(vm.handlers)-1]
+	vm.handlers = vm.handlers[:len(vm.handlers)-1]
+
+	vm.framesIndex = h.framesIndex
+	vm.sp = h.sp
+
+	caught := err.Clone().(*object.Error)
+	caught.Caught = true
+	// A full stack overflows again, which is raised in the outer handler
+	if err := vm.push(caught); err != nil {
+		return vm.handle(vm.errorObject(err))
+	}
+
+	vm.currentFrame().ip = h.pos - 1
+
+	return true
+}
+
+func (vm *VM) run() error {
 	var (
 		ip  int
 		ins code.Instructions
@@ -658,6 +1108,10 @@ func (vm *VM) Run() error {
 		ins = vm.currentFrame().Instructions()
 		op = code.Opcode(ins[ip])
 
+		if vm.Hook != nil && !vm.Hook(vm) {
+			return ErrHalted
+		}
+
 		if vm.Debug {
 			log.Printf(
 				"%-25s %-20s\n",
@@ -697,7 +1151,7 @@ func (vm *VM) Run() error {
 		case code.AssignGlobal:
 			globalIndex := code.ReadUint16(ins[ip+1:])
 			vm.currentFrame().ip += 2
-			vm.state.Globals[globalIndex] = vm.pop()
+			vm.state.setGlobal(int(globalIndex), vm.pop())
 
 			err := vm.push(Null)
 			if err != nil {
@@ -722,9 +1176,9 @@ func (vm *VM) Run() error {
 
 			ref := vm.pop()
 			if immutable, ok := ref.(object.Immutable); ok {
-				vm.state.Globals[globalIndex] = immutable.Clone()
+				vm.state.setGlobal(int(globalIndex), immutable.Clone())
 			} else {
-				vm.state.Globals[globalIndex] = ref
+				vm.state.setGlobal(int(globalIndex), ref)
 			}
 
 			err := vm.push(Null)
@@ -736,7 +1190,7 @@ func (vm *VM) Run() error {
 			globalIndex := code.ReadUint16(ins[ip+1:])
 			vm.currentFrame().ip += 2
 
-			err := vm.push(vm.state.Globals[globalIndex])
+			err := vm.push(vm.state.global(int(globalIndex)))
 			if err != nil {
 				return err
 			}
@@ -907,17 +1361,34 @@ func (vm *VM) Run() error {
 			numArgs := code.ReadUint8(ins[ip+1:])
 			vm.currentFrame().ip += 1
 
+			vm.Context.Yield()
 			err := vm.executeCall(int(numArgs))
 			if err != nil {
 				return err
 			}
 
+		case code.TailCall:
+			numArgs := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			vm.Context.Yield()
+			err := vm.executeTailCall(int(numArgs))
+			if err != nil {
+				return err
+			}
+
 		case code.Return:
 			returnValue := vm.pop()
 
 			frame := vm.popFrame()
 			vm.sp = frame.basePointer - 1
 
+			// Drop the handlers of try blocks of the returning frame
+			for len(vm.handlers) > 0 &&
+				vm.handlers[len(vm.handlers)-1].framesIndex > vm.framesIndex {
+				vm.handlers = vm.handlers[:len(vm.handlers)-1]
+			}
+
 			err := vm.push(returnValue)
 			if err != nil {
 				return err
@@ -936,6 +1407,81 @@ func (vm *VM) Run() error {
 			pos := int(code.ReadUint16(ins[ip+1:]))
 			vm.currentFrame().ip = pos - 1
 
+			// Let other tasks run (if any) in loops
+			vm.Context.Yield()
+
+		case code.Spawn:
+			numArgs := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			err := vm.executeSpawn(int(numArgs))
+			if err != nil {
+				return err
+			}
+
+		case code.Select:
+			numCases := code.ReadUint16(ins[ip+1:])
+			hasDefault := code.ReadUint8(ins[ip+3:])
+			vm.currentFrame().ip += 3
+
+			err := vm.executeSelect(int(numCases), hasDefault == 1)
+			if err != nil {
+				return err
+			}
+
+		case code.JumpIfNotCase:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			n := int(code.ReadUint16(ins[ip+3:]))
+			vm.currentFrame().ip += 4
+
+			if vm.stack[vm.sp-1].(*object.Integer).Value == int64(n) {
+				vm.pop()
+			} else {
+				vm.currentFrame().ip = pos - 1
+			}
+
+		case code.GetIter:
+			err := vm.executeGetIter(vm.pop())
+			if err != nil {
+				return err
+			}
+
+		case code.IterNext:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			iterator := vm.stack[vm.sp-1].(object.Iterator)
+			key, value, ok := iterator.Next()
+			if !ok {
+				vm.currentFrame().ip = pos - 1
+				break
+			}
+
+			err := vm.push(key)
+			if err != nil {
+				return err
+			}
+			err = vm.push(value)
+			if err != nil {
+				return err
+			}
+
+		case code.SetupTry:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			vm.handlers = append(vm.handlers, handler{
+				framesIndex: vm.framesIndex,
+				sp:          vm.sp,
+				pos:         pos,
+			})
+
+		case code.PopTry:
+			vm.handlers = vm.handlers[:len(vm.handlers)-1]
+
+		case code.Throw:
+			return vm.executeThrow(vm.pop())
+
 		case code.Pop:
 			vm.pop()
 
@@ -1,8 +1,12 @@
 package vm
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"path"
 	"path/filepath"
 	"strings"
@@ -37,40 +41,78 @@ func testIntegerObject(expected int64, actual object.Object) error {
 	return nil
 }
 
+func testFloatObject(expected float64, actual object.Object) error {
+	result, ok := actual.(*object.Float)
+	if !ok {
+		return fmt.Errorf("object is not Float. got=%T (%+v) want=%g",
+			actual, actual, expected)
+	}
+
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value. got=%g, want=%g",
+			result.Value, expected)
+	}
+
+	return nil
+}
+
 type vmTestCase struct {
 	input    string
 	expected interface{}
 }
 
+// runVmTests runs the tests with and without optimizations, optimized
+// programs must give identical results
 func runVmTests(t *testing.T, tests []vmTestCase) {
 	t.Helper()
 
-	for _, tt := range tests {
-		program := parse(tt.input)
-
-		comp := compiler.New()
-		err := comp.Compile(program)
-		if err != nil {
-			t.Log(tt.input)
-			t.Fatalf("compiler error: %s", err)
+	for _, optimize := range []bool{false, true} {
+		for _, tt := range tests {
+			runVmTest(t, tt, optimize)
 		}
+	}
+}
 
-		vm := New(comp.Bytecode())
+func runVmTest(t *testing.T, tt vmTestCase, optimize bool) {
+	t.Helper()
 
-		err = vm.Run()
-		if err != nil {
-			t.Log(tt.input)
-			t.Fatalf("vm error: %s", err)
-		}
-		if vm.sp != 0 {
-			t.Log(tt.input)
-			t.Fatal("vm stack pointer non-zero")
-		}
+	program := parse(tt.input)
+
+	comp := compiler.New()
+	comp.Optimize = optimize
+	err := comp.Compile(program)
+	if err != nil {
+		t.Log(tt.input)
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	vm := New(comp.Bytecode())
 
-		stackElem := vm.LastPopped()
+	err = vm.Run()
 
-		testExpectedObject(t, tt.expected, stackElem)
+	// Errors are raised and expected as the error the vm returns
+	if _, ok := tt.expected.(*object.Error); ok {
+		vmErr, ok := err.(*Error)
+		if !ok {
+			t.Logf("%s (optimize=%t)", tt.input, optimize)
+			t.Fatalf("expected vm error. got=%T (%+v)", err, err)
+		}
+		testExpectedObject(t, tt.expected, vmErr.Err.(*object.Error))
+		return
 	}
+
+	if err != nil {
+		t.Logf("%s (optimize=%t)", tt.input, optimize)
+		t.Fatalf("vm error: %s", err)
+	}
+	if vm.sp != 0 {
+		t.Logf("%s (optimize=%t)", tt.input, optimize)
+		t.Fatal("vm stack pointer non-zero")
+	}
+
+	stackElem := vm.LastPopped()
+
+	testExpectedObject(t, tt.expected, stackElem)
 }
 
 func testExpectedObject(
@@ -89,14 +131,14 @@ func testExpectedObject(
 			return
 		}
 
-		if len(hash.Pairs) != len(expected) {
+		if hash.Len() != len(expected) {
 			t.Errorf("hash has wrong number of Pairs. want=%d, got=%d",
-				len(expected), len(hash.Pairs))
+				len(expected), hash.Len())
 			return
 		}
 
 		for expectedKey, expectedValue := range expected {
-			pair, ok := hash.Pairs[expectedKey]
+			pair, ok := hashPairs(hash)[expectedKey]
 			if !ok {
 				t.Errorf("no pair for given key in Pairs")
 			}
@@ -139,6 +181,12 @@ func testExpectedObject(
 			t.Errorf("testIntegerObject failed: %s", err)
 		}
 
+	case float64:
+		err := testFloatObject(expected, actual)
+		if err != nil {
+			t.Errorf("testFloatObject failed: %s", err)
+		}
+
 	case bool:
 		err := testBooleanObject(bool(expected), actual)
 		if err != nil {
@@ -193,6 +241,30 @@ func testBooleanObject(expected bool, actual object.Object) error {
 	return nil
 }
 
+func TestFloatArithmetic(t *testing.T) {
+	tests := []vmTestCase{
+		{"1.5", 1.5},
+		{"1e3", 1000.0},
+		{"1.5 + 2.25", 3.75},
+		{"1 + 2.5", 3.5},
+		{"2.5 + 1", 3.5},
+		{"5.0 - 7", -2.0},
+		{"2 * 1.5", 3.0},
+		{"7 / 2.0", 3.5},
+		{"7.5 % 2", 1.5},
+		{"-1.5", -1.5},
+		{"1.5 < 2", true},
+		{"2 > 1.5", true},
+		{"1 == 1.0", true},
+		{"1.0 == 1", true},
+		{"1.5 != 1.5", false},
+		{"2.5 >= 2.5", true},
+		{"!1.5", false},
+	}
+
+	runVmTests(t, tests)
+}
+
 func TestIntegerArithmetic(t *testing.T) {
 	tests := []vmTestCase{
 		{"1", 1},
@@ -312,11 +384,386 @@ func TestIterations(t *testing.T) {
 		{"n := 10; while (n > 0) { n = n - 1 }; n", 0},
 		{"n := 0; while (n < 10) { n = n + 1 }", nil},
 		{"n := 10; while (n > 0) { n = n - 1 }", nil},
+		{"n := 0; while (true) { n = n + 1; if (n == 5) { break } }; n", 5},
+		{"n := 0; while (true) { break; n = 1 }", nil},
+		{"n := 0; s := 0; while (n < 10) { n = n + 1; if (n % 2 == 0) { continue } s = s + n }; s", 25},
+		{`
+		n := 0
+		i := 0
+		while (i < 3) {
+			i = i + 1
+			j := 0
+			while (true) {
+				j = j + 1
+				if (j > 2) { break }
+				if (j == 1) { continue }
+				n = n + 1
+			}
+		}
+		n`, 3},
+		{"f := fn() { n := 0; while (true) { n = n + 1; if (n == 3) { break } }; return n }; f()", 3},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestForIterations(t *testing.T) {
+	tests := []vmTestCase{
+		{"for x in [] { }", nil},
+		{"s := 0; for x in [1, 2, 3] { s = s + x }; s", 6},
+		{"s := 0; for i, x in [1, 2, 3] { s = s + i * x }; s", 8},
+		{`s := ""; for c in "héllo" { s = c + s }; s`, "olléh"},
+		{`s := 0; for i, c in "abc" { s = s + i }; s`, 3},
+		{`s := ""; for k, v in {"b": 2, "a": 1} { s = s + k + str(v) }; s`, "b2a1"},
+		{`h := {"b": 2, "a": 1}; h["c"] = 3; h["b"] = 4; s := ""; for k, v in h { s = s + k + str(v) }; s`, "b4a1c3"},
+		{`s := 0; for v in {"b": 2, "a": 1} { s = s + v }; s`, 3},
+		{"s := 0; for x in range(5) { s = s + x }; s", 10},
+		{"s := 0; for x in range(10, 0, -3) { s = s + x }; s", 22},
+		{"s := 0; for x in range(10) { if (x == 4) { break } s = s + x }; s", 6},
+		{"s := 0; for x in range(10) { if (x % 2 == 0) { continue } s = s + x }; s", 25},
+		{"s := 0; for x in range(3) { for y in range(3) { if (y > x) { break } s = s + 1 } }; s", 6},
+		{"f := fn(xs) { t := 0; for x in xs { t = t + x }; return t }; f(range(101))", 5050},
+		{"f := fn() { for x in range(10) { if (x == 4) { return x } } }; f()", 4},
+		{"x := 0; for x in [1, 2] { }; x", 2},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestTryExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"try { 1 } catch { 2 }", 1},
+		{"try { 1 / 0 } catch { 2 }", 2},
+		{`try { throw "oops" } catch (e) { e.message }`, "oops"},
+		{`try { throw "oops" } catch (e) { e.kind }`, "Error"},
+		{`try { 1 / 0 } catch (e) { e.kind }`, "ZeroDivisionError"},
+		{`try { 1 / 0 } catch (e) { e.message }`, "integer division or modulo by zero"},
+		{`try { 1 + "a" } catch (e) { e.kind }`, "TypeError"},
+		{`try { 1 + "a" } catch (e) { e.message }`, "unknown operator: int + str"},
+		{`try { [1][1] = 2 } catch (e) { e.kind }`, "IndexError"},
+		{`try { fn(x) { x }() } catch (e) { e.message }`, "wrong number of arguments: want=1, got=0"},
+		{`try { x := 1; x[0] } catch (e) { e.message }`, "index operator not supported: left=int index=int"},
+		{`try { len(1) } catch (e) { e.kind }`, "TypeError"},
+		{`try { throw 1 } catch (e) { e.kind }`, "TypeError"},
+		{`try { throw "ValueError: bad" } catch (e) { e.message }`, "bad"},
+		{`e := try { throw "oops" } catch (e) { e }; type(e)`, "error"},
+		{`try { try { throw "a" } catch (e) { throw e } } catch (e) { e.message }`, "a"},
+		{"x := 0; try { x = 1 } finally { x = x + 1 }; x", 2},
+		{"x := 0; try { try { 1 / 0 } finally { x = 1 } } catch { x = x + 1 }; x", 2},
+		{"x := 0; try { 1 / 0 } catch { x = 1 } finally { x = x * 10 }; x", 10},
+		{"x := 0; try { try { 1 / 0 } catch { throw \"b\" } finally { x = 1 } } catch { }; x", 1},
+		{"f := fn() { throw \"oops\" }; try { f() } catch (e) { e.message }", "oops"},
+		{"g := fn() { return 1 / 0 }; f := fn() { g() }; try { f() } catch (e) { e.kind }", "ZeroDivisionError"},
+		{"x := 0; f := fn() { try { return 1 } finally { x = 2 } }; f() + x", 3},
+		{"f := fn() { try { return 1 } finally { return 2 } }; f()", 2},
+		{"f := fn() { try { 1 / 0 } catch { return 1 } }; f()", 1},
+		{"x := 0; for i in range(3) { try { continue } finally { x = x + 1 } }; x", 3},
+		{"x := 0; for i in range(3) { try { break } finally { x = x + 1 } }; x", 1},
+		{"x := 0; while (true) { try { 1 / 0 } catch { break } }; try { 1 / 0 } catch { x = 1 }; x", 1},
+		{"s := 0; for i in range(5) { try { if (i == 2) { throw \"skip\" } s = s + i } catch { } }; s", 8},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestUncaughtErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{`throw "oops"`, &object.Error{Message: "oops"}},
+		{`1 / 0`, &object.Error{Message: "ZeroDivisionError: integer division or modulo by zero"}},
+		{`try { throw "a" } finally { 1 }`, &object.Error{Message: "a"}},
+		{`try { throw "a" } catch (e) { throw "b" }`, &object.Error{Message: "b"}},
+		{`try { 1 } catch (e) { 2 }; throw "c"`, &object.Error{Message: "c"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestRethrowKeepsStackTrace(t *testing.T) {
+	input := `f := fn() {
+  throw "oops"
+}
+try {
+  f()
+} catch (e) {
+  throw e
+}`
+
+	l := lexer.NewWithFilename(input, "test.monkey")
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	comp := compiler.New()
+	err := comp.Compile(program)
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	vm := New(comp.Bytecode())
+	err = vm.Run()
+	if err == nil {
+		t.Fatalf("expected VM error but resulted in none.")
+	}
+
+	vmErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("error is not *Error. got=%T (%+v)", err, err)
+	}
+
+	expected := `Traceback (most recent call last):
+  File "test.monkey", line 5, column 4, in <main>
+  File "test.monkey", line 2, column 3, in f
+oops
+`
+	if vmErr.StackTrace() != expected {
+		t.Fatalf("wrong stack trace: want=%q, got=%q", expected, vmErr.StackTrace())
+	}
+}
+
+func TestSpawnAndSelectExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"c := channel(); spawn fn() { send(c, 42) }(); recv(c)", 42},
+		{"c := channel(); f := fn(x) { send(c, x * 2) }; for i in range(3) { spawn f(i) }; recv(c) + recv(c) + recv(c)", 6},
+		{"c := channel(1); spawn send(c, 3); recv(c)", 3},
+		{"x := 0; c := channel(); spawn fn() { x = 1; send(c, true) }(); recv(c); x", 1},
+		{"n := 0; c := channel(1); spawn fn() { for i in range(5000) { n = n + 1 }; send(c, n) }(); while (len(c) == 0) { }; recv(c)", 5000},
+		{"c := channel(1); send(c, 1); recv(c)", 1},
+		{"c := channel(); close(c); recv(c)", Null},
+		{`c := channel(1); select { case send(c, 1) { "sent" } default { "full" } }`, "sent"},
+		{`c := channel(1); send(c, 1); select { case send(c, 2) { "sent" } default { "full" } }`, "full"},
+		{"c := channel(); select { case recv(c) { 1 } default { 2 } }", 2},
+		{"c := channel(1); send(c, 5); select { case v := recv(c) { v * 2 } }", 10},
+		{"c := channel(); d := channel(1); send(d, 1); select { case recv(c) { 1 } case recv(d) { 2 } }", 2},
+		{"c := channel(); close(c); select { case v := recv(c) { v } }", Null},
+		{"c := channel(); select { case recv(c) { 1 } default { } }", Null},
+		{"f := fn(c) { select { case v := recv(c) { return v } } }; c := channel(1); send(c, 7); f(c)", 7},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestChannelErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{"spawn 1", &object.Error{Message: "TypeError: spawn expected a function, got `int`"}},
+		{"select { case recv(1) { } }", &object.Error{Message: "TypeError: select case #1 expected a `channel` got `int`"}},
+		{"c := channel(); close(c); close(c)", &object.Error{Message: "ChannelError: close of closed channel"}},
+		{"c := channel(); close(c); send(c, 1)", &object.Error{Message: "ChannelError: send on closed channel"}},
+		{"c := channel(); close(c); select { case send(c, 1) { } }", &object.Error{Message: "ChannelError: send on closed channel"}},
+		{"channel(-1)", &object.Error{Message: "ValueError: channel() size must not be negative"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestTaskErrors(t *testing.T) {
+	r, w := io.Pipe()
+	defer r.Close()
+
+	comp := compiler.New()
+	if err := comp.Compile(parse(`f := fn() { 1 / 0 }; spawn f()`)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	vm := New(comp.Bytecode())
+	vm.Context = &object.Context{Stdout: ioutil.Discard, Stderr: w}
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil {
+		t.Fatalf("error reading stderr: %s", err)
+	}
+	if expected := "Traceback (most recent call last):\n"; line != expected {
+		t.Errorf("stderr: want=%q, got=%q", expected, line)
+	}
+}
+
+func TestEventLoop(t *testing.T) {
+	tests := []vmTestCase{
+		{"run_loop()", Null},
+		{`s := [""]; timer(20, fn() { s[0] = s[0] + "c" }); timer(0, fn() { s[0] = s[0] + "a" }); timer(10, fn() { s[0] = s[0] + "b" }); run_loop(); s[0]`, "abc"},
+		{`s := [""]; t := timer(0, fn() { s[0] = "x" }); cancel(t); run_loop(); s[0]`, ""},
+		{`t := timer(0, fn() { }); run_loop(); cancel(t)`, false},
+		{`n := [0]; t := timer(1, fn() { n[0] = n[0] + 1; if (n[0] == 3) { cancel(t) } }, true); run_loop(); n[0]`, 3},
+		{`n := [0]; timer(1, fn() { n[0] = n[0] + 1; if (n[0] == 2) { stop_loop() } }, true); run_loop(); n[0]`, 2},
+		{`n := [0]; timer(0, fn() { timer(0, fn() { n[0] = 2 }) }); run_loop(); n[0]`, 2},
+		{"unregister(0)", false},
+		{"timer(0, fn() { 1 / 0 }); run_loop()", &object.Error{Message: "ZeroDivisionError: integer division or modulo by zero"}},
+		{"timer(0, fn() { run_loop() }); run_loop()", &object.Error{Message: "RuntimeError: the event loop is already running"}},
+		{"timer(0, fn(x) { x }); run_loop()", &object.Error{Message: "TypeError: wrong number of arguments: want=1, got=0"}},
+		{"timer(-1, fn() { })", &object.Error{Message: "ValueError: timer() delay must not be negative"}},
+		{"timer(1, 2)", &object.Error{Message: "TypeError: timer() expected argument #2 to be `fn` got `int`"}},
+		{"timer(1, fn() { }, 1)", &object.Error{Message: "TypeError: timer() expected argument #3 to be `bool` got `int`"}},
+		{`register(0, "x", print)`, &object.Error{Message: "ValueError: invalid event 'x'"}},
+		{`register(0, "", print)`, &object.Error{Message: "ValueError: no events given"}},
 	}
 
 	runVmTests(t, tests)
 }
 
+func TestEventLoopSockets(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := l.Addr().String()
+	l.Close()
+
+	input := fmt.Sprintf(`
+srv := socket("tcp4")
+bind(srv, "%[1]s")
+listen(srv, 1)
+setblocking(srv, false)
+accept(srv)
+
+register(srv, "r", fn(fd, events) {
+  conn := accept(fd)
+  register(conn, "r", fn(fd, events) {
+    data := read(fd)
+    if (data == "") {
+      close(fd)
+      close(srv)
+    } else {
+      write(fd, events + ":" + data)
+    }
+  })
+})
+
+got := [""]
+cli := socket("tcp4")
+setblocking(cli, false)
+connect(cli, "%[1]s")
+register(cli, "w", fn(fd, events) {
+  write(fd, events + ":hello")
+  register(fd, "r", fn(fd, events) {
+    got[0] = read(fd)
+    close(fd)
+  })
+})
+
+run_loop()
+got[0]
+`, address)
+
+	runVmTest(t, vmTestCase{input, "r:w:hello"}, false)
+}
+
+func TestNetModule(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			`net := import("net")
+l := net.listen("tcp", "127.0.0.1:0")
+c := net.dial("tcp", net.address(l))
+conn := accept(l)
+write(c, "hello")
+data := read(conn)
+close(c); close(conn); close(l)
+data`,
+			"hello",
+		},
+		{
+			`net := import("net")
+l := net.listen("tcp4", "127.0.0.1:0")
+c := net.dial("tcp4", net.address(l))
+conn := accept(l)
+ok := net.peer(conn) == net.address(c) && net.peer(c) == net.address(l)
+close(c); close(conn); close(l)
+ok`,
+			true,
+		},
+		{
+			`net := import("net")
+u := net.listen("udp", "127.0.0.1:0")
+c := net.dial("udp", net.address(u))
+write(c, "ping")
+msg := net.recvfrom(u)
+net.sendto(u, msg[0] + "/pong", msg[1])
+data := read(c)
+close(c); close(u)
+data`,
+			"ping/pong",
+		},
+		{`import("net").dial("unix", "/tmp/x")`, &object.Error{Message: "ValueError: unknown network 'unix'"}},
+		{`import("net").listen("tcp", 1)`, &object.Error{Message: "TypeError: net.listen() expected argument #2 to be `str` got `int`"}},
+		{`import("nosuchmodule")`, &object.Error{Message: "ImportError: no module named 'nosuchmodule'"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestHTTPModule(t *testing.T) {
+	input := `http := import("http")
+srv := http.listen("127.0.0.1:0", {
+  "GET /hello/{name}": fn(req) { return "Hello " + req.params.name + "!" },
+  "POST /echo": fn(req) {
+    return {
+      "status": 201,
+      "headers": {"X-Method": req.method},
+      "body": req.body + "?" + req.query.q + ":" + req.headers["X-Token"]
+    }
+  },
+  "/empty": fn(req) { }
+})
+spawn srv.serve()
+base := "http://" + srv.address
+
+results := []
+r := http.get(base + "/hello/monkey")
+results = push(results, str(r.status) + " " + r.body)
+r = http.post(base + "/echo?q=1", "ping", {"X-Token": "secret"})
+results = push(results, str(r.status) + " " + r.headers["X-Method"] + " " + r.body)
+r = http.request("put", base + "/empty", "")
+results = push(results, str(r.status) + " " + r.body)
+r = http.request("DELETE", base + "/hello/monkey")
+results = push(results, str(r.status))
+r = http.get(base + "/nowhere")
+results = push(results, str(r.status))
+srv.close()
+join(results, "|")`
+
+	runVmTest(t, vmTestCase{input, "200 Hello monkey!|201 POST ping?1:secret|200 |405|404"}, false)
+}
+
+func TestHTTPModuleErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{`import("http").listen("127.0.0.1:0", {"GET /{": fn(req) { }})`, &object.Error{Message: "ValueError: invalid route 'GET /{': parsing \"GET /{\": at offset 5: bad wildcard segment (must end with '}')"}},
+		{`import("http").listen("127.0.0.1:0", {"/": 1})`, &object.Error{Message: "TypeError: http.listen() expected the handler of route '/' to be `fn` got `int`"}},
+		{`import("http").get("http://[::1")`, &object.Error{Message: "ValueError: parse \"http://[::1\": missing ']' in host"}},
+		{`import("http").get("http://127.0.0.1:0/")`, &object.Error{Message: "HTTPError: Get \"http://127.0.0.1:0/\": dial tcp 127.0.0.1:0: connect: connection refused"}},
+		{`srv := import("http").listen("127.0.0.1:0", {}); srv.close(); srv.serve()`, Null},
+	}
+
+	runVmTests(t, tests)
+
+	comp := compiler.New()
+	err := comp.Compile(parse(`http := import("http")
+srv := http.listen("127.0.0.1:0", {
+  "/fail": fn(req) { 1 / 0 },
+  "/invalid": fn(req) { return 1 }
+})
+spawn srv.serve()
+results := [http.get("http://" + srv.address + "/fail").status, http.get("http://" + srv.address + "/invalid").status]
+srv.close()
+results`))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	var stderr bytes.Buffer
+	vm := New(comp.Bytecode())
+	vm.Context = &object.Context{Stdout: ioutil.Discard, Stderr: &stderr}
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	testExpectedObject(t, []int{500, 500}, vm.LastPopped())
+
+	expected := "3:24: ZeroDivisionError: integer division or modulo by zero\n" +
+		"TypeError: expected handler to return `str`, `hash` or `null` got `int`\n"
+	if stderr.String() != expected {
+		t.Errorf("stderr: want=%q, got=%q", expected, stderr.String())
+	}
+}
+
 func TestIndexAssignmentStatements(t *testing.T) {
 	tests := []vmTestCase{
 		{"xs := [1, 2, 3]; xs[1] = 4; xs[1];", 4},
@@ -351,6 +798,10 @@ func TestGlobalBindExpressions(t *testing.T) {
 		{"one := 1; one", 1},
 		{"one := 1; two := 2; one + two", 3},
 		{"one := 1; two := one + one; one + two", 3},
+		// Builtins can be shadowed
+		{"len := fn(x) { return 42 }; len([1])", 42},
+		{"len := fn(x) { return 42 }; f := fn() { return len([1]) }; f()", 42},
+		{"f := fn() { len := fn(x) { return 42 }; return len([1]) }; f()", 42},
 	}
 
 	runVmTests(t, tests)
@@ -465,6 +916,13 @@ func TestIndexExpressions(t *testing.T) {
 		{"{1: 1, 2: 2}[2]", 2},
 		{"{1: 1}[0]", Null},
 		{"{}[0]", Null},
+		{`{[1, "a"]: 1}[[1, "a"]]`, 1},
+		{`{[[1], [2]]: 1}[[[1], [2]]]`, 1},
+		{`{[1, "a"]: 1}[["a", 1]]`, Null},
+		{`{[1]: 1}[[1.0]]`, Null},
+		{"k := [1]; h := {k: 1}; k[0] = 2; h[[1]]", 1},
+		{"k := [1]; h := {k: 1}; k[0] = 2; h[k]", Null},
+		{`try { {[1, {}]: 1} } catch (e) { e.message }`, "unusable as hash key: array"},
 		{`"abc"[0]`, "a"},
 		{`"abc"[1]`, "b"},
 		{`"abc"[2]`, "c"},
@@ -700,15 +1158,15 @@ func TestCallingFunctionsWithWrongArguments(t *testing.T) {
 	tests := []vmTestCase{
 		{
 			input:    `fn() { return 1; }(1);`,
-			expected: `wrong number of arguments: want=0, got=1`,
+			expected: `1:19: TypeError: wrong number of arguments: want=0, got=1`,
 		},
 		{
 			input:    `fn(a) { return a; }();`,
-			expected: `wrong number of arguments: want=1, got=0`,
+			expected: `1:20: TypeError: wrong number of arguments: want=1, got=0`,
 		},
 		{
 			input:    `fn(a, b) { return a + b; }(1);`,
-			expected: `wrong number of arguments: want=2, got=1`,
+			expected: `1:27: TypeError: wrong number of arguments: want=2, got=1`,
 		},
 	}
 
@@ -733,6 +1191,334 @@ func TestCallingFunctionsWithWrongArguments(t *testing.T) {
 	}
 }
 
+func TestRuntimeErrorPositions(t *testing.T) {
+	input := `f := fn(x) {
+  return x + "a"
+}
+f(1)`
+
+	l := lexer.NewWithFilename(input, "test.monkey")
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	comp := compiler.New()
+	err := comp.Compile(program)
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	vm := New(comp.Bytecode())
+	err = vm.Run()
+	if err == nil {
+		t.Fatalf("expected VM error but resulted in none.")
+	}
+
+	expected := "test.monkey:2:12: TypeError: unknown operator: int + str"
+	if err.Error() != expected {
+		t.Fatalf("wrong VM error: want=%q, got=%q", expected, err)
+	}
+}
+
+func TestStackTrace(t *testing.T) {
+	input := `add := fn(x, y) {
+  return x + y
+}
+apply := fn(f) {
+  f(1, "a")
+}
+apply(add)`
+
+	l := lexer.NewWithFilename(input, "test.monkey")
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	comp := compiler.New()
+	err := comp.Compile(program)
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	vm := New(comp.Bytecode())
+	err = vm.Run()
+	if err == nil {
+		t.Fatalf("expected VM error but resulted in none.")
+	}
+
+	vmErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("error is not *Error. got=%T (%+v)", err, err)
+	}
+
+	expected := `Traceback (most recent call last):
+  File "test.monkey", line 7, column 6, in <main>
+  File "test.monkey", line 5, column 4, in apply
+  File "test.monkey", line 2, column 12, in add
+TypeError: unknown operator: int + str
+`
+	if vmErr.StackTrace() != expected {
+		t.Fatalf("wrong stack trace: want=%q, got=%q", expected, vmErr.StackTrace())
+	}
+}
+
+func TestCallbackStackTrace(t *testing.T) {
+	input := `f := fn() {
+  1 / 0
+}
+timer(0, f)
+run_loop()`
+
+	comp := compiler.New()
+	if err := comp.Compile(parser.New(lexer.NewWithFilename(input, "test.monkey")).ParseProgram()); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	vmErr, ok := New(comp.Bytecode()).Run().(*Error)
+	if !ok {
+		t.Fatalf("expected VM error")
+	}
+
+	expected := `Traceback (most recent call last):
+  File "test.monkey", line 5, column 9, in <main>
+  File "test.monkey", line 2, column 5, in f
+ZeroDivisionError: integer division or modulo by zero
+`
+	if vmErr.StackTrace() != expected {
+		t.Fatalf("wrong stack trace: want=%q, got=%q", expected, vmErr.StackTrace())
+	}
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		input    string
+		opts     Options
+		expected string
+	}{
+		// The stack and frames grow beyond their initial sizes
+		{"f := fn(n) { if (n == 0) { return 0 } return 1 + f(n - 1) }; f(5000)", DefaultOptions, "5000"},
+		{
+			"f := fn(n) { if (n == 0) { return 0 } return 1 + f(n - 1) }; f(100)",
+			Options{StackSize: 1000, MaxFrames: 100},
+			"RecursionError: maximum recursion depth exceeded (100 frames)",
+		},
+		{
+			"f := fn(n) { if (n == 0) { return 0 } return 1 + f(n - 1) }; f(100)",
+			Options{StackSize: 100, MaxFrames: 1000},
+			"StackOverflowError: maximum stack size exceeded (100 values)",
+		},
+		{
+			"f := fn(n) { if (n == 0) { return 0 } return 1 + f(n - 1) }; f(98)",
+			Options{StackSize: 1000, MaxFrames: 100},
+			"98",
+		},
+		// Limits below the initial stack size are enforced
+		{
+			"f := fn(n) { if (n == 0) { return 0 } return 1 + f(n - 1) }; f(20)",
+			Options{StackSize: 50, MaxFrames: 1000},
+			"StackOverflowError: maximum stack size exceeded (50 values)",
+		},
+		{
+			"f := fn(n) { if (n == 0) { return 0 } return 1 + f(n - 1) }; f(5)",
+			Options{StackSize: 50, MaxFrames: 1000},
+			"5",
+		},
+		// Tail calls run in constant frame space
+		{
+			"f := fn(n) { if (n == 0) { return 0 } return f(n - 1) }; f(1000)",
+			Options{StackSize: 100, MaxFrames: 10},
+			"0",
+		},
+		// Errors can be caught
+		{
+			"f := fn() { return 1 + f() }; try { f() } catch (e) { e.kind }",
+			Options{StackSize: 1000, MaxFrames: 100},
+			"RecursionError",
+		},
+		{
+			"k := [\"\"]; f := fn(n) { try { f(n + 1) } catch (e) { k[0] = e.kind } }; f(0); k[0]",
+			Options{StackSize: 300, MaxFrames: 100000},
+			"StackOverflowError",
+		},
+	}
+
+	for _, tt := range tests {
+		comp := compiler.New()
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		vm.Options = tt.opts
+
+		var actual string
+		if err := vm.Run(); err != nil {
+			actual = err.(*Error).Err.Error()
+		} else {
+			actual = vm.LastPopped().String()
+		}
+		if actual != tt.expected {
+			t.Errorf("%s: want=%q, got=%q", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	var stdout bytes.Buffer
+	var status int
+	ctx := &object.Context{
+		Stdin:  strings.NewReader("Alice\n"),
+		Stdout: &stdout,
+		Args:   []string{"-v"},
+		Env:    []string{"GREETING=Hello"},
+		Exit:   func(n int) { status = n },
+	}
+
+	comp := compiler.New()
+	if err := comp.Compile(parse(`name := input("name? ")
+print(getenv("GREETING") + " " + name)
+print(args())
+exit(3)`)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	vm := New(comp.Bytecode())
+	vm.Context = ctx
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+
+	expected := "name? Hello Alice\n[\"-v\"]\n"
+	if stdout.String() != expected {
+		t.Errorf("stdout: want=%q, got=%q", expected, stdout.String())
+	}
+	if status != 3 {
+		t.Errorf("exit status: want=3, got=%d", status)
+	}
+}
+
+func TestSandbox(t *testing.T) {
+	tests := []struct {
+		input        string
+		capabilities object.Capability
+		expected     string
+	}{
+		{`len("monkey")`, 0, "6"},
+		{`args()`, object.ProcessCapability, "[]"},
+		{`exit()`, object.FilesystemCapability, "PermissionError: exit() requires the process capability"},
+		{`readfile("/dev/null")`, 0, "PermissionError: readfile() requires the filesystem capability"},
+		{`ffi("hello", "Hello")`, object.AllCapabilities &^ object.FFICapability, "PermissionError: ffi() requires the ffi capability"},
+		{`socket("tcp4")`, object.FilesystemCapability, "PermissionError: socket() requires the network capability"},
+		{`f := fn() { return open("/dev/null") }; try { f() } catch (e) { e.kind }`, 0, "PermissionError"},
+		{`c := channel(); close(c); recv(c)`, 0, "null"},
+		{`close(1)`, object.ProcessCapability, "PermissionError: close() requires the filesystem or network capability"},
+		{`timer(0, fn() { }); run_loop()`, 0, "null"},
+		{`register(0, "r", print)`, object.ProcessCapability, "PermissionError: register() requires the filesystem or network capability"},
+		{`import("http").get("http://localhost/")`, object.FilesystemCapability, "PermissionError: http.get() requires the network capability"},
+		{`import("net").dial("tcp", "localhost:80")`, 0, "PermissionError: net.dial() requires the network capability"},
+	}
+
+	for _, tt := range tests {
+		comp := compiler.New()
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		vm.Context.Sandbox = true
+		vm.Context.Capabilities = tt.capabilities
+
+		var actual string
+		if err := vm.Run(); err != nil {
+			actual = err.(*Error).Err.Error()
+		} else {
+			actual = vm.LastPopped().String()
+		}
+		if actual != tt.expected {
+			t.Errorf("%s: want=%q, got=%q", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestNewCall(t *testing.T) {
+	state := NewVMState()
+	comp := compiler.NewWithState(state.Symbols, state.Constants)
+	if err := comp.Compile(parse("add := fn(a, b) { return a + b }")); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	code := comp.Bytecode()
+	state.Constants = code.Constants
+	if err := NewWithState(code, state).Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+
+	state.Set("x", &object.Integer{Value: 2})
+	if x, ok := state.Get("x"); !ok || x.(*object.Integer).Value != 2 {
+		t.Fatalf("x: want=2, got=%v", x)
+	}
+	if _, ok := state.Get("y"); ok {
+		t.Fatalf("y: want undefined")
+	}
+
+	add, ok := state.Get("add")
+	if !ok {
+		t.Fatal("add: want defined")
+	}
+	x, _ := state.Get("x")
+
+	vm := NewCall(state, add, x, &object.Integer{Value: 3})
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	if err := testIntegerObject(5, vm.LastPopped()); err != nil {
+		t.Fatal(err)
+	}
+
+	length, _ := state.Get("len")
+	vm = NewCall(state, length, &object.String{Value: "monkey"})
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	if err := testIntegerObject(6, vm.LastPopped()); err != nil {
+		t.Fatal(err)
+	}
+
+	vm = NewCall(state, add, x)
+	if err := vm.Run(); err == nil {
+		t.Fatal("expected VM error")
+	}
+}
+
+func TestRepeatedStackTrace(t *testing.T) {
+	input := `f := fn(n) {
+  return 1 + f(n + 1)
+}
+f(0)`
+
+	l := lexer.NewWithFilename(input, "test.monkey")
+	comp := compiler.New()
+	if err := comp.Compile(parser.New(l).ParseProgram()); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	vm := New(comp.Bytecode())
+	vm.Options.MaxFrames = 10
+	vmErr, ok := vm.Run().(*Error)
+	if !ok {
+		t.Fatal("expected VM error")
+	}
+
+	expected := `Traceback (most recent call last):
+  File "test.monkey", line 4, column 2, in <main>
+  File "test.monkey", line 2, column 15, in f
+  File "test.monkey", line 2, column 15, in f
+  File "test.monkey", line 2, column 15, in f
+  [Previous line repeated 6 more times]
+RecursionError: maximum recursion depth exceeded (10 frames)
+`
+	if vmErr.StackTrace() != expected {
+		t.Fatalf("wrong stack trace: want=%q, got=%q", expected, vmErr.StackTrace())
+	}
+}
+
 func TestBuiltinFunctions(t *testing.T) {
 	tests := []vmTestCase{
 		{`len("")`, 0},
@@ -802,6 +1588,16 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`str("foo")`, "foo"},
 		{`str([1, 2, 3])`, "[1, 2, 3]"},
 		{`str({"a": 1})`, "{\"a\": 1}"},
+		{`str({"b": 1, "a": 2, 3: true})`, "{\"b\": 1, \"a\": 2, 3: true}"},
+		{`str(keys({"b": 1, "a": 2}))`, "[\"b\", \"a\"]"},
+		{`str(values({"b": 1, "a": 2}))`, "[1, 2]"},
+		{`str(items({"b": 1, "a": 2}))`, "[[\"b\", 1], [\"a\", 2]]"},
+		{`str(keys({}))`, "[]"},
+		{`keys([])`,
+			&object.Error{
+				Message: "TypeError: keys() expected argument #1 to be `hash` got `array`",
+			},
+		},
 	}
 
 	runVmTests(t, tests)
@@ -939,6 +1735,56 @@ func TestTailCalls(t *testing.T) {
 			`,
 			expected: 9999,
 		},
+
+		// mutually recursive functions reuse the frame of the caller
+		{
+			input: `
+			odd := null
+			even := fn(n) { if (n == 0) { return true }; return odd(n - 1) }
+			odd := fn(n) { if (n == 0) { return false }; return even(n - 1) }
+			even(10001)
+			`,
+			expected: false,
+		},
+
+		// a closure is called in tail position with its own free variables
+		{
+			input: `
+			adder := fn(x) { return fn(y) { return x + y } }
+			apply := fn(f, n) { return f(n) }
+			apply(adder(1), 2)
+			`,
+			expected: 3,
+		},
+
+		// builtins are called as usual in tail position
+		{
+			input:    `f := fn(x) { return len(x) }; f("abc")`,
+			expected: 3,
+		},
+
+		// calls in try blocks keep their frame to handle errors
+		{
+			input: `
+			fail := fn() { throw "oops" }
+			f := fn() { try { return fail() } catch (e) { return "caught" } }
+			f()
+			`,
+			expected: "caught",
+		},
+		{
+			input: `
+			fail := fn() { throw "oops" }
+			f := fn() { return fail() }
+			g := fn() { try { return f() } catch (e) { return "caught" } }
+			g()
+			`,
+			expected: "caught",
+		},
+		{
+			input:    `f := fn(x) { return x }; g := fn() { return f(1, 2) }; g()`,
+			expected: &object.Error{Message: "TypeError: wrong number of arguments: want=1, got=2"},
+		},
 	}
 
 	runVmTests(t, tests)
@@ -1234,3 +2080,12 @@ func BenchmarkFibonacci(b *testing.B) {
 		})
 	}
 }
+
+// hashPairs returns the pairs of the hash by their keys
+func hashPairs(h *object.Hash) map[object.HashKey]object.HashPair {
+	pairs := make(map[object.HashKey]object.HashPair)
+	for _, pair := range h.Pairs() {
+		pairs[pair.Key.(object.Hashable).HashKey()] = pair
+	}
+	return pairs
+}